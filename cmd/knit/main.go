@@ -25,20 +25,25 @@ import (
 	"github.com/openshift-kni/debug-tools/pkg/knit/cmd/ghw"
 	"github.com/openshift-kni/debug-tools/pkg/knit/cmd/k8s"
 	"github.com/openshift-kni/debug-tools/pkg/knit/cmd/machineinfo"
+	"github.com/openshift-kni/debug-tools/pkg/knit/cmd/snapshot"
 )
 
 func main() {
 	root := cmd.NewRootCommand(
 		k8s.NewPodResourcesCommand,
 		k8s.NewPodInfoCommand,
+		k8s.NewCheckpointsCommand,
+		k8s.NewPinCheckCommand,
+		k8s.NewHugepagesCommand,
 		ghw.NewLscpuCommand,
 		ghw.NewLspciCommand,
 		ghw.NewLstopoCommand,
 		machineinfo.NewMachineInfoCommand,
 		ethtool.NewEthtoolCommand,
+		snapshot.NewSnapshotCommand,
 	)
 	if err := root.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
@@ -26,8 +26,8 @@ import (
 
 	flag "github.com/spf13/pflag"
 
-	"github.com/openshift-kni/debug-tools/internal/pkg/numalign"
-	"github.com/openshift-kni/debug-tools/internal/pkg/vfs"
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+	"github.com/openshift-kni/debug-tools/pkg/numalign"
 )
 
 type config struct {
@@ -35,6 +35,7 @@ type config struct {
 	procfsRoot string
 	sysfsRoot  string
 	debug      bool
+	numaMaps   bool
 }
 
 func (cfg *config) SetFlags() {
@@ -42,6 +43,7 @@ func (cfg *config) SetFlags() {
 	flag.StringVarP(&cfg.procfsRoot, "procfs", "p", "/proc", "procfs root.")
 	flag.StringVarP(&cfg.sysfsRoot, "sysfs", "s", "/sys", "sysfs root.")
 	flag.BoolVarP(&cfg.debug, "debug", "D", false, "enable debug mode.")
+	flag.BoolVarP(&cfg.numaMaps, "numa-maps", "M", false, "check also the NUMA nodes the memory and the hugepages come from, reading numa_maps.")
 }
 
 func (cfg *config) GetProcFSRoot() string {
@@ -59,6 +61,17 @@ func (cfg *config) IsDebugEnabled() bool {
 	return cfg.debug
 }
 
+func (cfg *config) IsNUMAMapsEnabled() bool {
+	if val, ok := os.LookupEnv("NUMALIGN_NUMA_MAPS"); ok {
+		enabled, err := strconv.ParseBool(val)
+		if err != nil {
+			fatalf("invalid NUMALIGN_NUMA_MAPS value %q: %v", val, err)
+		}
+		return enabled
+	}
+	return cfg.numaMaps
+}
+
 func (cfg *config) GetSleepTime() time.Duration {
 	var sleepTime time.Duration
 	if val, ok := os.LookupEnv("NUMALIGN_SLEEP_HOURS"); ok {
@@ -69,7 +82,7 @@ func (cfg *config) GetSleepTime() time.Duration {
 	}
 	hours, err := strconv.Atoi(cfg.sleepHours)
 	if err != nil {
-		log.Fatalf("%v", err)
+		fatalf("%v", err)
 	}
 	if hours > 0 {
 		sleepTime = time.Duration(hours) * time.Hour
@@ -78,7 +91,13 @@ func (cfg *config) GetSleepTime() time.Duration {
 }
 
 func (cfg config) String() string {
-	return fmt.Sprintf("sleep=%v procfs=%q sysfs=%q debug=%v", cfg.sleepHours, cfg.procfsRoot, cfg.sysfsRoot, cfg.debug)
+	return fmt.Sprintf("sleep=%v procfs=%q sysfs=%q debug=%v numaMaps=%v", cfg.sleepHours, cfg.procfsRoot, cfg.sysfsRoot, cfg.debug, cfg.numaMaps)
+}
+
+// fatalf reports the error on stderr, because the log is discarded unless debug is enabled, then exits.
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
 }
 
 func main() {
@@ -94,9 +113,14 @@ func main() {
 
 	sleepTime := cfg.GetSleepTime()
 
-	numaRes, err := numalign.NewResources(vfs.LinuxFS{}, cfg.GetProcFSRoot(), cfg.GetSysFSRoot(), os.Environ(), flag.Args())
+	numaRes, err := numalign.NewResources(fswrap.LinuxFS{}, cfg.GetProcFSRoot(), cfg.GetSysFSRoot(), os.Environ(), flag.Args())
 	if err != nil {
-		log.Fatalf("%v", err)
+		fatalf("%v", err)
+	}
+	if cfg.IsNUMAMapsEnabled() {
+		if err := numaRes.ReadNUMAMaps(fswrap.LinuxFS{}, cfg.GetProcFSRoot(), flag.Args()); err != nil {
+			fatalf("%v", err)
+		}
 	}
 
 	res := numaRes.CheckAlignment()
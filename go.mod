@@ -1,6 +1,7 @@
 module github.com/openshift-kni/debug-tools
 
-go 1.20
+// the k8s.io v0.29 modules declare go 1.21, the go command does not accept an older version here
+go 1.21
 
 require (
 	github.com/google/cadvisor v0.46.0
@@ -17,6 +18,7 @@ require (
 	k8s.io/client-go v0.29.2
 	k8s.io/klog/v2 v2.110.1
 	k8s.io/kubelet v0.29.2
+	sigs.k8s.io/yaml v1.3.0
 )
 
 require (
@@ -70,7 +72,6 @@ require (
 	k8s.io/utils v0.0.0-20230726121419-3b25d923346b
 	sigs.k8s.io/json v0.0.0-20221116044647-bc3834ca7abd // indirect
 	sigs.k8s.io/structured-merge-diff/v4 v4.4.1 // indirect
-	sigs.k8s.io/yaml v1.3.0 // indirect
 )
 
 // Pinned to kubernetes-1.29.2
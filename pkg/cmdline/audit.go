@@ -0,0 +1,370 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package cmdline
+
+import (
+	"fmt"
+	"strings"
+
+	cpuset "k8s.io/utils/cpuset"
+)
+
+type Level string
+
+const (
+	LevelInfo    Level = "info"
+	LevelWarning Level = "warning"
+	LevelError   Level = "error"
+)
+
+type Finding struct {
+	Level   Level  `json:"level"`
+	Param   string `json:"param"`
+	Message string `json:"message"`
+}
+
+func (fi Finding) String() string {
+	return fmt.Sprintf("[%-7s] %-12s %s", fi.Level, fi.Param, fi.Message)
+}
+
+type Report struct {
+	Isolated []int     `json:"isolated"`
+	Params   Cmdline   `json:"params"`
+	Findings []Finding `json:"findings"`
+}
+
+// HasErrors tells if the report contains any error-level finding.
+func (re Report) HasErrors() bool {
+	for _, fi := range re.Findings {
+		if fi.Level == LevelError {
+			return true
+		}
+	}
+	return false
+}
+
+// the parameters we audit. Keep sorted by importance, because the report will follow this order.
+var auditedParams = []string{
+	"isolcpus",
+	"nohz",
+	"nohz_full",
+	"rcu_nocbs",
+	"irqaffinity",
+	"skew_tick",
+	"intel_pstate",
+	"idle",
+	"tsc",
+	"nosoftlockup",
+}
+
+// isolcpus flags, see https://www.kernel.org/doc/html/latest/admin-guide/kernel-parameters.html
+const (
+	isolFlagNohz       = "nohz"
+	isolFlagDomain     = "domain"
+	isolFlagManagedIRQ = "managed_irq"
+)
+
+// Isolcpus is the parsed value of the isolcpus parameter.
+type Isolcpus struct {
+	Flags []string
+	CPUs  cpuset.CPUSet
+}
+
+func (ic Isolcpus) HasFlag(flag string) bool {
+	if len(ic.Flags) == 0 {
+		// no flags means "domain" only
+		return flag == isolFlagDomain
+	}
+	for _, fl := range ic.Flags {
+		if fl == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseIsolcpus parses the value of the isolcpus parameter: "[flag-list,]<cpu-list>".
+func ParseIsolcpus(value string) (Isolcpus, error) {
+	ic := Isolcpus{}
+	items := strings.Split(value, ",")
+	idx := 0
+	for ; idx < len(items); idx++ {
+		item := items[idx]
+		if item != isolFlagNohz && item != isolFlagDomain && item != isolFlagManagedIRQ {
+			break
+		}
+		ic.Flags = append(ic.Flags, item)
+	}
+	cpus, err := cpuset.Parse(strings.Join(items[idx:], ","))
+	if err != nil {
+		return ic, fmt.Errorf("malformed isolcpus %q: %v", value, err)
+	}
+	ic.CPUs = cpus
+	return ic, nil
+}
+
+// IsolatedCPUs infers the isolated cpus set from the kernel parameters, using
+// isolcpus or, if missing, nohz_full. Returns an empty set if none is found.
+func IsolatedCPUs(cl Cmdline) (cpuset.CPUSet, error) {
+	if pa, ok := cl.Get("isolcpus"); ok {
+		ic, err := ParseIsolcpus(pa.Value)
+		return ic.CPUs, err
+	}
+	if pa, ok := cl.Get("nohz_full"); ok {
+		return cpuset.Parse(pa.Value)
+	}
+	return cpuset.New(), nil
+}
+
+type auditor struct {
+	cl       Cmdline
+	isolated cpuset.CPUSet
+	findings []Finding
+}
+
+// Audit checks the kernel parameters relevant for low-latency tuning against the isolated cpus set.
+func Audit(cl Cmdline, isolated cpuset.CPUSet) Report {
+	au := auditor{
+		cl:       cl,
+		isolated: isolated,
+	}
+	if isolated.IsEmpty() {
+		au.add(LevelWarning, "", "no isolated cpus given or found, skipping the cpu set checks")
+	}
+	for _, key := range auditedParams {
+		if count := cl.Count(key); count > 1 {
+			au.add(LevelWarning, key, "specified %d times, only the last one is honoured", count)
+		}
+	}
+	au.checkIsolcpus()
+	au.checkNohzFull()
+	au.checkRcuNocbs()
+	au.checkIrqaffinity()
+	au.checkSkewTick()
+	au.checkIntelPstate()
+	au.checkIdle()
+	au.checkTsc()
+	au.checkNosoftlockup()
+
+	params := Cmdline{}
+	for _, pa := range cl {
+		if isAudited(pa.Key) {
+			params = append(params, pa)
+		}
+	}
+	return Report{
+		Isolated: isolated.List(),
+		Params:   params,
+		Findings: au.findings,
+	}
+}
+
+func (au *auditor) add(level Level, param, format string, args ...interface{}) {
+	au.findings = append(au.findings, Finding{
+		Level:   level,
+		Param:   param,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// checkCoverage reports the gaps (isolated cpus not in `cpus`) and the extra cpus (cpus not isolated).
+func (au *auditor) checkCoverage(param string, cpus cpuset.CPUSet, gapLevel Level, gapMsg string) {
+	if au.isolated.IsEmpty() {
+		return
+	}
+	if gaps := au.isolated.Difference(cpus); !gaps.IsEmpty() {
+		au.add(gapLevel, param, "isolated cpus %s are not covered: %s", gaps.String(), gapMsg)
+	}
+	if extra := cpus.Difference(au.isolated); !extra.IsEmpty() {
+		au.add(LevelWarning, param, "cpus %s are outside the isolated set %s", extra.String(), au.isolated.String())
+	}
+}
+
+func (au *auditor) checkIsolcpus() {
+	pa, ok := au.cl.Get("isolcpus")
+	if !ok {
+		au.add(LevelError, "isolcpus", "missing: the isolated cpus are not removed from the scheduler load balancing")
+		return
+	}
+	ic, err := ParseIsolcpus(pa.Value)
+	if err != nil {
+		au.add(LevelError, "isolcpus", "%v", err)
+		return
+	}
+	au.checkCoverage("isolcpus", ic.CPUs, LevelError, "they are still load balanced by the scheduler")
+	if !ic.HasFlag(isolFlagDomain) {
+		au.add(LevelWarning, "isolcpus", "missing flag %q: the cpus are not removed from the scheduler domains", isolFlagDomain)
+	}
+	if !ic.HasFlag(isolFlagManagedIRQ) {
+		au.add(LevelWarning, "isolcpus", "missing flag %q: managed interrupts can still target the isolated cpus", isolFlagManagedIRQ)
+	}
+}
+
+func (au *auditor) checkNohzFull() {
+	if pa, ok := au.cl.Get("nohz"); ok && pa.Value == "off" {
+		if _, ok := au.cl.Get("nohz_full"); ok {
+			au.add(LevelError, "nohz", "nohz=off disables nohz_full")
+		}
+	}
+
+	pa, ok := au.cl.Get("nohz_full")
+	if !ok {
+		if isolPa, ok := au.cl.Get("isolcpus"); ok {
+			if ic, err := ParseIsolcpus(isolPa.Value); err == nil && ic.HasFlag(isolFlagNohz) {
+				return // isolcpus=nohz is equivalent
+			}
+		}
+		au.add(LevelWarning, "nohz_full", "missing: the scheduler tick keeps running on the isolated cpus")
+		return
+	}
+	cpus, err := cpuset.Parse(pa.Value)
+	if err != nil {
+		au.add(LevelError, "nohz_full", "malformed value %q: %v", pa.Value, err)
+		return
+	}
+	au.checkCoverage("nohz_full", cpus, LevelWarning, "the scheduler tick keeps running on them")
+	if cpus.Contains(0) {
+		au.add(LevelWarning, "nohz_full", "includes the boot cpu 0, which the kernel keeps for timekeeping")
+	}
+}
+
+func (au *auditor) checkRcuNocbs() {
+	pa, ok := au.cl.Get("rcu_nocbs")
+	if !ok {
+		au.add(LevelWarning, "rcu_nocbs", "missing: RCU callbacks can run on the isolated cpus")
+		return
+	}
+	if !pa.HasValue || pa.Value == "all" {
+		return
+	}
+	cpus, err := cpuset.Parse(pa.Value)
+	if err != nil {
+		au.add(LevelError, "rcu_nocbs", "malformed value %q: %v", pa.Value, err)
+		return
+	}
+	if nohzPa, ok := au.cl.Get("nohz_full"); ok {
+		// nohz_full cpus are offloaded anyway
+		if nohzCpus, err := cpuset.Parse(nohzPa.Value); err == nil {
+			if missing := nohzCpus.Difference(cpus); !missing.IsEmpty() {
+				au.add(LevelInfo, "rcu_nocbs", "nohz_full cpus %s are not listed, the kernel offloads them anyway", missing.String())
+			}
+			cpus = cpus.Union(nohzCpus)
+		}
+	}
+	au.checkCoverage("rcu_nocbs", cpus, LevelWarning, "RCU callbacks can run on them")
+}
+
+func (au *auditor) checkIrqaffinity() {
+	pa, ok := au.cl.Get("irqaffinity")
+	if !ok {
+		if !au.isolated.IsEmpty() {
+			au.add(LevelWarning, "irqaffinity", "missing: the default IRQ affinity includes the isolated cpus")
+		}
+		return
+	}
+	cpus, err := cpuset.Parse(pa.Value)
+	if err != nil {
+		au.add(LevelError, "irqaffinity", "malformed value %q: %v", pa.Value, err)
+		return
+	}
+	if cpus.IsEmpty() {
+		au.add(LevelError, "irqaffinity", "empty cpu set")
+		return
+	}
+	if overlap := cpus.Intersection(au.isolated); !overlap.IsEmpty() {
+		au.add(LevelError, "irqaffinity", "includes the isolated cpus %s", overlap.String())
+	}
+}
+
+func (au *auditor) checkSkewTick() {
+	pa, ok := au.cl.Get("skew_tick")
+	if !ok {
+		au.add(LevelWarning, "skew_tick", "missing: the ticks of all the cpus fire at the same time, increasing the lock contention")
+		return
+	}
+	if pa.Value != "1" {
+		au.add(LevelWarning, "skew_tick", "value %q does not enable the tick skew, expected \"1\"", pa.Value)
+	}
+}
+
+func (au *auditor) checkIntelPstate() {
+	pa, ok := au.cl.Get("intel_pstate")
+	if !ok {
+		au.add(LevelInfo, "intel_pstate", "not set: the driver defaults apply, and HWP may change the frequency of the isolated cpus")
+		return
+	}
+	for _, value := range strings.Split(pa.Value, ",") {
+		if !isOneOf(value, "disable", "active", "passive", "force", "no_hwp", "hwp_only", "support_acpi_ppc", "per_cpu_perf_limits", "no_cas") {
+			au.add(LevelWarning, "intel_pstate", "unknown value %q", value)
+		}
+	}
+}
+
+func (au *auditor) checkIdle() {
+	pa, ok := au.cl.Get("idle")
+	if !ok {
+		return
+	}
+	switch pa.Value {
+	case "poll":
+		au.add(LevelInfo, "idle", "the cpus busy-poll when idle: lowest wakeup latency, highest power consumption")
+	case "halt", "nomwait":
+		return
+	default:
+		au.add(LevelWarning, "idle", "unknown value %q", pa.Value)
+	}
+}
+
+func (au *auditor) checkTsc() {
+	pa, ok := au.cl.Get("tsc")
+	if !ok {
+		au.add(LevelWarning, "tsc", "not set: the clocksource watchdog can run on the isolated cpus, consider tsc=reliable or tsc=nowatchdog")
+		return
+	}
+	values := strings.Split(pa.Value, ",")
+	for _, value := range values {
+		if !isOneOf(value, "reliable", "noirqtime", "unstable", "nowatchdog", "recalibrate", "watchdog") {
+			au.add(LevelWarning, "tsc", "unknown value %q", value)
+		}
+	}
+	if isOneOf("unstable", values...) {
+		au.add(LevelError, "tsc", "tsc=unstable forces a slower clocksource")
+		return
+	}
+	if !isOneOf("reliable", values...) && !isOneOf("nowatchdog", values...) {
+		au.add(LevelWarning, "tsc", "the clocksource watchdog can run on the isolated cpus, consider tsc=reliable or tsc=nowatchdog")
+	}
+}
+
+func (au *auditor) checkNosoftlockup() {
+	if _, ok := au.cl.Get("nosoftlockup"); !ok {
+		au.add(LevelWarning, "nosoftlockup", "missing: the softlockup detector periodically wakes up on the isolated cpus")
+	}
+}
+
+func isAudited(key string) bool {
+	return isOneOf(key, auditedParams...)
+}
+
+func isOneOf(value string, candidates ...string) bool {
+	for _, candidate := range candidates {
+		if value == candidate {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package cmdline
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+)
+
+// Param is a kernel boot parameter, see https://www.kernel.org/doc/html/latest/admin-guide/kernel-parameters.html
+type Param struct {
+	Key      string `json:"key"`
+	Value    string `json:"value,omitempty"`
+	HasValue bool   `json:"hasValue"`
+}
+
+func (pa Param) String() string {
+	if !pa.HasValue {
+		return pa.Key
+	}
+	return pa.Key + "=" + pa.Value
+}
+
+// Cmdline is the kernel command line. Parameters are kept in the order they are found,
+// including the duplicates.
+type Cmdline []Param
+
+// Get returns the last occurrence of the parameter `key`, which is the one the kernel honours
+// for the parameters we care about.
+func (cl Cmdline) Get(key string) (Param, bool) {
+	for idx := len(cl) - 1; idx >= 0; idx-- {
+		if cl[idx].Key == key {
+			return cl[idx], true
+		}
+	}
+	return Param{}, false
+}
+
+// Count returns how many times the parameter `key` is found.
+func (cl Cmdline) Count(key string) int {
+	count := 0
+	for _, pa := range cl {
+		if pa.Key == key {
+			count++
+		}
+	}
+	return count
+}
+
+// Parse splits the kernel command line into parameters. Like the kernel does, double quotes
+// allow to have spaces in values, and are removed.
+func Parse(data string) Cmdline {
+	var cl Cmdline
+	for _, item := range splitQuoted(strings.TrimSpace(data)) {
+		pa := Param{Key: item}
+		if idx := strings.Index(item, "="); idx >= 0 {
+			pa.Key = item[:idx]
+			pa.Value = item[idx+1:]
+			pa.HasValue = true
+		}
+		cl = append(cl, pa)
+	}
+	return cl
+}
+
+func splitQuoted(data string) []string {
+	var items []string
+	var sb strings.Builder
+	inQuotes := false
+	for _, ch := range data {
+		switch {
+		case ch == '"':
+			inQuotes = !inQuotes
+		case (ch == ' ' || ch == '\t' || ch == '\n') && !inQuotes:
+			if sb.Len() > 0 {
+				items = append(items, sb.String())
+				sb.Reset()
+			}
+		default:
+			sb.WriteRune(ch)
+		}
+	}
+	if sb.Len() > 0 {
+		items = append(items, sb.String())
+	}
+	return items
+}
+
+type Handler struct {
+	log        *log.Logger
+	procfsRoot string
+	fs         fswrap.FSWrapper
+}
+
+func New(logger *log.Logger, procfsRoot string) *Handler {
+	return NewWithFS(logger, fswrap.LinuxFS{Log: logger}, procfsRoot)
+}
+
+// NewWithFS creates a Handler which reads the procfs content, rooted at procfsRoot, from fsys.
+func NewWithFS(logger *log.Logger, fsys fswrap.FSWrapper, procfsRoot string) *Handler {
+	return &Handler{
+		log:        logger,
+		procfsRoot: procfsRoot,
+		fs:         fsys,
+	}
+}
+
+func (handler *Handler) Read() (Cmdline, error) {
+	data, err := handler.fs.ReadFile(filepath.Join(handler.procfsRoot, "cmdline"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading cmdline from %q: %v", handler.procfsRoot, err)
+	}
+	return Parse(string(data)), nil
+}
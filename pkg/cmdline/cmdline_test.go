@@ -0,0 +1,215 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package cmdline_test
+
+import (
+	"io/ioutil"
+	"log"
+	"reflect"
+	"testing"
+
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/cmdline"
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+)
+
+var nullLog = log.New(ioutil.Discard, "", 0)
+
+const tunedCmdline = "BOOT_IMAGE=/vmlinuz-4.18.0 root=UUID=42 ro skew_tick=1 nohz=on nohz_full=2-7 rcu_nocbs=2-7 isolcpus=managed_irq,domain,2-7 irqaffinity=0-1 tsc=reliable nosoftlockup intel_pstate=disable\n"
+
+func TestParse(t *testing.T) {
+	cl := cmdline.Parse(`BOOT_IMAGE=/vmlinuz quiet  dyndbg="file foo.c +p" isolcpus=2-3 isolcpus=4-5` + "\n")
+	expected := cmdline.Cmdline{
+		{Key: "BOOT_IMAGE", Value: "/vmlinuz", HasValue: true},
+		{Key: "quiet"},
+		{Key: "dyndbg", Value: "file foo.c +p", HasValue: true},
+		{Key: "isolcpus", Value: "2-3", HasValue: true},
+		{Key: "isolcpus", Value: "4-5", HasValue: true},
+	}
+	if !reflect.DeepEqual(cl, expected) {
+		t.Fatalf("Parse mismatch got %#v expected %#v", cl, expected)
+	}
+
+	pa, ok := cl.Get("isolcpus")
+	if !ok || pa.Value != "4-5" {
+		t.Errorf("Get returned %v %v expected the last occurrence", pa, ok)
+	}
+	if cl.Count("isolcpus") != 2 {
+		t.Errorf("Count mismatch got %d expected 2", cl.Count("isolcpus"))
+	}
+	if _, ok := cl.Get("nohz_full"); ok {
+		t.Errorf("Get found a missing parameter")
+	}
+}
+
+func TestParseIsolcpus(t *testing.T) {
+	testCases := []struct {
+		value       string
+		flags       []string
+		cpus        cpuset.CPUSet
+		expectError bool
+	}{
+		{"2-7", nil, cpuset.New(2, 3, 4, 5, 6, 7), false},
+		{"managed_irq,domain,2-3,6", []string{"managed_irq", "domain"}, cpuset.New(2, 3, 6), false},
+		{"nohz,4", []string{"nohz"}, cpuset.New(4), false},
+		{"foo,4", nil, cpuset.New(), true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			ic, err := cmdline.ParseIsolcpus(tc.value)
+			if (err != nil) != tc.expectError {
+				t.Fatalf("unexpected error status: %v", err)
+			}
+			if tc.expectError {
+				return
+			}
+			if !reflect.DeepEqual(ic.Flags, tc.flags) {
+				t.Errorf("flags mismatch got %v expected %v", ic.Flags, tc.flags)
+			}
+			if !ic.CPUs.Equals(tc.cpus) {
+				t.Errorf("cpus mismatch got %v expected %v", ic.CPUs, tc.cpus)
+			}
+		})
+	}
+}
+
+func TestAudit(t *testing.T) {
+	type expectedFinding struct {
+		level cmdline.Level
+		param string
+	}
+	testCases := []struct {
+		name     string
+		cmdline  string
+		isolated cpuset.CPUSet
+		expected []expectedFinding
+	}{
+		{
+			name:     "well tuned",
+			cmdline:  tunedCmdline,
+			isolated: cpuset.New(2, 3, 4, 5, 6, 7),
+		},
+		{
+			name:     "isolated set larger than isolcpus",
+			cmdline:  tunedCmdline,
+			isolated: cpuset.New(1, 2, 3, 4, 5, 6, 7),
+			expected: []expectedFinding{
+				{cmdline.LevelError, "isolcpus"},
+				{cmdline.LevelWarning, "nohz_full"},
+				{cmdline.LevelWarning, "rcu_nocbs"},
+				{cmdline.LevelError, "irqaffinity"},
+			},
+		},
+		{
+			name:     "isolated set smaller than isolcpus",
+			cmdline:  tunedCmdline,
+			isolated: cpuset.New(4, 5, 6, 7),
+			expected: []expectedFinding{
+				{cmdline.LevelWarning, "isolcpus"},
+				{cmdline.LevelWarning, "nohz_full"},
+				{cmdline.LevelWarning, "rcu_nocbs"},
+			},
+		},
+		{
+			name:     "untuned",
+			cmdline:  "BOOT_IMAGE=/vmlinuz root=UUID=42 ro quiet",
+			isolated: cpuset.New(2, 3),
+			expected: []expectedFinding{
+				{cmdline.LevelError, "isolcpus"},
+				{cmdline.LevelWarning, "nohz_full"},
+				{cmdline.LevelWarning, "rcu_nocbs"},
+				{cmdline.LevelWarning, "irqaffinity"},
+				{cmdline.LevelWarning, "skew_tick"},
+				{cmdline.LevelInfo, "intel_pstate"},
+				{cmdline.LevelWarning, "tsc"},
+				{cmdline.LevelWarning, "nosoftlockup"},
+			},
+		},
+		{
+			name:     "contradictions",
+			cmdline:  "skew_tick=1 nohz=off nohz_full=0,2-3 rcu_nocbs isolcpus=2-3 irqaffinity=0-2 tsc=unstable nosoftlockup intel_pstate=disable",
+			isolated: cpuset.New(2, 3),
+			expected: []expectedFinding{
+				{cmdline.LevelWarning, "isolcpus"},
+				{cmdline.LevelError, "nohz"},
+				{cmdline.LevelWarning, "nohz_full"},
+				{cmdline.LevelWarning, "nohz_full"},
+				{cmdline.LevelError, "irqaffinity"},
+				{cmdline.LevelError, "tsc"},
+			},
+		},
+		{
+			name:     "duplicates",
+			cmdline:  tunedCmdline + " isolcpus=managed_irq,domain,2-7",
+			isolated: cpuset.New(2, 3, 4, 5, 6, 7),
+			expected: []expectedFinding{
+				{cmdline.LevelWarning, "isolcpus"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			report := cmdline.Audit(cmdline.Parse(tc.cmdline), tc.isolated)
+			var got []expectedFinding
+			for _, fi := range report.Findings {
+				got = append(got, expectedFinding{fi.Level, fi.Param})
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("findings mismatch got %v expected %v\n%v", got, tc.expected, report.Findings)
+			}
+		})
+	}
+}
+
+func TestIsolatedCPUs(t *testing.T) {
+	testCases := []struct {
+		cmdline  string
+		expected cpuset.CPUSet
+	}{
+		{tunedCmdline, cpuset.New(2, 3, 4, 5, 6, 7)},
+		{"nohz_full=4-5", cpuset.New(4, 5)},
+		{"quiet", cpuset.New()},
+	}
+	for _, tc := range testCases {
+		cpus, err := cmdline.IsolatedCPUs(cmdline.Parse(tc.cmdline))
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", tc.cmdline, err)
+			continue
+		}
+		if !cpus.Equals(tc.expected) {
+			t.Errorf("isolated cpus mismatch for %q: got %v expected %v", tc.cmdline, cpus, tc.expected)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	fsys := fswrap.NewMemFS(map[string]string{
+		"/proc/cmdline": tunedCmdline,
+	})
+	cl, err := cmdline.NewWithFS(nullLog, fsys, "/proc").Read()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(cl) != 12 {
+		t.Errorf("unexpected params count %d: %v", len(cl), cl)
+	}
+
+	if _, err := cmdline.NewWithFS(nullLog, fsys, "/missing").Read(); err == nil {
+		t.Errorf("Read succeeded on a missing procfs")
+	}
+}
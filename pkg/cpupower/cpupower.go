@@ -0,0 +1,281 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+// Package cpupower reads the cpu frequency scaling (cpufreq) and idle states (cpuidle) settings from sysfs.
+package cpupower
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+)
+
+// GovernorPerformance is the only governor which never lowers the frequency.
+const GovernorPerformance = "performance"
+
+// Frequency is the cpufreq setting of a cpu. The frequencies are in kHz.
+type Frequency struct {
+	Driver   string `json:"driver,omitempty"`
+	Governor string `json:"governor"`
+	Min      uint64 `json:"min"`
+	Max      uint64 `json:"max"`
+	Cur      uint64 `json:"cur"`
+	// HWMin and HWMax are the limits of the hardware
+	HWMin uint64 `json:"hwMin,omitempty"`
+	HWMax uint64 `json:"hwMax,omitempty"`
+}
+
+// IdleState is a cpuidle state (C-state) of a cpu.
+type IdleState struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+	// Latency is the exit latency, in microseconds
+	Latency  uint64 `json:"latency"`
+	Disabled bool   `json:"disabled,omitempty"`
+	// Usage is the number of times the state was entered, and Time the time spent in it, in microseconds
+	Usage uint64 `json:"usage"`
+	Time  uint64 `json:"time"`
+}
+
+// CPUInfo holds the power management settings of a cpu. Frequency is nil if cpufreq is not available,
+// like on most VMs.
+type CPUInfo struct {
+	CPU        int         `json:"cpu"`
+	Frequency  *Frequency  `json:"frequency,omitempty"`
+	IdleStates []IdleState `json:"idleStates"`
+	Warnings   []string    `json:"warnings,omitempty"`
+}
+
+// Check tells the settings which can cause latency spikes: a governor which can lower the frequency,
+// and enabled idle states with exit latency higher than maxLatency microseconds.
+func (ci CPUInfo) Check(maxLatency uint64) []string {
+	var warnings []string
+	if ci.Frequency != nil && ci.Frequency.Governor != GovernorPerformance {
+		warnings = append(warnings, fmt.Sprintf("governor %q can change the frequency in the %d-%d kHz range", ci.Frequency.Governor, ci.Frequency.Min, ci.Frequency.Max))
+	}
+	for _, st := range ci.IdleStates {
+		if st.Disabled || st.Latency <= maxLatency {
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf("idle state %s enabled, exit latency %dus", st.Name, st.Latency))
+	}
+	return warnings
+}
+
+// Residency is the usage of a idle state over a period.
+type Residency struct {
+	CPU   int    `json:"cpu"`
+	State string `json:"state"`
+	Usage uint64 `json:"usage"`
+	// Time spent in the state, in microseconds
+	Time uint64 `json:"time"`
+	// Percent of the period spent in the state
+	Percent float64 `json:"percent"`
+}
+
+func (res Residency) String() string {
+	return fmt.Sprintf("CPU=%d STATE=%s usage=+%d time=+%dus residency=%.2f%%", res.CPU, res.State, res.Usage, res.Time, res.Percent)
+}
+
+// IdleResidency computes the usage of the idle states between the two readings, assuming last is fresher than prev.
+// The states not used in the period are skipped.
+func IdleResidency(prev, last []CPUInfo, elapsed time.Duration) []Residency {
+	usecs := float64(elapsed.Microseconds())
+	if usecs <= 0 {
+		usecs = 1
+	}
+	prevStates := make(map[int]map[int]IdleState)
+	for _, ci := range prev {
+		prevStates[ci.CPU] = make(map[int]IdleState)
+		for _, st := range ci.IdleStates {
+			prevStates[ci.CPU][st.Index] = st
+		}
+	}
+	res := []Residency{}
+	for _, ci := range last {
+		for _, st := range ci.IdleStates {
+			prevSt := prevStates[ci.CPU][st.Index]
+			usage := st.Usage - prevSt.Usage
+			if usage == 0 {
+				continue
+			}
+			spent := st.Time - prevSt.Time
+			res = append(res, Residency{
+				CPU:     ci.CPU,
+				State:   st.Name,
+				Usage:   usage,
+				Time:    spent,
+				Percent: 100 * float64(spent) / usecs,
+			})
+		}
+	}
+	return res
+}
+
+type Handler struct {
+	log       *log.Logger
+	sysfsRoot string
+	fs        fswrap.FSWrapper
+}
+
+func New(logger *log.Logger, sysfsRoot string) *Handler {
+	return NewWithFS(logger, fswrap.LinuxFS{Log: logger}, sysfsRoot)
+}
+
+// NewWithFS creates a Handler which reads the sysfs content, rooted at sysfsRoot, from fsys.
+func NewWithFS(logger *log.Logger, fsys fswrap.FSWrapper, sysfsRoot string) *Handler {
+	return &Handler{
+		log:       logger,
+		sysfsRoot: sysfsRoot,
+		fs:        fsys,
+	}
+}
+
+// Read reads the settings of the given cpus. The cpus not present are skipped.
+// Missing cpufreq or cpuidle settings are not an error.
+func (handler *Handler) Read(cpus cpuset.CPUSet) ([]CPUInfo, error) {
+	cpusDir := filepath.Join(handler.sysfsRoot, "devices", "system", "cpu")
+	entries, err := handler.fs.ReadDir(cpusDir)
+	if err != nil {
+		return nil, err
+	}
+	var present []int
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), "cpu") {
+			continue
+		}
+		cpuid, err := strconv.Atoi(strings.TrimPrefix(entry.Name(), "cpu"))
+		if err != nil {
+			// cpufreq, cpuidle...
+			continue
+		}
+		present = append(present, cpuid)
+	}
+	cpus = cpus.Intersection(cpuset.New(present...))
+
+	infos := make([]CPUInfo, 0, cpus.Size())
+	for _, cpuid := range cpus.List() {
+		cpuDir := filepath.Join(cpusDir, fmt.Sprintf("cpu%d", cpuid))
+		freq, err := handler.readFrequency(filepath.Join(cpuDir, "cpufreq"))
+		if err != nil {
+			handler.log.Printf("Error reading the cpufreq settings of cpu %d: %v", cpuid, err)
+		}
+		states, err := handler.readIdleStates(filepath.Join(cpuDir, "cpuidle"))
+		if err != nil {
+			return nil, fmt.Errorf("error reading the idle states of cpu %d: %v", cpuid, err)
+		}
+		infos = append(infos, CPUInfo{
+			CPU:        cpuid,
+			Frequency:  freq,
+			IdleStates: states,
+		})
+	}
+	return infos, nil
+}
+
+func (handler *Handler) readFrequency(freqDir string) (*Frequency, error) {
+	governor, err := handler.readString(filepath.Join(freqDir, "scaling_governor"))
+	if err != nil {
+		return nil, err
+	}
+	freq := Frequency{Governor: governor}
+	// the driver is informative only
+	freq.Driver, _ = handler.readString(filepath.Join(freqDir, "scaling_driver"))
+	for name, val := range map[string]*uint64{
+		"scaling_min_freq": &freq.Min,
+		"scaling_max_freq": &freq.Max,
+		"scaling_cur_freq": &freq.Cur,
+		"cpuinfo_min_freq": &freq.HWMin,
+		"cpuinfo_max_freq": &freq.HWMax,
+	} {
+		*val, err = handler.readUint(filepath.Join(freqDir, name))
+		if err != nil {
+			handler.log.Printf("Error reading %q: %v", name, err)
+		}
+	}
+	return &freq, nil
+}
+
+func (handler *Handler) readIdleStates(idleDir string) ([]IdleState, error) {
+	states := []IdleState{}
+	entries, err := handler.fs.ReadDir(idleDir)
+	if err != nil {
+		// no cpuidle driver
+		handler.log.Printf("Error reading %q: %v", idleDir, err)
+		return states, nil
+	}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), "state") {
+			continue
+		}
+		idx, err := strconv.Atoi(strings.TrimPrefix(entry.Name(), "state"))
+		if err != nil {
+			continue
+		}
+		stateDir := filepath.Join(idleDir, entry.Name())
+		st := IdleState{Index: idx}
+		if st.Name, err = handler.readString(filepath.Join(stateDir, "name")); err != nil {
+			return states, err
+		}
+		if st.Latency, err = handler.readUint(filepath.Join(stateDir, "latency")); err != nil {
+			return states, err
+		}
+		disable, err := handler.readUint(filepath.Join(stateDir, "disable"))
+		if err != nil {
+			return states, err
+		}
+		st.Disabled = disable != 0
+		if st.Usage, err = handler.readUint(filepath.Join(stateDir, "usage")); err != nil {
+			return states, err
+		}
+		if st.Time, err = handler.readUint(filepath.Join(stateDir, "time")); err != nil {
+			return states, err
+		}
+		states = append(states, st)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Index < states[j].Index
+	})
+	return states, nil
+}
+
+func (handler *Handler) readString(path string) (string, error) {
+	data, err := handler.fs.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (handler *Handler) readUint(path string) (uint64, error) {
+	val, err := handler.readString(path)
+	if err != nil {
+		return 0, err
+	}
+	res, err := strconv.ParseUint(val, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed value in %q: %v", path, err)
+	}
+	return res, nil
+}
@@ -0,0 +1,155 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package cpupower_test
+
+import (
+	"io/ioutil"
+	"log"
+	"reflect"
+	"testing"
+	"time"
+
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/cpupower"
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+)
+
+var nullLog = log.New(ioutil.Discard, "", 0)
+
+func newFakeSysFS() *fswrap.MemFS {
+	mfs := fswrap.NewMemFS(map[string]string{
+		"/sys/devices/system/cpu/cpufreq/policy2/scaling_governor": "powersave\n",
+		"/sys/devices/system/cpu/cpufreq/policy2/scaling_driver":   "intel_pstate\n",
+		"/sys/devices/system/cpu/cpufreq/policy2/scaling_min_freq": "800000\n",
+		"/sys/devices/system/cpu/cpufreq/policy2/scaling_max_freq": "3500000\n",
+		"/sys/devices/system/cpu/cpufreq/policy2/scaling_cur_freq": "1200000\n",
+		"/sys/devices/system/cpu/cpufreq/policy2/cpuinfo_min_freq": "800000\n",
+		"/sys/devices/system/cpu/cpufreq/policy2/cpuinfo_max_freq": "3500000\n",
+		"/sys/devices/system/cpu/cpu2/cpuidle/state0/name":         "POLL\n",
+		"/sys/devices/system/cpu/cpu2/cpuidle/state0/latency":      "0\n",
+		"/sys/devices/system/cpu/cpu2/cpuidle/state0/disable":      "0\n",
+		"/sys/devices/system/cpu/cpu2/cpuidle/state0/usage":        "100\n",
+		"/sys/devices/system/cpu/cpu2/cpuidle/state0/time":         "1000\n",
+		"/sys/devices/system/cpu/cpu2/cpuidle/state1/name":         "C1\n",
+		"/sys/devices/system/cpu/cpu2/cpuidle/state1/latency":      "2\n",
+		"/sys/devices/system/cpu/cpu2/cpuidle/state1/disable":      "0\n",
+		"/sys/devices/system/cpu/cpu2/cpuidle/state1/usage":        "2000\n",
+		"/sys/devices/system/cpu/cpu2/cpuidle/state1/time":         "400000\n",
+		"/sys/devices/system/cpu/cpu2/cpuidle/state2/name":         "C6\n",
+		"/sys/devices/system/cpu/cpu2/cpuidle/state2/latency":      "133\n",
+		"/sys/devices/system/cpu/cpu2/cpuidle/state2/disable":      "0\n",
+		"/sys/devices/system/cpu/cpu2/cpuidle/state2/usage":        "50\n",
+		"/sys/devices/system/cpu/cpu2/cpuidle/state2/time":         "900000\n",
+		"/sys/devices/system/cpu/cpu3/cpuidle/state0/name":         "POLL\n",
+		"/sys/devices/system/cpu/cpu3/cpuidle/state0/latency":      "0\n",
+		"/sys/devices/system/cpu/cpu3/cpuidle/state0/disable":      "0\n",
+		"/sys/devices/system/cpu/cpu3/cpuidle/state0/usage":        "7\n",
+		"/sys/devices/system/cpu/cpu3/cpuidle/state0/time":         "70\n",
+		"/sys/devices/system/cpu/cpu3/cpuidle/state1/name":         "C6\n",
+		"/sys/devices/system/cpu/cpu3/cpuidle/state1/latency":      "133\n",
+		"/sys/devices/system/cpu/cpu3/cpuidle/state1/disable":      "1\n",
+		"/sys/devices/system/cpu/cpu3/cpuidle/state1/usage":        "0\n",
+		"/sys/devices/system/cpu/cpu3/cpuidle/state1/time":         "0\n",
+	})
+	// like the real sysfs, the per-cpu cpufreq directory links to the policy
+	mfs.AddSymlink("/sys/devices/system/cpu/cpu2/cpufreq", "../cpufreq/policy2")
+	return mfs
+}
+
+func TestRead(t *testing.T) {
+	infos, err := cpupower.NewWithFS(nullLog, newFakeSysFS(), "/sys").Read(cpuset.New(2, 3, 4, 5))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []cpupower.CPUInfo{
+		{
+			CPU: 2,
+			Frequency: &cpupower.Frequency{
+				Driver:   "intel_pstate",
+				Governor: "powersave",
+				Min:      800000,
+				Max:      3500000,
+				Cur:      1200000,
+				HWMin:    800000,
+				HWMax:    3500000,
+			},
+			IdleStates: []cpupower.IdleState{
+				{Index: 0, Name: "POLL", Latency: 0, Usage: 100, Time: 1000},
+				{Index: 1, Name: "C1", Latency: 2, Usage: 2000, Time: 400000},
+				{Index: 2, Name: "C6", Latency: 133, Usage: 50, Time: 900000},
+			},
+		},
+		{
+			CPU: 3,
+			IdleStates: []cpupower.IdleState{
+				{Index: 0, Name: "POLL", Latency: 0, Usage: 7, Time: 70},
+				{Index: 1, Name: "C6", Latency: 133, Disabled: true},
+			},
+		},
+	}
+	// cpu4 and cpu5 are not present
+	if !reflect.DeepEqual(infos, expected) {
+		t.Fatalf("got %+v expected %+v", infos, expected)
+	}
+
+	warnings := infos[0].Check(10)
+	expectedWarnings := []string{
+		`governor "powersave" can change the frequency in the 800000-3500000 kHz range`,
+		"idle state C6 enabled, exit latency 133us",
+	}
+	if !reflect.DeepEqual(warnings, expectedWarnings) {
+		t.Errorf("got warnings %v expected %v", warnings, expectedWarnings)
+	}
+	if warnings := infos[0].Check(200); len(warnings) != 1 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+	if warnings := infos[1].Check(10); len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+}
+
+func TestIdleResidency(t *testing.T) {
+	prev := []cpupower.CPUInfo{
+		{
+			CPU: 2,
+			IdleStates: []cpupower.IdleState{
+				{Index: 0, Name: "POLL", Usage: 100, Time: 1000},
+				{Index: 1, Name: "C1", Usage: 2000, Time: 400000},
+			},
+		},
+	}
+	last := []cpupower.CPUInfo{
+		{
+			CPU: 2,
+			IdleStates: []cpupower.IdleState{
+				{Index: 0, Name: "POLL", Usage: 100, Time: 1000},
+				{Index: 1, Name: "C1", Usage: 2100, Time: 900000},
+			},
+		},
+	}
+	res := cpupower.IdleResidency(prev, last, 2*time.Second)
+	expected := []cpupower.Residency{
+		{CPU: 2, State: "C1", Usage: 100, Time: 500000, Percent: 25},
+	}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("got %+v expected %+v", res, expected)
+	}
+	if res[0].String() != "CPU=2 STATE=C1 usage=+100 time=+500000us residency=25.00%" {
+		t.Errorf("unexpected rendering: %q", res[0].String())
+	}
+}
@@ -17,26 +17,51 @@
 package fswrap
 
 import (
-	"io/ioutil"
+	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
 )
 
-type FSWrapper struct {
+// FSWrapper is the read-only filesystem abstraction consumed by the knit packages.
+// It is a superset of io/fs.FS, but unlike io/fs.FS the implementations accept
+// both rooted ("/proc/interrupts") and unrooted ("proc/interrupts") names,
+// because the knit packages always build absolute paths from their procfs/sysfs roots.
+type FSWrapper interface {
+	fs.ReadFileFS
+	fs.ReadDirFS
+	fs.GlobFS
+}
+
+// LinuxFS is a FSWrapper which reads from the host filesystem.
+// If a logger is set, it logs all the accesses.
+type LinuxFS struct {
 	Log *log.Logger
 }
 
-func (fs FSWrapper) Open(name string) (*os.File, error) {
-	fs.Log.Printf("fswrap %-8s %q", "Open", name)
+func (lfs LinuxFS) Open(name string) (fs.File, error) {
+	lfs.trace("Open", name)
 	return os.Open(name)
 }
 
-func (fs FSWrapper) ReadFile(filename string) ([]byte, error) {
-	fs.Log.Printf("fswrap %-8s %q", "ReadFile", filename)
-	return ioutil.ReadFile(filename)
+func (lfs LinuxFS) ReadFile(filename string) ([]byte, error) {
+	lfs.trace("ReadFile", filename)
+	return os.ReadFile(filename)
+}
+
+func (lfs LinuxFS) ReadDir(dirname string) ([]fs.DirEntry, error) {
+	lfs.trace("ReadDir", dirname)
+	return os.ReadDir(dirname)
+}
+
+func (lfs LinuxFS) Glob(pattern string) ([]string, error) {
+	lfs.trace("Glob", pattern)
+	return filepath.Glob(pattern)
 }
 
-func (fs FSWrapper) ReadDir(dirname string) ([]os.FileInfo, error) {
-	fs.Log.Printf("fswrap %-8s %q", "ReadDir", dirname)
-	return ioutil.ReadDir(dirname)
+func (lfs LinuxFS) trace(op, name string) {
+	if lfs.Log == nil {
+		return
+	}
+	lfs.Log.Printf("fswrap %-8s %q", op, name)
 }
@@ -0,0 +1,177 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package fswrap_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+)
+
+var _ fswrap.FSWrapper = fswrap.LinuxFS{}
+var _ fswrap.FSWrapper = &fswrap.MemFS{}
+var _ fswrap.FSWrapper = &fswrap.TarFS{}
+
+func TestMemFSReadFile(t *testing.T) {
+	mfs := newFakeSysFS()
+
+	for _, name := range []string{
+		"/sys/devices/pci0000:00/0000:00:1f.6/numa_node",
+		"sys/devices/pci0000:00/0000:00:1f.6/numa_node",
+		"/sys/bus/pci/devices/0000:00:1f.6/numa_node",
+	} {
+		data, err := mfs.ReadFile(name)
+		if err != nil {
+			t.Errorf("ReadFile(%q) failed: %v", name, err)
+			continue
+		}
+		if string(data) != "0\n" {
+			t.Errorf("ReadFile(%q) content mismatch: got %q", name, string(data))
+		}
+	}
+
+	if _, err := mfs.ReadFile("/sys/devices/system/node/node2/cpulist"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected ErrNotExist, got %v", err)
+	}
+	if _, err := mfs.ReadFile("/sys/devices/system/node"); err == nil {
+		t.Errorf("expected error reading a directory, got none")
+	}
+}
+
+func TestMemFSReadDir(t *testing.T) {
+	mfs := newFakeSysFS()
+
+	ents, err := mfs.ReadDir("/sys/devices/system/node")
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	var names []string
+	for _, ent := range ents {
+		if !ent.IsDir() {
+			t.Errorf("expected %q to be a directory", ent.Name())
+		}
+		names = append(names, ent.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"node0", "node1"}) {
+		t.Errorf("ReadDir content mismatch: got %v", names)
+	}
+
+	ents, err = mfs.ReadDir("/sys/bus/pci/devices")
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if len(ents) != 1 || ents[0].Type() != fs.ModeSymlink {
+		t.Errorf("ReadDir expected one symlink, got %v", ents)
+	}
+}
+
+func TestMemFSGlob(t *testing.T) {
+	mfs := newFakeSysFS()
+
+	matches, err := mfs.Glob("/sys/devices/system/node/node*")
+	if err != nil {
+		t.Fatalf("Glob failed: %v", err)
+	}
+	expected := []string{"/sys/devices/system/node/node0", "/sys/devices/system/node/node1"}
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("Glob mismatch: got %v expected %v", matches, expected)
+	}
+
+	matches, err = mfs.Glob("sys/bus/pci/devices/*/numa_node")
+	if err != nil {
+		t.Fatalf("Glob failed: %v", err)
+	}
+	expected = []string{"sys/bus/pci/devices/0000:00:1f.6/numa_node"}
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("Glob mismatch: got %v expected %v", matches, expected)
+	}
+}
+
+func TestMemFSOpen(t *testing.T) {
+	mfs := newFakeSysFS()
+
+	fh, err := mfs.Open("/sys/devices/system/node/node1/cpulist")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer fh.Close()
+	data, err := io.ReadAll(fh)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if string(data) != "4-7\n" {
+		t.Errorf("content mismatch: got %q", string(data))
+	}
+}
+
+func TestTarFS(t *testing.T) {
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	for _, hdr := range []*tar.Header{
+		{Typeflag: tar.TypeDir, Name: "proc/irq/", Mode: 0755},
+		{Typeflag: tar.TypeReg, Name: "proc/irq/131/smp_affinity_list", Mode: 0644, Size: 4},
+		{Typeflag: tar.TypeSymlink, Name: "proc/irq/42", Linkname: "131", Mode: 0777},
+	} {
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("WriteHeader failed: %v", err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte("0-3\n")); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("tar Close failed: %v", err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatalf("gzip Close failed: %v", err)
+	}
+
+	tfs, err := fswrap.NewTarFS(&buf)
+	if err != nil {
+		t.Fatalf("NewTarFS failed: %v", err)
+	}
+	for _, name := range []string{"/proc/irq/131/smp_affinity_list", "/proc/irq/42/smp_affinity_list"} {
+		data, err := tfs.ReadFile(name)
+		if err != nil {
+			t.Errorf("ReadFile(%q) failed: %v", name, err)
+			continue
+		}
+		if string(data) != "0-3\n" {
+			t.Errorf("ReadFile(%q) content mismatch: got %q", name, string(data))
+		}
+	}
+}
+
+func newFakeSysFS() *fswrap.MemFS {
+	mfs := fswrap.NewMemFS(map[string]string{
+		"/sys/devices/system/node/node0/cpulist":         "0-3\n",
+		"/sys/devices/system/node/node1/cpulist":         "4-7\n",
+		"/sys/devices/pci0000:00/0000:00:1f.6/numa_node": "0\n",
+	})
+	mfs.AddSymlink("/sys/bus/pci/devices/0000:00:1f.6", "../../../devices/pci0000:00/0000:00:1f.6")
+	return mfs
+}
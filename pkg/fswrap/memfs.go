@@ -0,0 +1,295 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package fswrap
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const maxSymlinkHops = 40 // like the linux kernel
+
+var (
+	errIsDir        = errors.New("is a directory")
+	errNotDir       = errors.New("not a directory")
+	errTooManyLinks = errors.New("too many levels of symbolic links")
+)
+
+type memEntry struct {
+	name string // base name
+	mode fs.FileMode
+	data []byte
+	link string
+}
+
+// MemFS is a FSWrapper which holds all its content in memory.
+// Parent directories are created implicitly when entries are added.
+// The zero value is not usable, use NewMemFS instead.
+type MemFS struct {
+	// clean, unrooted path -> entry
+	entries map[string]*memEntry
+	// clean, unrooted directory path -> base names of its entries
+	dirents map[string]map[string]struct{}
+}
+
+// NewMemFS creates a MemFS holding the given files, expressed as
+// path -> content. The map can be nil, to create an empty MemFS.
+func NewMemFS(files map[string]string) *MemFS {
+	mfs := &MemFS{
+		entries: map[string]*memEntry{
+			".": {name: ".", mode: fs.ModeDir | 0755},
+		},
+		dirents: make(map[string]map[string]struct{}),
+	}
+	for name, content := range files {
+		mfs.AddFile(name, []byte(content))
+	}
+	return mfs
+}
+
+// AddFile adds (or replaces) the file `name` with the given data.
+func (mfs *MemFS) AddFile(name string, data []byte) {
+	mfs.add(name, &memEntry{mode: 0644, data: data})
+}
+
+// AddDir adds the directory `name`, if not already present.
+func (mfs *MemFS) AddDir(name string) {
+	if _, ok := mfs.entries[cleanName(name)]; ok {
+		return
+	}
+	mfs.add(name, &memEntry{mode: fs.ModeDir | 0755})
+}
+
+// AddSymlink adds (or replaces) the symlink `name` pointing to `target`.
+// Relative targets are resolved against the directory containing the symlink,
+// like on a real filesystem.
+func (mfs *MemFS) AddSymlink(name, target string) {
+	mfs.add(name, &memEntry{mode: fs.ModeSymlink | 0777, link: target})
+}
+
+func (mfs *MemFS) add(name string, entry *memEntry) {
+	key := cleanName(name)
+	entry.name = path.Base(key)
+	mfs.link(key, entry)
+	for dir := path.Dir(key); dir != "."; dir = path.Dir(dir) {
+		if _, ok := mfs.entries[dir]; ok {
+			break
+		}
+		mfs.link(dir, &memEntry{name: path.Base(dir), mode: fs.ModeDir | 0755})
+	}
+}
+
+func (mfs *MemFS) link(key string, entry *memEntry) {
+	mfs.entries[key] = entry
+	dir := path.Dir(key)
+	if mfs.dirents[dir] == nil {
+		mfs.dirents[dir] = make(map[string]struct{})
+	}
+	mfs.dirents[dir][entry.name] = struct{}{}
+}
+
+func (mfs *MemFS) Open(name string) (fs.File, error) {
+	key, entry, err := mfs.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return &memFile{
+		fsys:   mfs,
+		key:    key,
+		entry:  entry,
+		reader: bytes.NewReader(entry.data),
+	}, nil
+}
+
+func (mfs *MemFS) ReadFile(name string) ([]byte, error) {
+	_, entry, err := mfs.resolve("read", name)
+	if err != nil {
+		return nil, err
+	}
+	if entry.mode.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errIsDir}
+	}
+	return append([]byte{}, entry.data...), nil
+}
+
+func (mfs *MemFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	key, entry, err := mfs.resolve("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errNotDir}
+	}
+	return mfs.children(key), nil
+}
+
+func (mfs *MemFS) Glob(pattern string) ([]string, error) {
+	matches, err := fs.Glob(memView{mfs}, cleanName(pattern))
+	if err != nil || !path.IsAbs(pattern) {
+		return matches, err
+	}
+	for idx := range matches {
+		matches[idx] = "/" + matches[idx]
+	}
+	return matches, nil
+}
+
+func (mfs *MemFS) children(key string) []fs.DirEntry {
+	var ents []fs.DirEntry
+	for name := range mfs.dirents[key] {
+		entry := mfs.entries[path.Join(key, name)]
+		ents = append(ents, fs.FileInfoToDirEntry(memInfo{entry}))
+	}
+	sort.Slice(ents, func(i, j int) bool {
+		return ents[i].Name() < ents[j].Name()
+	})
+	return ents
+}
+
+// resolve finds the entry for the given name, following all the symlinks, including the last one.
+func (mfs *MemFS) resolve(op, name string) (string, *memEntry, error) {
+	key := cleanName(name)
+	for hops := 0; hops < maxSymlinkHops; hops++ {
+		var err error
+		var entry *memEntry
+		key, entry, err = mfs.walk(key)
+		if err != nil {
+			return "", nil, &fs.PathError{Op: op, Path: name, Err: err}
+		}
+		if entry.mode&fs.ModeSymlink == 0 {
+			return key, entry, nil
+		}
+		key = linkTarget(key, entry.link)
+	}
+	return "", nil, &fs.PathError{Op: op, Path: name, Err: errTooManyLinks}
+}
+
+// walk finds the entry for the given key, following the symlinks in all but the last component.
+func (mfs *MemFS) walk(key string) (string, *memEntry, error) {
+	if entry, ok := mfs.entries[key]; ok {
+		return key, entry, nil
+	}
+	components := strings.Split(key, "/")
+	for hops := 0; hops < maxSymlinkHops; hops++ {
+		resolved := false
+		for idx := 1; idx < len(components); idx++ {
+			prefix := path.Join(components[:idx]...)
+			entry, ok := mfs.entries[prefix]
+			if !ok {
+				return "", nil, fs.ErrNotExist
+			}
+			if entry.mode&fs.ModeSymlink == 0 {
+				continue
+			}
+			target := linkTarget(prefix, entry.link)
+			components = append(strings.Split(target, "/"), components[idx:]...)
+			resolved = true
+			break
+		}
+		key = path.Join(components...)
+		if entry, ok := mfs.entries[key]; ok {
+			return key, entry, nil
+		}
+		if !resolved {
+			return "", nil, fs.ErrNotExist
+		}
+	}
+	return "", nil, errTooManyLinks
+}
+
+func linkTarget(key, link string) string {
+	if path.IsAbs(link) {
+		return cleanName(link)
+	}
+	return cleanName(path.Join(path.Dir(key), link))
+}
+
+func cleanName(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// memView exposes MemFS to the io/fs helpers without the Glob method, to avoid recursion.
+type memView struct {
+	mfs *MemFS
+}
+
+func (mv memView) Open(name string) (fs.File, error) {
+	return mv.mfs.Open(name)
+}
+
+func (mv memView) ReadDir(name string) ([]fs.DirEntry, error) {
+	return mv.mfs.ReadDir(name)
+}
+
+type memInfo struct {
+	entry *memEntry
+}
+
+func (mi memInfo) Name() string       { return mi.entry.name }
+func (mi memInfo) Size() int64        { return int64(len(mi.entry.data)) }
+func (mi memInfo) Mode() fs.FileMode  { return mi.entry.mode }
+func (mi memInfo) ModTime() time.Time { return time.Time{} }
+func (mi memInfo) IsDir() bool        { return mi.entry.mode.IsDir() }
+func (mi memInfo) Sys() interface{}   { return nil }
+
+type memFile struct {
+	fsys   *MemFS
+	key    string
+	entry  *memEntry
+	reader *bytes.Reader
+	dirPos int
+}
+
+func (mf *memFile) Stat() (fs.FileInfo, error) {
+	return memInfo{mf.entry}, nil
+}
+
+func (mf *memFile) Read(buf []byte) (int, error) {
+	if mf.entry.mode.IsDir() {
+		return 0, &fs.PathError{Op: "read", Path: mf.key, Err: errIsDir}
+	}
+	return mf.reader.Read(buf)
+}
+
+func (mf *memFile) Close() error {
+	return nil
+}
+
+func (mf *memFile) ReadDir(count int) ([]fs.DirEntry, error) {
+	if !mf.entry.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: mf.key, Err: errNotDir}
+	}
+	ents := mf.fsys.children(mf.key)[mf.dirPos:]
+	if count > 0 && len(ents) > count {
+		ents = ents[:count]
+	}
+	mf.dirPos += len(ents)
+	if count > 0 && len(ents) == 0 {
+		return nil, io.EOF
+	}
+	return ents, nil
+}
@@ -0,0 +1,137 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package fswrap
+
+import (
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Recorder is a FSWrapper which forwards all the accesses to another FSWrapper,
+// and records what was accessed, along with the file contents, into a fixture.
+// Files are recorded only when they are read, while directories are recorded
+// when they are listed or globbed.
+type Recorder struct {
+	fsys FSWrapper
+	// clean, unrooted prefix on fsys -> clean, unrooted prefix in the fixture
+	roots    map[string]string
+	prefixes []string
+	lock     sync.Mutex
+	fixture  *MemFS
+}
+
+// NewRecorder creates a Recorder which wraps fsys. The `roots` map translates the
+// path prefixes on fsys to path prefixes in the fixture, so for example a procfs
+// mounted on "/host/proc" can be recorded as "proc". The map can be nil, to record
+// all the paths as they are.
+func NewRecorder(fsys FSWrapper, roots map[string]string) *Recorder {
+	rec := &Recorder{
+		fsys:    fsys,
+		roots:   make(map[string]string),
+		fixture: NewMemFS(nil),
+	}
+	for src, dst := range roots {
+		src = cleanName(src)
+		rec.roots[src] = cleanName(dst)
+		rec.prefixes = append(rec.prefixes, src)
+	}
+	// longest prefix first
+	sort.Slice(rec.prefixes, func(i, j int) bool {
+		return len(rec.prefixes[i]) > len(rec.prefixes[j])
+	})
+	return rec
+}
+
+// Fixture returns the recorded content. The recorder keeps updating it, so callers
+// should consume it once they are done with the accesses.
+func (rec *Recorder) Fixture() *MemFS {
+	return rec.fixture
+}
+
+func (rec *Recorder) Open(name string) (fs.File, error) {
+	data, err := rec.fsys.ReadFile(name)
+	if err != nil {
+		// most likely a directory: nothing to record until it is listed
+		return rec.fsys.Open(name)
+	}
+	rec.recordFile(name, data)
+	return NewMemFS(map[string]string{name: string(data)}).Open(name)
+}
+
+func (rec *Recorder) ReadFile(name string) ([]byte, error) {
+	data, err := rec.fsys.ReadFile(name)
+	if err != nil {
+		return data, err
+	}
+	rec.recordFile(name, data)
+	return data, nil
+}
+
+func (rec *Recorder) ReadDir(name string) ([]fs.DirEntry, error) {
+	ents, err := rec.fsys.ReadDir(name)
+	if err != nil {
+		return ents, err
+	}
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+	rec.fixture.AddDir(rec.rebase(name))
+	for _, ent := range ents {
+		if ent.IsDir() {
+			rec.fixture.AddDir(rec.rebase(path.Join(name, ent.Name())))
+		}
+	}
+	return ents, nil
+}
+
+func (rec *Recorder) Glob(pattern string) ([]string, error) {
+	matches, err := rec.fsys.Glob(pattern)
+	if err != nil {
+		return matches, err
+	}
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+	for _, match := range matches {
+		fi, err := fs.Stat(rec.fsys, match)
+		if err != nil || !fi.IsDir() {
+			continue
+		}
+		rec.fixture.AddDir(rec.rebase(match))
+	}
+	return matches, nil
+}
+
+func (rec *Recorder) recordFile(name string, data []byte) {
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+	rec.fixture.AddFile(rec.rebase(name), append([]byte{}, data...))
+}
+
+func (rec *Recorder) rebase(name string) string {
+	key := cleanName(name)
+	for _, prefix := range rec.prefixes {
+		if key == prefix {
+			return rec.roots[prefix]
+		}
+		if strings.HasPrefix(key, prefix+"/") {
+			return path.Join(rec.roots[prefix], strings.TrimPrefix(key, prefix+"/"))
+		}
+	}
+	return key
+}
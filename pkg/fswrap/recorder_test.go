@@ -0,0 +1,120 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package fswrap_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+)
+
+func TestRecorder(t *testing.T) {
+	src := fswrap.NewMemFS(map[string]string{
+		"/host/proc/interrupts":                 "           CPU0       CPU1\n",
+		"/host/proc/irq/131/smp_affinity_list":  "0-1\n",
+		"/host/proc/irq/131/enp0s31f6/.keep":    "",
+		"/host/sys/devices/system/node/node0/x": "",
+		"/host/sys/devices/system/node/node1/x": "",
+		"/host/proc/irq/131/effective_affinity": "0\n",
+		"/host/proc/irq/132/smp_affinity_list":  "1\n",
+		"/host/sys/devices/system/cpu/online":   "0-1\n",
+	})
+	rec := fswrap.NewRecorder(src, map[string]string{
+		"/host/proc": "proc",
+		"/host/sys":  "sys",
+	})
+
+	fh, err := rec.Open("/host/proc/interrupts")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if _, err := io.ReadAll(fh); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	fh.Close()
+	if _, err := rec.ReadFile("/host/proc/irq/131/smp_affinity_list"); err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if _, err := rec.ReadDir("/host/proc/irq/131"); err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if _, err := rec.Glob("/host/sys/devices/system/node/node*"); err != nil {
+		t.Fatalf("Glob failed: %v", err)
+	}
+	if _, err := rec.ReadFile("/host/sys/devices/system/cpu/missing"); err == nil {
+		t.Fatalf("ReadFile succeeded on a missing file")
+	}
+
+	var buf bytes.Buffer
+	if err := rec.Fixture().WriteTar(&buf); err != nil {
+		t.Fatalf("WriteTar failed: %v", err)
+	}
+	fixture, err := fswrap.NewTarFS(&buf)
+	if err != nil {
+		t.Fatalf("NewTarFS failed: %v", err)
+	}
+
+	for name, expected := range map[string]string{
+		"/proc/interrupts":                "           CPU0       CPU1\n",
+		"/proc/irq/131/smp_affinity_list": "0-1\n",
+	} {
+		data, err := fixture.ReadFile(name)
+		if err != nil {
+			t.Errorf("missing recorded file %q: %v", name, err)
+			continue
+		}
+		if string(data) != expected {
+			t.Errorf("content mismatch for %q: got %q expected %q", name, string(data), expected)
+		}
+	}
+
+	// not read, so not recorded
+	for _, name := range []string{
+		"/proc/irq/131/effective_affinity",
+		"/proc/irq/132/smp_affinity_list",
+		"/sys/devices/system/cpu/online",
+	} {
+		if _, err := fixture.ReadFile(name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("unexpected recorded file %q: %v", name, err)
+		}
+	}
+
+	ents, err := fixture.ReadDir("/proc/irq/131")
+	if err != nil {
+		t.Fatalf("missing recorded directory: %v", err)
+	}
+	var names []string
+	for _, ent := range ents {
+		names = append(names, ent.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"enp0s31f6", "smp_affinity_list"}) {
+		t.Errorf("recorded directory content mismatch: got %v", names)
+	}
+
+	matches, err := fixture.Glob("/sys/devices/system/node/node*")
+	if err != nil {
+		t.Fatalf("Glob failed: %v", err)
+	}
+	if !reflect.DeepEqual(matches, []string{"/sys/devices/system/node/node0", "/sys/devices/system/node/node1"}) {
+		t.Errorf("recorded glob mismatch: got %v", matches)
+	}
+}
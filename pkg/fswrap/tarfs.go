@@ -0,0 +1,137 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package fswrap
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// TarFS is a FSWrapper which serves the content of a tarball, like the ones
+// created by `knit snapshot` or by ghw-snapshot. The tarball is fully loaded in memory.
+type TarFS struct {
+	*MemFS
+}
+
+// OpenTarFS creates a TarFS from the tarball at `path`, which may be gzip-compressed.
+func OpenTarFS(path string) (*TarFS, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	return NewTarFS(fh)
+}
+
+// NewTarFS creates a TarFS reading the tarball from `r`, which may be gzip-compressed.
+func NewTarFS(r io.Reader) (*TarFS, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(gzipMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	var src io.Reader = br
+	if bytes.Equal(magic, gzipMagic) {
+		gzr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gzr.Close()
+		src = gzr
+	}
+
+	mfs := NewMemFS(nil)
+	tr := tar.NewReader(src)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			mfs.AddDir(hdr.Name)
+		case tar.TypeSymlink:
+			mfs.AddSymlink(hdr.Name, hdr.Linkname)
+		case tar.TypeReg:
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, fmt.Errorf("reading %q: %w", hdr.Name, err)
+			}
+			mfs.AddFile(hdr.Name, data)
+		}
+	}
+	return &TarFS{MemFS: mfs}, nil
+}
+
+// WriteTar writes the content of the MemFS as gzip-compressed tarball, which can be read back
+// by NewTarFS, or consumed by `knit --snapshot`.
+func (mfs *MemFS) WriteTar(w io.Writer) error {
+	var keys []string
+	for key := range mfs.entries {
+		if key == "." {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	gzw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gzw)
+	for _, key := range keys {
+		entry := mfs.entries[key]
+		hdr := &tar.Header{
+			Name: key,
+			Mode: int64(entry.mode.Perm()),
+		}
+		switch {
+		case entry.mode.IsDir():
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+		case entry.mode&fs.ModeSymlink != 0:
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = entry.link
+		default:
+			hdr.Typeflag = tar.TypeReg
+			hdr.Size = int64(len(entry.data))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if _, err := tw.Write(entry.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gzw.Close()
+}
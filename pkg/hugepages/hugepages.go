@@ -0,0 +1,234 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+// Package hugepages reads the hugepages pools from sysfs, and checks the hugepages allocated to
+// the containers come from the NUMA nodes of their cpus.
+package hugepages
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+	"github.com/openshift-kni/debug-tools/pkg/kubeletstate"
+)
+
+const (
+	dirPrefix = "hugepages-"
+	// MemoryTypePrefix prefixes the hugepages memory types reported by podresources, like "hugepages-1Gi"
+	MemoryTypePrefix = "hugepages-"
+)
+
+// Pool is the hugepages pool of a given size.
+type Pool struct {
+	// SizeKB is the size of the pages, in kB
+	SizeKB  uint64 `json:"sizeKB"`
+	Total   uint64 `json:"total"`
+	Free    uint64 `json:"free"`
+	Surplus uint64 `json:"surplus"`
+	// Reserved pages are committed but not yet faulted in. The kernel reports them only system-wide.
+	Reserved uint64 `json:"reserved,omitempty"`
+}
+
+func (po Pool) String() string {
+	res := fmt.Sprintf("%8dkB total %6d free %6d surplus %6d", po.SizeKB, po.Total, po.Free, po.Surplus)
+	if po.Reserved > 0 {
+		res += fmt.Sprintf(" reserved %6d", po.Reserved)
+	}
+	return res
+}
+
+// NodePools are the hugepages pools of a NUMA node, sorted by page size.
+type NodePools struct {
+	Node  int    `json:"node"`
+	Pools []Pool `json:"pools"`
+}
+
+// Inventory holds the hugepages pools per NUMA node, and the system-wide ones.
+type Inventory struct {
+	Nodes  []NodePools `json:"nodes"`
+	System []Pool      `json:"system"`
+}
+
+type Handler struct {
+	log       *log.Logger
+	sysfsRoot string
+	fs        fswrap.FSWrapper
+}
+
+func New(logger *log.Logger, sysfsRoot string) *Handler {
+	return NewWithFS(logger, fswrap.LinuxFS{Log: logger}, sysfsRoot)
+}
+
+// NewWithFS creates a Handler which reads the sysfs content, rooted at sysfsRoot, from fsys.
+func NewWithFS(logger *log.Logger, fsys fswrap.FSWrapper, sysfsRoot string) *Handler {
+	return &Handler{
+		log:       logger,
+		sysfsRoot: sysfsRoot,
+		fs:        fsys,
+	}
+}
+
+// Read reads the hugepages pools. Nodes without hugepages support are reported with no pools.
+func (handler *Handler) Read() (Inventory, error) {
+	inv := Inventory{
+		Nodes: []NodePools{},
+	}
+	nodesDir := filepath.Join(handler.sysfsRoot, "devices", "system", "node")
+	entries, err := handler.fs.ReadDir(nodesDir)
+	if err != nil {
+		return inv, err
+	}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), "node") {
+			continue
+		}
+		node, err := strconv.Atoi(strings.TrimPrefix(entry.Name(), "node"))
+		if err != nil {
+			continue
+		}
+		pools, err := handler.readPools(filepath.Join(nodesDir, entry.Name(), "hugepages"), false)
+		if err != nil {
+			return inv, fmt.Errorf("error reading the hugepages of NUMA node %d: %v", node, err)
+		}
+		inv.Nodes = append(inv.Nodes, NodePools{
+			Node:  node,
+			Pools: pools,
+		})
+	}
+	sort.Slice(inv.Nodes, func(i, j int) bool {
+		return inv.Nodes[i].Node < inv.Nodes[j].Node
+	})
+
+	inv.System, err = handler.readPools(filepath.Join(handler.sysfsRoot, "kernel", "mm", "hugepages"), true)
+	if err != nil {
+		return inv, fmt.Errorf("error reading the system hugepages: %v", err)
+	}
+	return inv, nil
+}
+
+func (handler *Handler) readPools(hpDir string, withReserved bool) ([]Pool, error) {
+	pools := []Pool{}
+	entries, err := handler.fs.ReadDir(hpDir)
+	if err != nil {
+		// no hugepages support
+		handler.log.Printf("Error reading %q: %v", hpDir, err)
+		return pools, nil
+	}
+	for _, entry := range entries {
+		size := strings.TrimPrefix(entry.Name(), dirPrefix)
+		if size == entry.Name() || !strings.HasSuffix(size, "kB") {
+			continue
+		}
+		sizeKB, err := strconv.ParseUint(strings.TrimSuffix(size, "kB"), 10, 64)
+		if err != nil {
+			return pools, fmt.Errorf("malformed hugepages size %q: %v", entry.Name(), err)
+		}
+		poolDir := filepath.Join(hpDir, entry.Name())
+		pool := Pool{SizeKB: sizeKB}
+		counters := map[string]*uint64{
+			"nr_hugepages":      &pool.Total,
+			"free_hugepages":    &pool.Free,
+			"surplus_hugepages": &pool.Surplus,
+		}
+		if withReserved {
+			counters["resv_hugepages"] = &pool.Reserved
+		}
+		for name, val := range counters {
+			*val, err = handler.readUint(filepath.Join(poolDir, name))
+			if err != nil {
+				return pools, err
+			}
+		}
+		pools = append(pools, pool)
+	}
+	sort.Slice(pools, func(i, j int) bool {
+		return pools[i].SizeKB < pools[j].SizeKB
+	})
+	return pools, nil
+}
+
+func (handler *Handler) readUint(path string) (uint64, error) {
+	data, err := handler.fs.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	val, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed value in %q: %v", path, err)
+	}
+	return val, nil
+}
+
+// Allocation is a hugepages block allocated to a container, as reported by podresources.
+type Allocation struct {
+	Container string `json:"container"`
+	Type      string `json:"type"`
+	Size      uint64 `json:"size"`
+	Nodes     []int  `json:"nodes"`
+	// CPUNodes are the NUMA nodes of the container exclusive cpus
+	CPUNodes []int `json:"cpuNodes,omitempty"`
+	// Misaligned is set if the hugepages come from NUMA nodes the container cpus are not on
+	Misaligned bool `json:"misaligned,omitempty"`
+}
+
+func (al Allocation) String() string {
+	res := fmt.Sprintf("%s: %s %d bytes on NUMA nodes %v", al.Container, al.Type, al.Size, al.Nodes)
+	if len(al.CPUNodes) > 0 {
+		res += fmt.Sprintf(", cpus on NUMA nodes %v", al.CPUNodes)
+	}
+	if al.Misaligned {
+		res = "WARNING: " + res + " - misaligned"
+	}
+	return res
+}
+
+// Check lists the hugepages allocated to the containers, flagging the ones which come from NUMA nodes
+// different from the ones of the container exclusive cpus. The containers without exclusive cpus can't be misaligned.
+// cpuNodes maps the cpu ids to their NUMA node.
+func Check(containers []kubeletstate.PodResourcesContainer, cpuNodes map[int]int) []Allocation {
+	allocs := []Allocation{}
+	for _, cnt := range containers {
+		var nodes []int
+		for _, cpuid := range cnt.CPUs {
+			if node, ok := cpuNodes[cpuid]; ok {
+				nodes = append(nodes, node)
+			}
+		}
+		cpuNodeSet := cpuset.New(nodes...)
+		for _, block := range cnt.Memory {
+			if !strings.HasPrefix(block.Type, MemoryTypePrefix) {
+				continue
+			}
+			hpNodes := cpuset.New(block.NUMAAffinity...)
+			allocs = append(allocs, Allocation{
+				Container:  fmt.Sprintf("pod %s/%s container %s", cnt.Namespace, cnt.PodName, cnt.Name),
+				Type:       block.Type,
+				Size:       block.Size,
+				Nodes:      hpNodes.List(),
+				CPUNodes:   cpuNodeSet.List(),
+				Misaligned: !cpuNodeSet.IsEmpty() && !hpNodes.IsSubsetOf(cpuNodeSet),
+			})
+		}
+	}
+	return allocs
+}
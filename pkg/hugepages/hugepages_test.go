@@ -0,0 +1,125 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package hugepages_test
+
+import (
+	"io/ioutil"
+	"log"
+	"reflect"
+	"testing"
+
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+	"github.com/openshift-kni/debug-tools/pkg/hugepages"
+	"github.com/openshift-kni/debug-tools/pkg/kubeletstate"
+)
+
+var nullLog = log.New(ioutil.Discard, "", 0)
+
+func TestRead(t *testing.T) {
+	mfs := fswrap.NewMemFS(map[string]string{
+		"/sys/devices/system/node/node0/cpulist":                                         "0-3\n",
+		"/sys/devices/system/node/node0/hugepages/hugepages-2048kB/nr_hugepages":         "512\n",
+		"/sys/devices/system/node/node0/hugepages/hugepages-2048kB/free_hugepages":       "256\n",
+		"/sys/devices/system/node/node0/hugepages/hugepages-2048kB/surplus_hugepages":    "0\n",
+		"/sys/devices/system/node/node0/hugepages/hugepages-1048576kB/nr_hugepages":      "4\n",
+		"/sys/devices/system/node/node0/hugepages/hugepages-1048576kB/free_hugepages":    "1\n",
+		"/sys/devices/system/node/node0/hugepages/hugepages-1048576kB/surplus_hugepages": "0\n",
+		"/sys/devices/system/node/node1/cpulist":                                         "4-7\n",
+		"/sys/devices/system/node/node1/hugepages/hugepages-2048kB/nr_hugepages":         "0\n",
+		"/sys/devices/system/node/node1/hugepages/hugepages-2048kB/free_hugepages":       "0\n",
+		"/sys/devices/system/node/node1/hugepages/hugepages-2048kB/surplus_hugepages":    "2\n",
+		"/sys/devices/system/node/possible":                                              "0-1\n",
+		"/sys/kernel/mm/hugepages/hugepages-2048kB/nr_hugepages":                         "512\n",
+		"/sys/kernel/mm/hugepages/hugepages-2048kB/free_hugepages":                       "256\n",
+		"/sys/kernel/mm/hugepages/hugepages-2048kB/surplus_hugepages":                    "2\n",
+		"/sys/kernel/mm/hugepages/hugepages-2048kB/resv_hugepages":                       "16\n",
+	})
+
+	inv, err := hugepages.NewWithFS(nullLog, mfs, "/sys").Read()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := hugepages.Inventory{
+		Nodes: []hugepages.NodePools{
+			{
+				Node: 0,
+				Pools: []hugepages.Pool{
+					{SizeKB: 2048, Total: 512, Free: 256},
+					{SizeKB: 1048576, Total: 4, Free: 1},
+				},
+			},
+			{
+				Node: 1,
+				Pools: []hugepages.Pool{
+					{SizeKB: 2048, Surplus: 2},
+				},
+			},
+		},
+		System: []hugepages.Pool{
+			{SizeKB: 2048, Total: 512, Free: 256, Surplus: 2, Reserved: 16},
+		},
+	}
+	if !reflect.DeepEqual(inv, expected) {
+		t.Errorf("got %+v expected %+v", inv, expected)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	cpuNodes := map[int]int{0: 0, 1: 0, 2: 1, 3: 1}
+	containers := []kubeletstate.PodResourcesContainer{
+		{
+			Namespace: "ns",
+			PodName:   "aligned",
+			Name:      "cnt",
+			CPUs:      []int{0, 1},
+			Memory: []kubeletstate.MemoryBlock{
+				{Type: "memory", Size: 1 << 30, NUMAAffinity: []int{1}},
+				{Type: "hugepages-1Gi", Size: 2 << 30, NUMAAffinity: []int{0}},
+			},
+		},
+		{
+			Namespace: "ns",
+			PodName:   "misaligned",
+			Name:      "cnt",
+			CPUs:      []int{2, 3},
+			Memory: []kubeletstate.MemoryBlock{
+				{Type: "hugepages-2Mi", Size: 64 << 20, NUMAAffinity: []int{1, 0}},
+			},
+		},
+		{
+			Namespace: "ns",
+			PodName:   "shared",
+			Name:      "cnt",
+			Memory: []kubeletstate.MemoryBlock{
+				{Type: "hugepages-2Mi", Size: 64 << 20, NUMAAffinity: []int{1}},
+			},
+		},
+	}
+
+	got := hugepages.Check(containers, cpuNodes)
+	expected := []hugepages.Allocation{
+		{Container: "pod ns/aligned container cnt", Type: "hugepages-1Gi", Size: 2 << 30, Nodes: []int{0}, CPUNodes: []int{0}},
+		{Container: "pod ns/misaligned container cnt", Type: "hugepages-2Mi", Size: 64 << 20, Nodes: []int{0, 1}, CPUNodes: []int{1}, Misaligned: true},
+		{Container: "pod ns/shared container cnt", Type: "hugepages-2Mi", Size: 64 << 20, Nodes: []int{1}, CPUNodes: []int{}},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %+v expected %+v", got, expected)
+	}
+	if got[1].String() != "WARNING: pod ns/misaligned container cnt: hugepages-2Mi 67108864 bytes on NUMA nodes [0 1], cpus on NUMA nodes [1] - misaligned" {
+		t.Errorf("unexpected rendering: %q", got[1].String())
+	}
+}
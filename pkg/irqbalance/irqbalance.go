@@ -0,0 +1,257 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package irqbalance
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+	"github.com/openshift-kni/debug-tools/pkg/irqs"
+)
+
+const (
+	EnvBannedCPUs    = "IRQBALANCE_BANNED_CPUS"
+	EnvBannedCPUList = "IRQBALANCE_BANNED_CPULIST"
+	EnvArgs          = "IRQBALANCE_ARGS"
+	EnvOneShot       = "IRQBALANCE_ONESHOT"
+
+	// BannedFromKernel means no cpu is banned in the configuration, so irqbalance
+	// bans the isolated cpus found in the kernel command line (isolcpus, nohz_full).
+	BannedFromKernel = "kernel"
+)
+
+// the configuration files, relative to the etc root: RHEL and derivatives, then Debian and derivatives.
+var configPaths = []string{
+	filepath.Join("sysconfig", "irqbalance"),
+	filepath.Join("default", "irqbalance"),
+}
+
+// Config is the irqbalance configuration, as found in its environment file.
+type Config struct {
+	// Path is the configuration file found, empty if none
+	Path string            `json:"path,omitempty"`
+	Env  map[string]string `json:"env,omitempty"`
+	// BannedFrom tells where the banned cpus come from: EnvBannedCPUList, EnvBannedCPUs, BannedFromKernel
+	BannedFrom string        `json:"bannedFrom,omitempty"`
+	BannedCPUs cpuset.CPUSet `json:"-"`
+	// BannedIRQs are the IRQs irqbalance is told not to balance (--banirq)
+	BannedIRQs []int `json:"bannedIRQs,omitempty"`
+	// PolicyScript (--policyscript) and BanScript (--banscript, older versions) can ban IRQs at runtime
+	PolicyScript string `json:"policyScript,omitempty"`
+	BanScript    string `json:"banScript,omitempty"`
+	OneShot      bool   `json:"oneShot,omitempty"`
+}
+
+// ParseEnv parses a shell-like environment file, like the irqbalance configuration.
+// Comments and blank lines are skipped, values can be quoted.
+func ParseEnv(data string) map[string]string {
+	env := make(map[string]string)
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		idx := strings.Index(line, "=")
+		if idx <= 0 {
+			continue
+		}
+		key := strings.TrimSpace(line[:idx])
+		value := strings.TrimSpace(line[idx+1:])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		env[key] = value
+	}
+	return env
+}
+
+// ParseCPUMask parses a hex cpu mask, like "0000ff00" or "00000001,0000ff00" (comma-separated 32 bit words).
+func ParseCPUMask(mask string) (cpuset.CPUSet, error) {
+	digits := strings.TrimPrefix(strings.ReplaceAll(strings.TrimSpace(mask), ",", ""), "0x")
+	var cpus []int
+	for idx := 0; idx < len(digits); idx++ {
+		nibble, err := strconv.ParseUint(digits[len(digits)-1-idx:len(digits)-idx], 16, 8)
+		if err != nil {
+			return cpuset.New(), fmt.Errorf("malformed cpu mask %q: %v", mask, err)
+		}
+		for bit := 0; bit < 4; bit++ {
+			if nibble&(1<<bit) != 0 {
+				cpus = append(cpus, idx*4+bit)
+			}
+		}
+	}
+	return cpuset.New(cpus...), nil
+}
+
+// ParseConfig builds the Config from the irqbalance environment. IRQBALANCE_BANNED_CPULIST,
+// supported by newer irqbalance versions, takes precedence over IRQBALANCE_BANNED_CPUS.
+func ParseConfig(env map[string]string) (Config, error) {
+	conf := Config{
+		Env:        env,
+		BannedCPUs: cpuset.New(),
+	}
+	var err error
+	if val := env[EnvBannedCPUList]; val != "" {
+		conf.BannedFrom = EnvBannedCPUList
+		conf.BannedCPUs, err = cpuset.Parse(val)
+		if err != nil {
+			return conf, fmt.Errorf("malformed %s %q: %v", EnvBannedCPUList, val, err)
+		}
+	} else if val := env[EnvBannedCPUs]; val != "" {
+		conf.BannedFrom = EnvBannedCPUs
+		conf.BannedCPUs, err = ParseCPUMask(val)
+		if err != nil {
+			return conf, err
+		}
+	}
+	conf.OneShot = env[EnvOneShot] != "" && env[EnvOneShot] != "0"
+
+	args := strings.Fields(env[EnvArgs])
+	for idx := 0; idx < len(args); idx++ {
+		name, value := args[idx], ""
+		if eq := strings.Index(name, "="); eq >= 0 {
+			name, value = name[:eq], name[eq+1:]
+		} else if idx+1 < len(args) && (name == "-i" || name == "--banirq" || name == "-l" || name == "--policyscript" || name == "--banscript") {
+			idx++
+			value = args[idx]
+		}
+		switch name {
+		case "-i", "--banirq":
+			irq, err := strconv.Atoi(value)
+			if err != nil {
+				return conf, fmt.Errorf("malformed banned irq %q: %v", value, err)
+			}
+			conf.BannedIRQs = append(conf.BannedIRQs, irq)
+		case "-l", "--policyscript":
+			conf.PolicyScript = value
+		case "--banscript":
+			conf.BanScript = value
+		case "-o", "--oneshot":
+			conf.OneShot = true
+		}
+	}
+	return conf, nil
+}
+
+// WithKernelBanned returns the config with the banned cpus irqbalance uses if the configuration bans none:
+// the isolated cpus found in the kernel command line.
+func (conf Config) WithKernelBanned(kernelIsolated cpuset.CPUSet) Config {
+	if conf.BannedFrom != "" {
+		return conf
+	}
+	conf.BannedFrom = BannedFromKernel
+	conf.BannedCPUs = kernelIsolated
+	return conf
+}
+
+type Handler struct {
+	log     *log.Logger
+	etcRoot string
+	fs      fswrap.FSWrapper
+}
+
+func New(logger *log.Logger, etcRoot string) *Handler {
+	return NewWithFS(logger, fswrap.LinuxFS{Log: logger}, etcRoot)
+}
+
+// NewWithFS creates a Handler which reads the configuration, rooted at etcRoot, from fsys.
+func NewWithFS(logger *log.Logger, fsys fswrap.FSWrapper, etcRoot string) *Handler {
+	return &Handler{
+		log:     logger,
+		etcRoot: etcRoot,
+		fs:      fsys,
+	}
+}
+
+// Read reads the first irqbalance configuration file found. A missing configuration is not an error:
+// irqbalance runs with its defaults, and the returned Config has an empty Path.
+func (handler *Handler) Read() (Config, error) {
+	for _, configPath := range configPaths {
+		fullPath := filepath.Join(handler.etcRoot, configPath)
+		data, err := handler.fs.ReadFile(fullPath)
+		if err != nil {
+			handler.log.Printf("Error reading %q: %v", fullPath, err)
+			continue
+		}
+		conf, err := ParseConfig(ParseEnv(string(data)))
+		conf.Path = fullPath
+		return conf, err
+	}
+	return ParseConfig(map[string]string{})
+}
+
+// IRQOnBanned is a IRQ whose affinity includes banned cpus.
+type IRQOnBanned struct {
+	IRQ     int    `json:"irq"`
+	Source  string `json:"source"`
+	CPUs    []int  `json:"cpus"`
+	Banned  []int  `json:"banned"`
+	Managed bool   `json:"managed,omitempty"`
+}
+
+func (iob IRQOnBanned) String() string {
+	res := fmt.Sprintf("IRQ %3d [%24s]: can run on banned cpus %v", iob.IRQ, iob.Source, iob.Banned)
+	if iob.Managed {
+		res += " (managed)"
+	}
+	return res
+}
+
+// Report is the consistency check of the irqbalance configuration.
+type Report struct {
+	Config   Config `json:"config"`
+	Isolated []int  `json:"isolated"`
+	Banned   []int  `json:"banned"`
+	// IsolatedNotBanned are the isolated cpus irqbalance can move IRQs to
+	IsolatedNotBanned []int `json:"isolatedNotBanned"`
+	// BannedNotIsolated are the housekeeping cpus irqbalance won't use
+	BannedNotIsolated []int         `json:"bannedNotIsolated"`
+	IRQsOnBanned      []IRQOnBanned `json:"irqsOnBanned"`
+}
+
+// Check compares the banned cpus with the isolated cpus, and with the current IRQ affinities.
+func Check(conf Config, isolated cpuset.CPUSet, infos []irqs.Info) Report {
+	report := Report{
+		Config:            conf,
+		Isolated:          isolated.List(),
+		Banned:            conf.BannedCPUs.List(),
+		IsolatedNotBanned: isolated.Difference(conf.BannedCPUs).List(),
+		BannedNotIsolated: conf.BannedCPUs.Difference(isolated).List(),
+		IRQsOnBanned:      []IRQOnBanned{},
+	}
+	for _, info := range infos {
+		banned := info.CPUs.Intersection(conf.BannedCPUs)
+		if banned.Size() == 0 {
+			continue
+		}
+		report.IRQsOnBanned = append(report.IRQsOnBanned, IRQOnBanned{
+			IRQ:     info.IRQ,
+			Source:  info.Source,
+			CPUs:    info.CPUs.List(),
+			Banned:  banned.List(),
+			Managed: info.Managed,
+		})
+	}
+	return report
+}
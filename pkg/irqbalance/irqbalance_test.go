@@ -0,0 +1,137 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package irqbalance_test
+
+import (
+	"io/ioutil"
+	"log"
+	"reflect"
+	"testing"
+
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+	"github.com/openshift-kni/debug-tools/pkg/irqbalance"
+	"github.com/openshift-kni/debug-tools/pkg/irqs"
+)
+
+var nullLog = log.New(ioutil.Discard, "", 0)
+
+func TestParseCPUMask(t *testing.T) {
+	testCases := []struct {
+		mask     string
+		expected cpuset.CPUSet
+		expError bool
+	}{
+		{mask: "0000000c", expected: cpuset.New(2, 3)},
+		{mask: "00000001,00000000", expected: cpuset.New(32)},
+		{mask: "0xff", expected: cpuset.New(0, 1, 2, 3, 4, 5, 6, 7)},
+		{mask: "0", expected: cpuset.New()},
+		{mask: "fz", expError: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.mask, func(t *testing.T) {
+			cpus, err := irqbalance.ParseCPUMask(tc.mask)
+			if (err != nil) != tc.expError {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tc.expError && !cpus.Equals(tc.expected) {
+				t.Errorf("got %v expected %v", cpus, tc.expected)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	testCases := []struct {
+		name         string
+		files        map[string]string
+		path         string
+		bannedFrom   string
+		banned       cpuset.CPUSet
+		bannedIRQs   []int
+		policyScript string
+	}{
+		{
+			name:   "missing",
+			banned: cpuset.New(),
+		},
+		{
+			name: "sysconfig mask",
+			files: map[string]string{
+				"/etc/sysconfig/irqbalance": "# IRQBALANCE_BANNED_CPULIST=1\nIRQBALANCE_BANNED_CPUS=\"000000f0\"\nIRQBALANCE_ARGS=\"--banirq=28 -i 30 --policyscript /usr/bin/policy\"\n",
+			},
+			path:         "/etc/sysconfig/irqbalance",
+			bannedFrom:   irqbalance.EnvBannedCPUs,
+			banned:       cpuset.New(4, 5, 6, 7),
+			bannedIRQs:   []int{28, 30},
+			policyScript: "/usr/bin/policy",
+		},
+		{
+			name: "default cpulist",
+			files: map[string]string{
+				"/etc/default/irqbalance": "export IRQBALANCE_BANNED_CPUS=ff\nIRQBALANCE_BANNED_CPULIST='2-3'\n",
+			},
+			path:       "/etc/default/irqbalance",
+			bannedFrom: irqbalance.EnvBannedCPUList,
+			banned:     cpuset.New(2, 3),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, err := irqbalance.NewWithFS(nullLog, fswrap.NewMemFS(tc.files), "/etc").Read()
+			if err != nil {
+				t.Fatalf("Read failed: %v", err)
+			}
+			if conf.Path != tc.path || conf.BannedFrom != tc.bannedFrom || !conf.BannedCPUs.Equals(tc.banned) {
+				t.Errorf("unexpected config: %+v", conf)
+			}
+			if !reflect.DeepEqual(conf.BannedIRQs, tc.bannedIRQs) || conf.PolicyScript != tc.policyScript {
+				t.Errorf("unexpected args: %+v", conf)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	conf, err := irqbalance.ParseConfig(map[string]string{})
+	if err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+	conf = conf.WithKernelBanned(cpuset.New(2, 3, 4))
+	if conf.BannedFrom != irqbalance.BannedFromKernel {
+		t.Errorf("unexpected banned from: %q", conf.BannedFrom)
+	}
+
+	infos := []irqs.Info{
+		{IRQ: 24, Source: "eth0", CPUs: cpuset.New(0, 1)},
+		{IRQ: 25, Source: "eth0-rx-1", CPUs: cpuset.New(0, 1, 2, 3, 4, 5, 6, 7)},
+		{IRQ: 26, Source: "nvme0q3", CPUs: cpuset.New(3), Managed: true},
+	}
+	report := irqbalance.Check(conf, cpuset.New(2, 3, 4, 5), infos)
+
+	if !reflect.DeepEqual(report.IsolatedNotBanned, []int{5}) || !reflect.DeepEqual(report.BannedNotIsolated, []int{}) {
+		t.Errorf("unexpected cpus check: %+v", report)
+	}
+	expected := []irqbalance.IRQOnBanned{
+		{IRQ: 25, Source: "eth0-rx-1", CPUs: []int{0, 1, 2, 3, 4, 5, 6, 7}, Banned: []int{2, 3, 4}},
+		{IRQ: 26, Source: "nvme0q3", CPUs: []int{3}, Banned: []int{3}, Managed: true},
+	}
+	if !reflect.DeepEqual(report.IRQsOnBanned, expected) {
+		t.Errorf("IRQs on banned cpus mismatch\ngot      %+v\nexpected %+v", report.IRQsOnBanned, expected)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"io"
 	"log"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -77,26 +78,51 @@ func (S Stats) Clone() Stats {
 	return R
 }
 
+// how the managed state of a IRQ was detected
+const (
+	ManagedFromDebugFS   = "debugfs"
+	ManagedFromHeuristic = "heuristic"
+)
+
 type Info struct {
+	// Source is the first action found in /proc/irq. Resolver describes the IRQ much better.
 	Source string
 	IRQ    int
 	CPUs   cpuset.CPUSet
+	// Managed is true for the kernel-managed IRQs, whose affinity is set by the kernel
+	// and can't be changed using smp_affinity, see isolcpus=managed_irq.
+	Managed bool
+	// ManagedFrom is either ManagedFromDebugFS or ManagedFromHeuristic
+	ManagedFrom string
 }
 
 type Handler struct {
-	log        *log.Logger
-	procfsRoot string
-	fs         fswrap.FSWrapper
+	log         *log.Logger
+	procfsRoot  string
+	debugfsRoot string
+	fs          fswrap.FSWrapper
 }
 
 func New(logger *log.Logger, procfsRoot string) *Handler {
+	return NewWithFS(logger, fswrap.LinuxFS{Log: logger}, procfsRoot)
+}
+
+// NewWithFS creates a Handler which reads the procfs content, rooted at procfsRoot, from fsys.
+func NewWithFS(logger *log.Logger, fsys fswrap.FSWrapper, procfsRoot string) *Handler {
 	return &Handler{
 		log:        logger,
 		procfsRoot: procfsRoot,
-		fs:         fswrap.FSWrapper{Log: logger},
+		fs:         fsys,
 	}
 }
 
+// WithDebugFS makes the Handler detect the managed IRQs using the debugfs content, rooted at debugfsRoot
+// (usually /sys/kernel/debug). Without debugfs, or if it is not mounted, the Handler falls back to heuristics.
+func (handler *Handler) WithDebugFS(debugfsRoot string) *Handler {
+	handler.debugfsRoot = debugfsRoot
+	return handler
+}
+
 func (handler *Handler) ReadInfo(flags uint) ([]Info, error) {
 	// the best source of information here is man 5 procfs
 	// and https://www.kernel.org/doc/Documentation/IRQ-affinity.txt
@@ -123,7 +149,7 @@ func (handler *Handler) ReadInfo(flags uint) ([]Info, error) {
 		affinityListFile = "effective_affinity_list"
 	}
 
-	irqInfos := make([]Info, len(irqs))
+	irqInfos := make([]Info, 0, len(irqs))
 	for _, irq := range irqs {
 		irqDir := filepath.Join(irqRoot, fmt.Sprintf("%d", irq))
 
@@ -138,11 +164,13 @@ func (handler *Handler) ReadInfo(flags uint) ([]Info, error) {
 			continue // keep running
 		}
 
-		irqInfos = append(irqInfos, Info{
+		info := Info{
 			CPUs:   irqCpus,
 			IRQ:    irq,
 			Source: handler.findSourceForIRQ(irq),
-		})
+		}
+		info.Managed, info.ManagedFrom = handler.isManaged(irq, info.Source)
+		irqInfos = append(irqInfos, info)
 	}
 	return irqInfos, nil
 }
@@ -212,7 +240,8 @@ func parseInterrupts(logger *log.Logger, rd io.Reader) (Stats, error) {
 	return stats, nil
 }
 
-// TODO: we may want to crosscorrelate with `/proc/interrupts, which always give a valid (!= "") source
+// findSourceForIRQ returns the name of the first action registered in /proc/irq/<irq>, if any.
+// For the device, the driver and the queue served, cross-correlated with /proc/interrupts, see Resolver.
 func (handler *Handler) findSourceForIRQ(irq int) string {
 	irqDir := filepath.Join(handler.procfsRoot, "irq", fmt.Sprintf("%d", irq))
 	files, err := handler.fs.ReadDir(irqDir)
@@ -228,3 +257,21 @@ func (handler *Handler) findSourceForIRQ(irq int) string {
 	handler.log.Printf("Cannot find source for irq %d", irq)
 	return ""
 }
+
+// managed IRQs are allocated with an affinity spread by the kernel (see pci_alloc_irq_vectors_affinity).
+// Without debugfs, we recognize the queues of the drivers known to do so: nvme (except the admin queue),
+// virtio-blk and virtio-scsi.
+var managedSourceRE = regexp.MustCompile(`^(nvme\d+q[1-9]\d*|virtio\d+-(req|request)\.\d+)$`)
+
+// isManaged tells if the IRQ is kernel-managed, and how it was detected. The debugfs irq/irqs/<irq> file
+// lists the IRQD_AFFINITY_MANAGED state flag (see kernel/irq/debugfs.c).
+func (handler *Handler) isManaged(irq int, source string) (bool, string) {
+	if handler.debugfsRoot != "" {
+		data, err := handler.fs.ReadFile(filepath.Join(handler.debugfsRoot, "irq", "irqs", strconv.Itoa(irq)))
+		if err == nil {
+			return strings.Contains(string(data), "IRQD_AFFINITY_MANAGED"), ManagedFromDebugFS
+		}
+		handler.log.Printf("Error reading the debugfs state of irq %d: %v", irq, err)
+	}
+	return managedSourceRE.MatchString(source), ManagedFromHeuristic
+}
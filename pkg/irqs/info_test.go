@@ -33,6 +33,7 @@ import (
 
 	cpuset "k8s.io/utils/cpuset"
 
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
 	"github.com/openshift-kni/debug-tools/pkg/irqs"
 )
 
@@ -180,6 +181,9 @@ func TestReportingStatsJSON(t *testing.T) {
 	reporter := irqs.NewReporter(&buf, jsonOutput, verboseMode, cpus)
 	reporter.Delta(lastTime, prevStats, lastStats)
 
+	if !strings.Contains(buf.String(), `"kind":"IRQ"`) {
+		t.Errorf("missing kind in %s", buf.String())
+	}
 	err = checkInterrupsDiffJSON(buf, irqsDiffTestCases)
 	if err != nil {
 		t.Errorf("Error reporting JSON: %v", err)
@@ -192,6 +196,12 @@ func TestReportingStatsJSON(t *testing.T) {
 	if err != nil {
 		t.Errorf("Error reporting JSON: %v", err)
 	}
+
+	buf.Reset()
+	irqs.NewSoftirqReporter(&buf, jsonOutput, verboseMode, cpus).Summary(initTs, initStats, lastStats)
+	if !strings.Contains(buf.String(), `"kind":"SOFTIRQ"`) {
+		t.Errorf("missing kind in %s", buf.String())
+	}
 }
 
 type irqAffinity struct {
@@ -319,3 +329,98 @@ var fakeStatsLast irqs.Stats = map[int]irqs.Counter{
 	2: {"0": 0, "1": 0, "8": 4, "12": 0},
 	3: {"0": 0, "1": 0, "8": 0, "12": 717},
 }
+
+func TestReadInfoFromSnapshot(t *testing.T) {
+	fsys, err := fswrap.OpenTarFS(filepath.Join("..", "..", "test", "data", "dell_2_numa", "sysinfo.tgz"))
+	if err != nil {
+		t.Fatalf("error opening the snapshot: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join("..", "..", "test", "data", "dell_2_numa", "irqaff.json"))
+	if err != nil {
+		t.Fatalf("error reading the expected data: %v", err)
+	}
+	var expected []struct {
+		IRQ         int    `json:"irq"`
+		Source      string `json:"source"`
+		CPUAffinity []int  `json:"affinity"`
+	}
+	if err := json.Unmarshal(data, &expected); err != nil {
+		t.Fatalf("error decoding the expected data: %v", err)
+	}
+
+	ih := irqs.NewWithFS(nullLog, fsys, "/proc")
+	irqInfos, err := ih.ReadInfo(0)
+	if err != nil {
+		t.Fatalf("error parsing irqs from the snapshot: %v", err)
+	}
+
+	if len(irqInfos) != len(expected) {
+		t.Fatalf("IRQ count mismatch got %d expected %d", len(irqInfos), len(expected))
+	}
+	for i, exp := range expected {
+		got := irqInfos[i]
+		if got.IRQ != exp.IRQ || got.Source != exp.Source || !reflect.DeepEqual(got.CPUs.List(), exp.CPUAffinity) {
+			t.Errorf("IRQ info mismatch got %v expected %v", got, exp)
+		}
+	}
+}
+
+func TestReadInfoManaged(t *testing.T) {
+	files := map[string]string{
+		"/proc/irq/126/smp_affinity_list": "0-3",
+		"/proc/irq/127/smp_affinity_list": "0",
+		"/proc/irq/131/smp_affinity_list": "0-3",
+		"/proc/irq/140/smp_affinity_list": "2",
+	}
+	fsys := fswrap.NewMemFS(files)
+	fsys.AddDir("/proc/irq/126/nvme0q0")
+	fsys.AddDir("/proc/irq/127/nvme0q1")
+	fsys.AddDir("/proc/irq/131/enp0s31f6")
+	fsys.AddDir("/proc/irq/140/i40e-ens1f0-TxRx-2")
+
+	testCases := []struct {
+		name        string
+		debugfs     map[string]string
+		managed     map[int]bool
+		managedFrom string
+	}{
+		{
+			name:        "heuristic",
+			managed:     map[int]bool{127: true},
+			managedFrom: irqs.ManagedFromHeuristic,
+		},
+		{
+			name: "debugfs",
+			debugfs: map[string]string{
+				"/sys/kernel/debug/irq/irqs/126": "handler:  handle_edge_irq\ndstate:   0x00401200\n            IRQD_ACTIVATED\n            IRQD_IRQ_STARTED\n",
+				"/sys/kernel/debug/irq/irqs/127": "handler:  handle_edge_irq\ndstate:   0x02601200\n            IRQD_ACTIVATED\n            IRQD_AFFINITY_MANAGED\n",
+				"/sys/kernel/debug/irq/irqs/131": "handler:  handle_edge_irq\ndstate:   0x00401200\n",
+				"/sys/kernel/debug/irq/irqs/140": "handler:  handle_edge_irq\ndstate:   0x02601200\n            IRQD_AFFINITY_MANAGED\n",
+			},
+			managed:     map[int]bool{127: true, 140: true},
+			managedFrom: irqs.ManagedFromDebugFS,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for name, data := range tc.debugfs {
+				fsys.AddFile(name, []byte(data))
+			}
+			ih := irqs.NewWithFS(nullLog, fsys, "/proc").WithDebugFS("/sys/kernel/debug")
+			irqInfos, err := ih.ReadInfo(0)
+			if err != nil {
+				t.Fatalf("ReadInfo failed: %v", err)
+			}
+			for _, irqInfo := range irqInfos {
+				if irqInfo.Source == "" {
+					continue
+				}
+				if irqInfo.Managed != tc.managed[irqInfo.IRQ] || irqInfo.ManagedFrom != tc.managedFrom {
+					t.Errorf("IRQ %d (%s): managed=%v from %q", irqInfo.IRQ, irqInfo.Source, irqInfo.Managed, irqInfo.ManagedFrom)
+				}
+			}
+		})
+	}
+}
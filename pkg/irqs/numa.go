@@ -0,0 +1,146 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package irqs
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Locality is the NUMA placement of a device IRQ, compared to the NUMA node of its PCI device.
+type Locality struct {
+	IRQ    int    `json:"irq"`
+	Device Device `json:"device"`
+	// DeviceNode is the NUMA node of the PCI device
+	DeviceNode int `json:"deviceNode"`
+	// CPUs is the (effective) affinity of the IRQ
+	CPUs []int `json:"cpus"`
+	// CPUNodes are the NUMA nodes of the CPUs
+	CPUNodes []int `json:"cpuNodes"`
+	// Remote is true if any of the CPUs belongs to a NUMA node other than the device one
+	Remote bool `json:"remote"`
+}
+
+func (loc Locality) String() string {
+	placement := "local"
+	if loc.Remote {
+		placement = "REMOTE"
+	}
+	return fmt.Sprintf("IRQ %3d [%s]: device on node %d, runs on cpus %v (nodes %v): %s", loc.IRQ, loc.Device.String(), loc.DeviceNode, loc.CPUs, loc.CPUNodes, placement)
+}
+
+// DeviceLocality summarizes the NUMA placement of all the IRQs of a PCI device.
+type DeviceLocality struct {
+	PCIAddress string `json:"pciAddress"`
+	Driver     string `json:"driver,omitempty"`
+	NetDev     string `json:"netdev,omitempty"`
+	NUMANode   int    `json:"numaNode"`
+	Local      int    `json:"local"`
+	Remote     int    `json:"remote"`
+	RemoteIRQs []int  `json:"remoteIRQs,omitempty"`
+}
+
+func (dl DeviceLocality) String() string {
+	name := dl.PCIAddress
+	if dl.NetDev != "" {
+		name = dl.NetDev + " " + name
+	}
+	if dl.Driver != "" {
+		name += " (" + dl.Driver + ")"
+	}
+	return fmt.Sprintf("%s node %d: %d local %d remote IRQs %v", name, dl.NUMANode, dl.Local, dl.Remote, dl.RemoteIRQs)
+}
+
+// LocalityReport is the NUMA placement of the device IRQs, and its per-device summary.
+type LocalityReport struct {
+	IRQs    []Locality       `json:"irqs"`
+	Devices []DeviceLocality `json:"devices"`
+}
+
+// CheckLocality compares the affinity of the IRQs served by PCI devices with the NUMA node of the devices.
+// devices are the resolved IRQs (see Resolver), deviceNodes maps the PCI addresses to their NUMA node,
+// cpuNodes maps the cpus to their NUMA node. IRQs whose device NUMA node is unknown, or reported as -1
+// (no NUMA affinity), are skipped. The report is sorted by IRQ and by PCI address.
+func CheckLocality(infos []Info, devices map[int]Device, deviceNodes map[string]int, cpuNodes map[int]int) LocalityReport {
+	report := LocalityReport{
+		IRQs:    []Locality{},
+		Devices: []DeviceLocality{},
+	}
+	summaries := make(map[string]*DeviceLocality)
+
+	for _, info := range infos {
+		dev, ok := devices[info.IRQ]
+		if !ok || dev.PCIAddress == "" {
+			continue
+		}
+		devNode, ok := deviceNodes[dev.PCIAddress]
+		if !ok || devNode < 0 {
+			continue
+		}
+
+		loc := Locality{
+			IRQ:        info.IRQ,
+			Device:     dev,
+			DeviceNode: devNode,
+			CPUs:       info.CPUs.List(),
+			CPUNodes:   []int{},
+		}
+		seen := make(map[int]bool)
+		for _, cpu := range loc.CPUs {
+			node, ok := cpuNodes[cpu]
+			if !ok || seen[node] {
+				continue
+			}
+			seen[node] = true
+			loc.CPUNodes = append(loc.CPUNodes, node)
+			if node != devNode {
+				loc.Remote = true
+			}
+		}
+		sort.Ints(loc.CPUNodes)
+		report.IRQs = append(report.IRQs, loc)
+
+		summary, ok := summaries[dev.PCIAddress]
+		if !ok {
+			summary = &DeviceLocality{
+				PCIAddress: dev.PCIAddress,
+				Driver:     dev.Driver,
+				NetDev:     dev.NetDev,
+				NUMANode:   devNode,
+			}
+			summaries[dev.PCIAddress] = summary
+		}
+		if loc.Remote {
+			summary.Remote++
+			summary.RemoteIRQs = append(summary.RemoteIRQs, info.IRQ)
+		} else {
+			summary.Local++
+		}
+	}
+
+	sort.Slice(report.IRQs, func(i, j int) bool {
+		return report.IRQs[i].IRQ < report.IRQs[j].IRQ
+	})
+	for _, summary := range summaries {
+		sort.Ints(summary.RemoteIRQs)
+		report.Devices = append(report.Devices, *summary)
+	}
+	sort.Slice(report.Devices, func(i, j int) bool {
+		return report.Devices[i].PCIAddress < report.Devices[j].PCIAddress
+	})
+	return report
+}
@@ -0,0 +1,90 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package irqs_test
+
+import (
+	"reflect"
+	"testing"
+
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/irqs"
+)
+
+func TestCheckLocality(t *testing.T) {
+	nic := irqs.Device{PCIAddress: "0000:3b:00.0", Driver: "i40e", NetDev: "ens1f0"}
+	nvme := irqs.Device{PCIAddress: "0000:d8:00.0", Driver: "nvme"}
+	virt := irqs.Device{PCIAddress: "0000:00:01.0", Driver: "virtio-pci"}
+
+	devices := map[int]irqs.Device{
+		0:   {IRQ: 0, Actions: []string{"timer"}},
+		140: withIRQ(nic, 140),
+		141: withIRQ(nic, 141),
+		142: withIRQ(nic, 142),
+		150: withIRQ(nvme, 150),
+		160: withIRQ(virt, 160),
+	}
+	deviceNodes := map[string]int{
+		"0000:3b:00.0": 0,
+		"0000:d8:00.0": 1,
+		"0000:00:01.0": -1,
+	}
+	// node0: cpus 0-3, node1: cpus 4-7
+	cpuNodes := map[int]int{0: 0, 1: 0, 2: 0, 3: 0, 4: 1, 5: 1, 6: 1, 7: 1}
+
+	infos := []irqs.Info{
+		{IRQ: 0, CPUs: cpuset.New(0)},
+		{IRQ: 142, CPUs: cpuset.New(5)},
+		{IRQ: 140, CPUs: cpuset.New(1)},
+		{IRQ: 141, CPUs: cpuset.New(3, 4)},
+		{IRQ: 150, CPUs: cpuset.New(6)},
+		{IRQ: 160, CPUs: cpuset.New(2)},
+	}
+
+	report := irqs.CheckLocality(infos, devices, deviceNodes, cpuNodes)
+
+	var got []int
+	var remotes []int
+	for _, loc := range report.IRQs {
+		got = append(got, loc.IRQ)
+		if loc.Remote {
+			remotes = append(remotes, loc.IRQ)
+		}
+	}
+	if !reflect.DeepEqual(got, []int{140, 141, 142, 150}) {
+		t.Errorf("unexpected IRQs: %v", got)
+	}
+	if !reflect.DeepEqual(remotes, []int{141, 142}) {
+		t.Errorf("unexpected remote IRQs: %v", remotes)
+	}
+	if nodes := report.IRQs[1].CPUNodes; !reflect.DeepEqual(nodes, []int{0, 1}) {
+		t.Errorf("unexpected cpu nodes for IRQ 141: %v", nodes)
+	}
+
+	expected := []irqs.DeviceLocality{
+		{PCIAddress: "0000:3b:00.0", Driver: "i40e", NetDev: "ens1f0", NUMANode: 0, Local: 1, Remote: 2, RemoteIRQs: []int{141, 142}},
+		{PCIAddress: "0000:d8:00.0", Driver: "nvme", NUMANode: 1, Local: 1},
+	}
+	if !reflect.DeepEqual(report.Devices, expected) {
+		t.Errorf("devices mismatch\ngot      %+v\nexpected %+v", report.Devices, expected)
+	}
+}
+
+func withIRQ(dev irqs.Device, irq int) irqs.Device {
+	dev.IRQ = irq
+	return dev
+}
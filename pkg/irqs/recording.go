@@ -0,0 +1,110 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package irqs
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// RecordingHeader describes the samples of a recording.
+type RecordingHeader struct {
+	// Kind is either KindIRQ or KindSoftirq
+	Kind    string         `json:"kind"`
+	Devices map[int]Device `json:"devices,omitempty"`
+}
+
+// Sample is the full counters, for all the cpus, read at the given time.
+type Sample struct {
+	Timestamp time.Time `json:"timestamp"`
+	Counters  Stats     `json:"counters"`
+}
+
+// Recording is a sequence of samples, in time order, which can be replayed through any Reporter.
+// Recordings are stored as JSON lines: the header first, then a line per sample.
+type Recording struct {
+	Header  RecordingHeader
+	Samples []Sample
+}
+
+// Recorder writes a recording as the samples are read.
+type Recorder struct {
+	enc *json.Encoder
+}
+
+// NewRecorder creates a Recorder which writes into sink, starting with the given header.
+func NewRecorder(sink io.Writer, header RecordingHeader) (*Recorder, error) {
+	rec := &Recorder{
+		enc: json.NewEncoder(sink),
+	}
+	return rec, rec.enc.Encode(header)
+}
+
+func (rec *Recorder) Record(ts time.Time, stats Stats) error {
+	return rec.enc.Encode(Sample{
+		Timestamp: ts,
+		Counters:  stats,
+	})
+}
+
+// ReadRecording reads a recording written by a Recorder.
+func ReadRecording(rd io.Reader) (Recording, error) {
+	var rec Recording
+	src := bufio.NewScanner(rd)
+	src.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	if !src.Scan() {
+		if err := src.Err(); err != nil {
+			return rec, err
+		}
+		return rec, fmt.Errorf("empty recording")
+	}
+	if err := json.Unmarshal(src.Bytes(), &rec.Header); err != nil {
+		return rec, fmt.Errorf("malformed recording header: %v", err)
+	}
+	if rec.Header.Kind != KindIRQ && rec.Header.Kind != KindSoftirq {
+		return rec, fmt.Errorf("unsupported recording kind %q", rec.Header.Kind)
+	}
+	lineNo := 1
+	for src.Scan() {
+		lineNo++
+		var sample Sample
+		if err := json.Unmarshal(src.Bytes(), &sample); err != nil {
+			return rec, fmt.Errorf("malformed sample at line %d: %v", lineNo, err)
+		}
+		rec.Samples = append(rec.Samples, sample)
+	}
+	return rec, src.Err()
+}
+
+// Replay feeds the samples to the reporter, like irqwatch does while watching:
+// a Delta for each sample after the first one, and the Summary over all the samples.
+func (rec Recording) Replay(reporter Reporter) error {
+	if len(rec.Samples) < 2 {
+		return fmt.Errorf("need at least two samples, found %d", len(rec.Samples))
+	}
+	first := rec.Samples[0]
+	last := first
+	for _, sample := range rec.Samples[1:] {
+		reporter.Delta(sample.Timestamp, last.Counters, sample.Counters)
+		last = sample
+	}
+	reporter.Summary(first.Timestamp, first.Counters, last.Counters)
+	return nil
+}
@@ -0,0 +1,106 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package irqs_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/irqs"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	header := irqs.RecordingHeader{
+		Kind: irqs.KindIRQ,
+		Devices: map[int]irqs.Device{
+			8: {IRQ: 8, Actions: []string{"rtc0"}},
+		},
+	}
+	initTs := time.Date(2024, 3, 11, 10, 0, 0, 0, time.UTC)
+	samples := []irqs.Sample{
+		{Timestamp: initTs, Counters: fakeStatsInit},
+		{Timestamp: initTs.Add(time.Second), Counters: fakeStatsLast},
+		{Timestamp: initTs.Add(2 * time.Second), Counters: fakeStatsLast},
+	}
+
+	var buf bytes.Buffer
+	recorder, err := irqs.NewRecorder(&buf, header)
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+	for _, sample := range samples {
+		if err := recorder.Record(sample.Timestamp, sample.Counters); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	rec, err := irqs.ReadRecording(&buf)
+	if err != nil {
+		t.Fatalf("ReadRecording failed: %v", err)
+	}
+	if !reflect.DeepEqual(rec.Header, header) {
+		t.Errorf("header mismatch got %+v expected %+v", rec.Header, header)
+	}
+	if len(rec.Samples) != len(samples) {
+		t.Fatalf("samples mismatch got %d expected %d", len(rec.Samples), len(samples))
+	}
+	for idx, sample := range rec.Samples {
+		if !sample.Timestamp.Equal(samples[idx].Timestamp) || !reflect.DeepEqual(sample.Counters, samples[idx].Counters) {
+			t.Errorf("sample %d mismatch got %+v expected %+v", idx, sample, samples[idx])
+		}
+	}
+
+	var out bytes.Buffer
+	reporter := irqs.NewDeviceReporter(&out, false, 1, cpuset.New(2, 3), rec.Header.Devices)
+	if err := rec.Replay(reporter); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	report := out.String()
+	for _, expected := range []string{
+		"IRQ summary on cpus 2-3 after 2s",
+		"CPU=2 IRQ=8 [rtc0] +3",
+		"CPU=3 IRQ=12 +4",
+	} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("missing %q in the report %q", expected, report)
+		}
+	}
+}
+
+func TestReadRecordingErrors(t *testing.T) {
+	for _, data := range []string{
+		"",
+		"{\"kind\":\"NMI\"}\n",
+		"{\"kind\":\"IRQ\"}\n{\"timestamp\":42}\n",
+	} {
+		if _, err := irqs.ReadRecording(strings.NewReader(data)); err == nil {
+			t.Errorf("ReadRecording succeeded on %q", data)
+		}
+	}
+	rec, err := irqs.ReadRecording(strings.NewReader("{\"kind\":\"SOFTIRQ\"}\n"))
+	if err != nil {
+		t.Fatalf("ReadRecording failed: %v", err)
+	}
+	if err := rec.Replay(irqs.NewSoftirqReporter(&bytes.Buffer{}, false, 1, cpuset.New(0))); err == nil {
+		t.Errorf("Replay succeeded without samples")
+	}
+}
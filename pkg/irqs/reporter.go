@@ -20,39 +20,87 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"strconv"
 	"time"
 
 	cpuset "k8s.io/utils/cpuset"
 )
 
+// the kinds of counters reported
+const (
+	KindIRQ     = "IRQ"
+	KindSoftirq = "SOFTIRQ"
+)
+
+// Reporter reports the counters. The Summary elapsed time spans from initTs to the timestamp
+// of the last Delta, or to the current time if Delta was never called.
 type Reporter interface {
 	Delta(ts time.Time, prevStats, lastStats Stats)
 	Summary(initTs time.Time, prevStats, lastStats Stats)
 }
 
 func NewReporter(sink io.Writer, jsonOutput bool, verbose int, cpus cpuset.CPUSet) Reporter {
+	return newReporter(sink, jsonOutput, verbose, cpus, KindIRQ, nil)
+}
+
+// NewDeviceReporter creates a Reporter which describes the IRQs using the given devices, see Resolver
+func NewDeviceReporter(sink io.Writer, jsonOutput bool, verbose int, cpus cpuset.CPUSet, devices map[int]Device) Reporter {
+	return newReporter(sink, jsonOutput, verbose, cpus, KindIRQ, devices)
+}
+
+// NewSoftirqReporter creates a Reporter for the softirq counters, see soft.Handler.ReadStats
+func NewSoftirqReporter(sink io.Writer, jsonOutput bool, verbose int, cpus cpuset.CPUSet) Reporter {
+	return newReporter(sink, jsonOutput, verbose, cpus, KindSoftirq, nil)
+}
+
+func newReporter(sink io.Writer, jsonOutput bool, verbose int, cpus cpuset.CPUSet, kind string, devices map[int]Device) Reporter {
 	if jsonOutput {
 		return &reporterJSON{
 			verbose: verbose,
 			cpus:    cpus,
 			sink:    sink,
+			kind:    kind,
+			devices: devices,
 		}
 	}
 	return &reporterText{
 		verbose: verbose,
 		cpus:    cpus,
 		sink:    sink,
+		kind:    kind,
+		devices: devices,
 	}
 
 }
 
+// LookupDevice finds the device served by the IRQ with the given name, as found in Stats.
+func LookupDevice(devices map[int]Device, irqName string) (Device, bool) {
+	irq, err := strconv.Atoi(irqName)
+	if err != nil {
+		return Device{}, false
+	}
+	dev, ok := devices[irq]
+	return dev, ok
+}
+
 type reporterText struct {
 	verbose int
 	cpus    cpuset.CPUSet
 	sink    io.Writer
+	kind    string
+	devices map[int]Device
+	lastTs  time.Time
+}
+
+func (rt *reporterText) describe(irqName string) string {
+	if dev, ok := LookupDevice(rt.devices, irqName); ok {
+		return irqName + " [" + dev.String() + "]"
+	}
+	return irqName
 }
 
 func (rt *reporterText) Delta(ts time.Time, prevStats, lastStats Stats) {
+	rt.lastTs = ts
 	if rt.verbose < 2 {
 		return
 	}
@@ -67,7 +115,7 @@ func (rt *reporterText) Delta(ts time.Time, prevStats, lastStats Stats) {
 			if val == 0 {
 				continue
 			}
-			fmt.Fprintf(rt.sink, "%v CPU=%d IRQ=%s +%d\n", ts, cpuid, irqName, val)
+			fmt.Fprintf(rt.sink, "%v CPU=%d %s=%s +%d\n", ts, cpuid, rt.kind, rt.describe(irqName), val)
 		}
 	}
 }
@@ -76,11 +124,11 @@ func (rt *reporterText) Summary(initTs time.Time, prevStats, lastStats Stats) {
 	if rt.verbose < 1 {
 		return
 	}
-	timeDelta := time.Now().Sub(initTs)
+	timeDelta := elapsedSince(initTs, rt.lastTs)
 	delta := prevStats.Delta(lastStats)
 	cpuids := rt.cpus.List()
 
-	fmt.Fprintf(rt.sink, "\nIRQ summary on cpus %v after %v\n", rt.cpus, timeDelta)
+	fmt.Fprintf(rt.sink, "\n%s summary on cpus %v after %v\n", rt.kind, rt.cpus, timeDelta)
 	for _, cpuid := range cpuids {
 		counter, ok := delta[cpuid]
 		if !ok {
@@ -90,7 +138,7 @@ func (rt *reporterText) Summary(initTs time.Time, prevStats, lastStats Stats) {
 			if val == 0 {
 				continue
 			}
-			fmt.Fprintf(rt.sink, "CPU=%d IRQ=%s +%d\n", cpuid, irqName, val)
+			fmt.Fprintf(rt.sink, "CPU=%d %s=%s +%d\n", cpuid, rt.kind, rt.describe(irqName), val)
 		}
 	}
 }
@@ -99,21 +147,45 @@ type reporterJSON struct {
 	verbose int
 	cpus    cpuset.CPUSet
 	sink    io.Writer
+	kind    string
+	devices map[int]Device
+	lastTs  time.Time
 }
 
 type irqDelta struct {
-	Timestamp time.Time `json:"timestamp"`
-	Counters  Stats     `json:"counters"`
+	Kind      string            `json:"kind"`
+	Timestamp time.Time         `json:"timestamp"`
+	Counters  Stats             `json:"counters"`
+	Devices   map[string]Device `json:"devices,omitempty"`
+}
+
+// devicesFor returns the devices served by the IRQs found in the stats, keyed by IRQ name.
+func (rj *reporterJSON) devicesFor(stats Stats) map[string]Device {
+	if len(rj.devices) == 0 {
+		return nil
+	}
+	devs := make(map[string]Device)
+	for _, counter := range stats {
+		for irqName := range counter {
+			if dev, ok := LookupDevice(rj.devices, irqName); ok {
+				devs[irqName] = dev
+			}
+		}
+	}
+	return devs
 }
 
 func (rj *reporterJSON) Delta(ts time.Time, prevStats, lastStats Stats) {
+	rj.lastTs = ts
 	if rj.verbose < 2 {
 		return
 	}
 	res := irqDelta{
+		Kind:      rj.kind,
 		Timestamp: ts,
 		Counters:  countersForCPUs(rj.cpus, prevStats.Delta(lastStats)),
 	}
+	res.Devices = rj.devicesFor(res.Counters)
 	json.NewEncoder(rj.sink).Encode(res)
 }
 
@@ -121,9 +193,15 @@ type irqwatchDuration struct {
 	d time.Duration
 }
 
+func (d irqwatchDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.d.String())
+}
+
 type irqSummary struct {
-	Elapsed  irqwatchDuration `json:"elapsed"`
-	Counters Stats            `json:"counters"`
+	Kind     string            `json:"kind"`
+	Elapsed  irqwatchDuration  `json:"elapsed"`
+	Counters Stats             `json:"counters"`
+	Devices  map[string]Device `json:"devices,omitempty"`
 }
 
 func (rj *reporterJSON) Summary(initTs time.Time, prevStats, lastStats Stats) {
@@ -131,14 +209,23 @@ func (rj *reporterJSON) Summary(initTs time.Time, prevStats, lastStats Stats) {
 		return
 	}
 	res := irqSummary{
+		Kind: rj.kind,
 		Elapsed: irqwatchDuration{
-			d: time.Now().Sub(initTs),
+			d: elapsedSince(initTs, rj.lastTs),
 		},
 		Counters: countersForCPUs(rj.cpus, prevStats.Delta(lastStats)),
 	}
+	res.Devices = rj.devicesFor(res.Counters)
 	json.NewEncoder(rj.sink).Encode(res)
 }
 
+func elapsedSince(initTs, lastTs time.Time) time.Duration {
+	if lastTs.IsZero() {
+		return time.Now().Sub(initTs)
+	}
+	return lastTs.Sub(initTs)
+}
+
 func countersForCPUs(cpus cpuset.CPUSet, stats Stats) Stats {
 	res := make(Stats)
 	cpuids := cpus.List()
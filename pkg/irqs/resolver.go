@@ -0,0 +1,279 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package irqs
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+)
+
+// Device describes what a IRQ serves.
+type Device struct {
+	IRQ   int    `json:"irq"`
+	Chip  string `json:"chip,omitempty"`
+	HWIRQ string `json:"hwirq,omitempty"`
+	// Type is the trigger type, like "edge" or "level"
+	Type string `json:"type,omitempty"`
+	// Actions are the names the handlers were registered with, usually the device or queue name
+	Actions []string `json:"actions,omitempty"`
+	// PCI device, for the MSI/MSI-X IRQs
+	PCIAddress string `json:"pciAddress,omitempty"`
+	Driver     string `json:"driver,omitempty"`
+	NetDev     string `json:"netdev,omitempty"`
+	// Queue is the netdev queue name, taken from the actions, if any
+	Queue string `json:"queue,omitempty"`
+}
+
+// Name returns the most specific name of what the IRQ serves: the queue, or the actions.
+func (dev Device) Name() string {
+	if dev.Queue != "" {
+		return dev.Queue
+	}
+	return strings.Join(dev.Actions, ",")
+}
+
+// String returns the name and the details, like "ens1f0-TxRx-3 (i40e, 0000:3b:00.0)".
+func (dev Device) String() string {
+	var details []string
+	if dev.Driver != "" {
+		details = append(details, dev.Driver)
+	}
+	if dev.PCIAddress != "" {
+		details = append(details, dev.PCIAddress)
+	}
+	name := dev.Name()
+	if name == "" {
+		name = strings.TrimSpace(dev.Chip + " " + dev.HWIRQ)
+	}
+	if len(details) == 0 {
+		return name
+	}
+	return name + " (" + strings.Join(details, ", ") + ")"
+}
+
+// Resolver finds the devices served by the IRQs, cross-correlating /proc/interrupts,
+// /sys/kernel/irq and the PCI devices in sysfs.
+type Resolver struct {
+	log        *log.Logger
+	procfsRoot string
+	sysfsRoot  string
+	fs         fswrap.FSWrapper
+}
+
+func NewResolver(logger *log.Logger, fsys fswrap.FSWrapper, procfsRoot, sysfsRoot string) *Resolver {
+	return &Resolver{
+		log:        logger,
+		procfsRoot: procfsRoot,
+		sysfsRoot:  sysfsRoot,
+		fs:         fsys,
+	}
+}
+
+// Resolve returns the devices served by the IRQs. Each source is optional, and failures are not critical:
+// the more sources are available, the more complete the devices are.
+func (res *Resolver) Resolve() map[int]Device {
+	devices := make(map[int]Device)
+
+	src, err := res.fs.Open(filepath.Join(res.procfsRoot, "interrupts"))
+	if err == nil {
+		for _, dev := range parseInterruptsDevices(src) {
+			devices[dev.IRQ] = dev
+		}
+		src.Close()
+	} else {
+		res.log.Printf("Error reading interrupts from %q: %v", res.procfsRoot, err)
+	}
+
+	res.resolveKernelIRQs(devices)
+	res.resolvePCIDevices(devices)
+	return devices
+}
+
+// hwirq and flow handler name, like "2-edge", "12582981-edge", "9-fasteoi"
+var hwirqTypeRE = regexp.MustCompile(`^(\d+)-(\S+)$`)
+
+// parseInterruptsDevices extracts the devices from the trailing columns of /proc/interrupts
+// (see kernel/irq/proc.c:show_interrupts). Only the numeric IRQs are reported.
+func parseInterruptsDevices(rd io.Reader) []Device {
+	src := bufio.NewScanner(rd)
+	src.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	if !src.Scan() {
+		return nil
+	}
+	numCPUs := len(strings.Fields(src.Text()))
+
+	var devices []Device
+	for src.Scan() {
+		items := strings.Fields(src.Text())
+		if len(items) <= 1+numCPUs {
+			continue
+		}
+		irq, err := strconv.Atoi(strings.TrimSuffix(items[0], ":"))
+		if err != nil {
+			continue
+		}
+		dev := Device{IRQ: irq}
+		rest := items[1+numCPUs:]
+		actionsIdx := len(rest)
+		for idx, item := range rest {
+			if match := hwirqTypeRE.FindStringSubmatch(item); match != nil {
+				dev.Chip = strings.Join(rest[:idx], " ")
+				dev.HWIRQ = match[1]
+				dev.Type = match[2]
+				actionsIdx = idx + 1
+				break
+			}
+		}
+		if dev.Chip == "" {
+			// older kernels don't report the hwirq
+			dev.Chip = rest[0]
+			actionsIdx = 1
+		}
+		dev.Actions = splitActions(strings.Join(rest[actionsIdx:], " "))
+		devices = append(devices, dev)
+	}
+	return devices
+}
+
+func splitActions(data string) []string {
+	var actions []string
+	for _, action := range strings.Split(data, ",") {
+		if action = strings.TrimSpace(action); action != "" {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+// resolveKernelIRQs completes the devices using /sys/kernel/irq, available since linux 4.17
+func (res *Resolver) resolveKernelIRQs(devices map[int]Device) {
+	irqRoot := filepath.Join(res.sysfsRoot, "kernel", "irq")
+	entries, err := res.fs.ReadDir(irqRoot)
+	if err != nil {
+		res.log.Printf("Error reading %q: %v", irqRoot, err)
+		return
+	}
+	for _, entry := range entries {
+		irq, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		dev, ok := devices[irq]
+		if !ok {
+			dev = Device{IRQ: irq}
+		}
+		irqDir := filepath.Join(irqRoot, entry.Name())
+		if val := res.readAttr(irqDir, "chip_name"); val != "" {
+			dev.Chip = val
+		}
+		if val := res.readAttr(irqDir, "hwirq"); val != "" {
+			dev.HWIRQ = val
+		}
+		if val := res.readAttr(irqDir, "type"); val != "" {
+			dev.Type = val
+		}
+		if actions := splitActions(res.readAttr(irqDir, "actions")); len(actions) > 0 {
+			dev.Actions = actions
+		}
+		devices[irq] = dev
+	}
+}
+
+// resolvePCIDevices attaches the PCI devices to their MSI/MSI-X IRQs
+func (res *Resolver) resolvePCIDevices(devices map[int]Device) {
+	pattern := filepath.Join(res.sysfsRoot, "bus", "pci", "devices", "*", "msi_irqs", "*")
+	matches, err := res.fs.Glob(pattern)
+	if err != nil {
+		res.log.Printf("Error globbing %q: %v", pattern, err)
+		return
+	}
+	type pciInfo struct {
+		driver string
+		netdev string
+	}
+	pciInfos := make(map[string]pciInfo)
+	for _, match := range matches {
+		irq, err := strconv.Atoi(filepath.Base(match))
+		if err != nil {
+			continue
+		}
+		devDir := filepath.Dir(filepath.Dir(match))
+		addr := filepath.Base(devDir)
+		info, ok := pciInfos[addr]
+		if !ok {
+			info = pciInfo{
+				driver: res.readDriver(devDir),
+				netdev: res.readNetDev(devDir),
+			}
+			pciInfos[addr] = info
+		}
+
+		dev, ok := devices[irq]
+		if !ok {
+			dev = Device{IRQ: irq}
+		}
+		dev.PCIAddress = addr
+		dev.Driver = info.driver
+		dev.NetDev = info.netdev
+		if info.netdev != "" {
+			for _, action := range dev.Actions {
+				if off := strings.Index(action, info.netdev); off >= 0 {
+					// drop the driver prefix, like in "i40e-ens1f0-TxRx-3"
+					dev.Queue = action[off:]
+					break
+				}
+			}
+		}
+		devices[irq] = dev
+	}
+}
+
+func (res *Resolver) readDriver(devDir string) string {
+	data, err := res.fs.ReadFile(filepath.Join(devDir, "uevent"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "DRIVER=") {
+			return strings.TrimPrefix(line, "DRIVER=")
+		}
+	}
+	return ""
+}
+
+func (res *Resolver) readNetDev(devDir string) string {
+	entries, err := res.fs.ReadDir(filepath.Join(devDir, "net"))
+	if err != nil || len(entries) == 0 {
+		return ""
+	}
+	return entries[0].Name()
+}
+
+func (res *Resolver) readAttr(dir, name string) string {
+	data, err := res.fs.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,127 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package irqs_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+	"github.com/openshift-kni/debug-tools/pkg/irqs"
+)
+
+const resolverInterrupts = `            CPU0       CPU1
+   0:         46          0   IO-APIC   2-edge      timer
+   9:          0          0   IO-APIC   9-fasteoi   acpi, i801_smbus
+  24:          0          0  IR-PCI-MSI 1572864-edge      i40e-0000:3b:00.0:misc
+ 142:       1234         17  IR-PCI-MSI 1572867-edge      i40e-ens1f0-TxRx-3
+ 150:          0          0   PCI-MSI-edge      eth0
+ NMI:          0          0   Non-maskable interrupts
+ LOC:       1000       2000   Local timer interrupts
+`
+
+func TestResolve(t *testing.T) {
+	fsys := fswrap.NewMemFS(map[string]string{
+		"/proc/interrupts":                                   resolverInterrupts,
+		"/sys/kernel/irq/9/chip_name":                        "IR-IO-APIC\n",
+		"/sys/kernel/irq/9/hwirq":                            "9\n",
+		"/sys/kernel/irq/9/type":                             "level\n",
+		"/sys/kernel/irq/9/actions":                          "acpi\n",
+		"/sys/bus/pci/devices/0000:3b:00.0/uevent":           "DRIVER=i40e\nPCI_SLOT_NAME=0000:3b:00.0\n",
+		"/sys/bus/pci/devices/0000:3b:00.0/net/ens1f0/mtu":   "1500\n",
+		"/sys/bus/pci/devices/0000:3b:00.0/msi_irqs/24":      "msix\n",
+		"/sys/bus/pci/devices/0000:3b:00.0/msi_irqs/142":     "msix\n",
+		"/sys/bus/pci/devices/0000:00:1f.6/uevent":           "DRIVER=e1000e\n",
+		"/sys/bus/pci/devices/0000:00:1f.6/msi_irqs/150":     "msi\n",
+		"/sys/bus/pci/devices/0000:00:1f.6/enable":           "1\n",
+		"/sys/bus/pci/devices/0000:00:1f.6/net/eth0/ifindex": "2\n",
+	})
+
+	devices := irqs.NewResolver(nullLog, fsys, "/proc", "/sys").Resolve()
+
+	expected := map[int]irqs.Device{
+		0: {IRQ: 0, Chip: "IO-APIC", HWIRQ: "2", Type: "edge", Actions: []string{"timer"}},
+		// /sys/kernel/irq takes precedence
+		9: {IRQ: 9, Chip: "IR-IO-APIC", HWIRQ: "9", Type: "level", Actions: []string{"acpi"}},
+		24: {
+			IRQ: 24, Chip: "IR-PCI-MSI", HWIRQ: "1572864", Type: "edge", Actions: []string{"i40e-0000:3b:00.0:misc"},
+			PCIAddress: "0000:3b:00.0", Driver: "i40e", NetDev: "ens1f0",
+		},
+		142: {
+			IRQ: 142, Chip: "IR-PCI-MSI", HWIRQ: "1572867", Type: "edge", Actions: []string{"i40e-ens1f0-TxRx-3"},
+			PCIAddress: "0000:3b:00.0", Driver: "i40e", NetDev: "ens1f0", Queue: "ens1f0-TxRx-3",
+		},
+		// older kernels don't report the hwirq
+		150: {
+			IRQ: 150, Chip: "PCI-MSI-edge", Actions: []string{"eth0"},
+			PCIAddress: "0000:00:1f.6", Driver: "e1000e", NetDev: "eth0", Queue: "eth0",
+		},
+	}
+	if !reflect.DeepEqual(devices, expected) {
+		t.Errorf("devices mismatch\ngot      %+v\nexpected %+v", devices, expected)
+	}
+}
+
+func TestDeviceString(t *testing.T) {
+	testCases := []struct {
+		dev      irqs.Device
+		expected string
+	}{
+		{
+			dev:      irqs.Device{IRQ: 142, Actions: []string{"i40e-ens1f0-TxRx-3"}, PCIAddress: "0000:3b:00.0", Driver: "i40e", Queue: "ens1f0-TxRx-3"},
+			expected: "ens1f0-TxRx-3 (i40e, 0000:3b:00.0)",
+		},
+		{
+			dev:      irqs.Device{IRQ: 9, Actions: []string{"acpi", "i801_smbus"}},
+			expected: "acpi,i801_smbus",
+		},
+		{
+			dev:      irqs.Device{IRQ: 7, Chip: "IR-IO-APIC", HWIRQ: "7"},
+			expected: "IR-IO-APIC 7",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			if got := tc.dev.String(); got != tc.expected {
+				t.Errorf("got %q expected %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestReportingDevicesText(t *testing.T) {
+	devices := map[int]irqs.Device{
+		142: {IRQ: 142, Actions: []string{"i40e-ens1f0-TxRx-3"}, PCIAddress: "0000:3b:00.0", Driver: "i40e"},
+	}
+	initStats := irqs.Stats{0: irqs.Counter{"142": 10, "LOC": 100}}
+	lastStats := irqs.Stats{0: irqs.Counter{"142": 15, "LOC": 100}}
+
+	var buf bytes.Buffer
+	reporter := irqs.NewDeviceReporter(&buf, false, 2, cpuset.New(0), devices)
+	reporter.Delta(time.Now(), initStats, lastStats)
+
+	expected := "IRQ=142 [i40e-ens1f0-TxRx-3 (i40e, 0000:3b:00.0)] +5"
+	if !strings.Contains(buf.String(), expected) {
+		t.Errorf("missing %q in output %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,219 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package irqs
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cpuset "k8s.io/utils/cpuset"
+	"sigs.k8s.io/yaml"
+)
+
+// DeviceIRQs matches all the device IRQs, which have a numeric name, as opposed
+// to the architecture-specific interrupts like "LOC", "RES", "NMI".
+const DeviceIRQs = "devices"
+
+// Rule bounds the rate of the given IRQs on the given cpus.
+type Rule struct {
+	Name string `json:"name,omitempty"`
+	// IRQs are the IRQ (or softirq) names, or DeviceIRQs. Empty means all.
+	IRQs []string `json:"irqs,omitempty"`
+	// CPUs is a cpulist. Empty means the cpus being watched.
+	CPUs string `json:"cpus,omitempty"`
+	// MaxRate is the maximum allowed rate, per second and per cpu. Zero means no IRQ at all.
+	MaxRate float64 `json:"maxRate"`
+
+	cpus *cpuset.CPUSet
+}
+
+type ruleSet struct {
+	Rules []Rule `json:"rules"`
+}
+
+// ParseRule parses a rule in the compact form IRQS[@CPUS]=MAXRATE, with IRQS a comma-separated list.
+// For example: "devices=0", "LOC@2-7=1000", "LOC,RES@4=10".
+func ParseRule(text string) (Rule, error) {
+	off := strings.LastIndex(text, "=")
+	if off < 0 {
+		return Rule{}, fmt.Errorf("malformed rule %q: missing the max rate", text)
+	}
+	maxRate, err := strconv.ParseFloat(text[off+1:], 64)
+	if err != nil {
+		return Rule{}, fmt.Errorf("malformed rule %q: %v", text, err)
+	}
+	rule := Rule{
+		Name:    text,
+		MaxRate: maxRate,
+	}
+	irqList := text[:off]
+	if at := strings.Index(irqList, "@"); at >= 0 {
+		rule.CPUs = irqList[at+1:]
+		irqList = irqList[:at]
+	}
+	if irqList != "" {
+		rule.IRQs = strings.Split(irqList, ",")
+	}
+	if err := rule.validate(); err != nil {
+		return Rule{}, err
+	}
+	return rule, nil
+}
+
+// ParseRules parses the rules from a YAML document, like:
+//
+//	rules:
+//	- name: no device IRQs on isolated cpus
+//	  irqs: [devices]
+//	  maxRate: 0
+//	- irqs: [LOC]
+//	  cpus: 2-7
+//	  maxRate: 1000
+func ParseRules(data []byte) ([]Rule, error) {
+	var rs ruleSet
+	if err := yaml.UnmarshalStrict(data, &rs); err != nil {
+		return nil, err
+	}
+	for idx := range rs.Rules {
+		if err := rs.Rules[idx].validate(); err != nil {
+			return nil, fmt.Errorf("rule #%d: %v", idx, err)
+		}
+	}
+	return rs.Rules, nil
+}
+
+func (rule *Rule) validate() error {
+	if rule.MaxRate < 0 {
+		return fmt.Errorf("negative max rate %v", rule.MaxRate)
+	}
+	if rule.CPUs != "" {
+		cpus, err := cpuset.Parse(rule.CPUs)
+		if err != nil {
+			return fmt.Errorf("malformed cpus %q: %v", rule.CPUs, err)
+		}
+		rule.cpus = &cpus
+	}
+	if rule.Name == "" {
+		rule.Name = rule.String()
+	}
+	return nil
+}
+
+func (rule Rule) String() string {
+	irqs := "all"
+	if len(rule.IRQs) > 0 {
+		irqs = strings.Join(rule.IRQs, ",")
+	}
+	cpus := "watched cpus"
+	if rule.CPUs != "" {
+		cpus = "cpus " + rule.CPUs
+	}
+	return fmt.Sprintf("IRQ %s on %s at most %v/s", irqs, cpus, rule.MaxRate)
+}
+
+func (rule Rule) matchesIRQ(name string) bool {
+	if len(rule.IRQs) == 0 {
+		return true
+	}
+	for _, irq := range rule.IRQs {
+		if irq == name {
+			return true
+		}
+		if irq == DeviceIRQs {
+			if _, err := strconv.Atoi(name); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Violation is a IRQ exceeding the rate allowed by a rule on a cpu.
+type Violation struct {
+	Rule string `json:"rule"`
+	// Kind tells if IRQ is a IRQ or a softirq, see KindIRQ and KindSoftirq. Empty means KindIRQ.
+	Kind  string  `json:"kind,omitempty"`
+	CPU   int     `json:"cpu"`
+	IRQ   string  `json:"irq"`
+	Count uint64  `json:"count"`
+	Rate  float64 `json:"rate"`
+	// Device describes the IRQ, if known, see Resolver
+	Device string `json:"device,omitempty"`
+}
+
+func (vi Violation) String() string {
+	kind := vi.Kind
+	if kind == "" {
+		kind = KindIRQ
+	}
+	irq := vi.IRQ
+	if vi.Device != "" {
+		irq += " [" + vi.Device + "]"
+	}
+	return fmt.Sprintf("VIOLATION rule %q: CPU=%d %s=%s +%d (%.2f/s)", vi.Rule, vi.CPU, kind, irq, vi.Count, vi.Rate)
+}
+
+// Check verifies the counters delta, accumulated over the elapsed time, against the rule.
+// Rules without cpus are checked against the given cpus. Violations are sorted by cpu and IRQ.
+func (rule Rule) Check(delta Stats, elapsed time.Duration, cpus cpuset.CPUSet) []Violation {
+	if rule.cpus != nil {
+		cpus = *rule.cpus
+	}
+	secs := elapsed.Seconds()
+	var viols []Violation
+	for _, cpuid := range cpus.List() {
+		counter := delta[cpuid]
+		names := make([]string, 0, len(counter))
+		for name := range counter {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			count := counter[name]
+			if count == 0 || !rule.matchesIRQ(name) {
+				continue
+			}
+			rate := float64(count)
+			if secs > 0 {
+				rate /= secs
+			}
+			if rule.MaxRate > 0 && rate <= rule.MaxRate {
+				continue
+			}
+			viols = append(viols, Violation{
+				Rule:  rule.Name,
+				CPU:   cpuid,
+				IRQ:   name,
+				Count: count,
+				Rate:  rate,
+			})
+		}
+	}
+	return viols
+}
+
+// CheckRules verifies the counters delta against all the rules.
+func CheckRules(rules []Rule, delta Stats, elapsed time.Duration, cpus cpuset.CPUSet) []Violation {
+	var viols []Violation
+	for _, rule := range rules {
+		viols = append(viols, rule.Check(delta, elapsed, cpus)...)
+	}
+	return viols
+}
@@ -0,0 +1,125 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package irqs_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/irqs"
+)
+
+func TestParseRule(t *testing.T) {
+	testCases := []struct {
+		text     string
+		irqs     []string
+		cpus     string
+		maxRate  float64
+		expError bool
+	}{
+		{text: "devices=0", irqs: []string{"devices"}},
+		{text: "LOC@2-7=1000", irqs: []string{"LOC"}, cpus: "2-7", maxRate: 1000},
+		{text: "LOC,RES@2,4=0.5", irqs: []string{"LOC", "RES"}, cpus: "2,4", maxRate: 0.5},
+		{text: "=10", maxRate: 10},
+		{text: "LOC", expError: true},
+		{text: "LOC=fast", expError: true},
+		{text: "LOC@x=1", expError: true},
+		{text: "LOC=-1", expError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			rule, err := irqs.ParseRule(tc.text)
+			if (err != nil) != tc.expError {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.expError {
+				return
+			}
+			if !reflect.DeepEqual(rule.IRQs, tc.irqs) || rule.CPUs != tc.cpus || rule.MaxRate != tc.maxRate {
+				t.Errorf("unexpected rule: %+v", rule)
+			}
+		})
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := irqs.ParseRules([]byte(`rules:
+- name: no device IRQs on isolated cpus
+  irqs: [devices]
+  maxRate: 0
+- irqs: [LOC]
+  cpus: 2-3
+  maxRate: 100
+`))
+	if err != nil {
+		t.Fatalf("ParseRules failed: %v", err)
+	}
+	if len(rules) != 2 || rules[0].Name != "no device IRQs on isolated cpus" || rules[1].Name == "" {
+		t.Errorf("unexpected rules: %+v", rules)
+	}
+
+	for _, data := range []string{
+		"rules:\n- irq: [LOC]\n",
+		"rules:\n- cpus: 2-x\n",
+	} {
+		if _, err := irqs.ParseRules([]byte(data)); err == nil {
+			t.Errorf("ParseRules succeeded on %q", data)
+		}
+	}
+}
+
+func TestCheckRules(t *testing.T) {
+	delta := irqs.Stats{
+		0: irqs.Counter{"LOC": 5000, "24": 100},
+		2: irqs.Counter{"LOC": 1000, "24": 0, "NMI": 1},
+		3: irqs.Counter{"LOC": 2500, "130": 3},
+	}
+	rules := []irqs.Rule{
+		mustParseRule(t, "devices=0"),
+		mustParseRule(t, "LOC@2-3=200"),
+	}
+
+	got := irqs.CheckRules(rules, delta, 10*time.Second, cpuset.New(2, 3))
+	expected := []irqs.Violation{
+		{Rule: "devices=0", CPU: 3, IRQ: "130", Count: 3, Rate: 0.3},
+		{Rule: "LOC@2-3=200", CPU: 3, IRQ: "LOC", Count: 2500, Rate: 250},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("violations mismatch got %+v expected %+v", got, expected)
+	}
+	if got[1].String() != `VIOLATION rule "LOC@2-3=200": CPU=3 IRQ=LOC +2500 (250.00/s)` {
+		t.Errorf("unexpected rendering: %q", got[1].String())
+	}
+
+	viol := irqs.Violation{Rule: "TIMER=10", Kind: irqs.KindSoftirq, CPU: 2, IRQ: "TIMER", Count: 200, Rate: 20}
+	if viol.String() != `VIOLATION rule "TIMER=10": CPU=2 SOFTIRQ=TIMER +200 (20.00/s)` {
+		t.Errorf("unexpected rendering: %q", viol.String())
+	}
+}
+
+func mustParseRule(t *testing.T, text string) irqs.Rule {
+	t.Helper()
+	rule, err := irqs.ParseRule(text)
+	if err != nil {
+		t.Fatalf("ParseRule(%q) failed: %v", text, err)
+	}
+	return rule
+}
@@ -26,6 +26,7 @@ import (
 	"strings"
 
 	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+	"github.com/openshift-kni/debug-tools/pkg/irqs"
 )
 
 // presented in kernel order
@@ -47,10 +48,15 @@ type Handler struct {
 }
 
 func New(logger *log.Logger, procfsRoot string) *Handler {
+	return NewWithFS(logger, fswrap.LinuxFS{Log: logger}, procfsRoot)
+}
+
+// NewWithFS creates a Handler which reads the procfs content, rooted at procfsRoot, from fsys.
+func NewWithFS(logger *log.Logger, fsys fswrap.FSWrapper, procfsRoot string) *Handler {
 	return &Handler{
 		log:        logger,
 		procfsRoot: procfsRoot,
-		fs:         fswrap.FSWrapper{Log: logger},
+		fs:         fsys,
 	}
 }
 
@@ -63,6 +69,29 @@ func (handler *Handler) ReadInfo() (*Info, error) {
 	return parseSoftirqs(handler.log, src)
 }
 
+// ReadStats reads the softirq counters in the same format irqs.Handler.ReadStats uses, keyed by softirq name.
+func (handler *Handler) ReadStats() (irqs.Stats, error) {
+	info, err := handler.ReadInfo()
+	if err != nil {
+		return nil, err
+	}
+	return info.Stats(), nil
+}
+
+// Stats converts the counters in irqs.Stats, keyed by softirq name.
+func (info *Info) Stats() irqs.Stats {
+	stats := make(irqs.Stats)
+	for name, counters := range info.Counters {
+		for cpuid, count := range counters {
+			if stats[cpuid] == nil {
+				stats[cpuid] = make(irqs.Counter)
+			}
+			stats[cpuid][name] = count
+		}
+	}
+	return stats
+}
+
 func parseSoftirqs(logger *log.Logger, rd io.Reader) (*Info, error) {
 	src := bufio.NewScanner(rd)
 	src.Scan()
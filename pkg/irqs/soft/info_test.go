@@ -22,8 +22,11 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+	"github.com/openshift-kni/debug-tools/pkg/irqs"
 	softirqs "github.com/openshift-kni/debug-tools/pkg/irqs/soft"
 )
 
@@ -84,6 +87,31 @@ func TestReadInfo(t *testing.T) {
 	}
 }
 
+func TestReadStats(t *testing.T) {
+	fsys := fswrap.NewMemFS(map[string]string{
+		"/proc/softirqs": fakeSoftirqs,
+	})
+	sh := softirqs.NewWithFS(nullLog, fsys, "/proc")
+	prevStats, err := sh.ReadStats()
+	if err != nil {
+		t.Fatalf("ReadStats failed: %v", err)
+	}
+	if len(prevStats) != 4 {
+		t.Fatalf("unexpected cpus: %v", prevStats)
+	}
+	if v := prevStats[2]["NET_TX"]; v != 1282 {
+		t.Errorf("Counters mismatch got %v expected %v", v, 1282)
+	}
+
+	lastStats := prevStats.Clone()
+	lastStats[3]["NET_RX"] += 7
+	delta := prevStats.Delta(lastStats)
+	expected := irqs.Stats{0: {}, 1: {}, 2: {}, 3: {"NET_RX": 7}}
+	if !reflect.DeepEqual(delta, expected) {
+		t.Errorf("Delta mismatch got %v expected %v", delta, expected)
+	}
+}
+
 const fakeSoftirqs string = `                    CPU0       CPU1       CPU2       CPU3       
           HI:       3853     390251      75886       3513
        TIMER:     128764     200838     129415     129834
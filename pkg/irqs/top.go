@@ -0,0 +1,204 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package irqs
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cpuset "k8s.io/utils/cpuset"
+)
+
+// the sort orders of the top table rows
+const (
+	SortByTotal = "total"
+	SortByMax   = "max"
+	SortByName  = "name"
+	// SortByCPUPrefix is followed by the cpu id, like "cpu:3"
+	SortByCPUPrefix = "cpu:"
+)
+
+// TopRow holds the per-second rates of a IRQ or softirq.
+type TopRow struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Device describes the IRQ, if known
+	Device string `json:"device,omitempty"`
+	// Rates are per cpu
+	Rates map[int]float64 `json:"rates"`
+	Total float64         `json:"total"`
+	Max   float64         `json:"max"`
+}
+
+// TopTable holds the busiest IRQs and softirqs over a period.
+type TopTable struct {
+	Timestamp time.Time `json:"timestamp"`
+	Elapsed   string    `json:"elapsed"`
+	CPUs      []int     `json:"cpus"`
+	Isolated  []int     `json:"isolated,omitempty"`
+	Rows      []TopRow  `json:"rows"`
+}
+
+// TopRows computes the per-second rates, on the given cpus, of the counters delta accumulated over the elapsed time.
+// The counters which never fired on the given cpus are skipped.
+func TopRows(kind string, delta Stats, elapsed time.Duration, cpus cpuset.CPUSet, devices map[int]Device) []TopRow {
+	secs := elapsed.Seconds()
+	if secs <= 0 {
+		secs = 1
+	}
+	rows := make(map[string]*TopRow)
+	for _, cpuid := range cpus.List() {
+		for name, count := range delta[cpuid] {
+			if count == 0 {
+				continue
+			}
+			row, ok := rows[name]
+			if !ok {
+				row = &TopRow{
+					Kind:  kind,
+					Name:  name,
+					Rates: make(map[int]float64),
+				}
+				if dev, ok := LookupDevice(devices, name); ok {
+					row.Device = dev.String()
+				}
+				rows[name] = row
+			}
+			rate := float64(count) / secs
+			row.Rates[cpuid] = rate
+			row.Total += rate
+			if rate > row.Max {
+				row.Max = rate
+			}
+		}
+	}
+	res := make([]TopRow, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, *row)
+	}
+	return res
+}
+
+// ValidateSortBy checks the sort order is one of SortByTotal, SortByMax, SortByName, or SortByCPUPrefix followed by a cpu id.
+func ValidateSortBy(sortBy string) error {
+	switch sortBy {
+	case SortByTotal, SortByMax, SortByName:
+		return nil
+	}
+	if strings.HasPrefix(sortBy, SortByCPUPrefix) {
+		if _, err := strconv.Atoi(strings.TrimPrefix(sortBy, SortByCPUPrefix)); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported sort order %q, use %s, %s, %s or %sN", sortBy, SortByTotal, SortByMax, SortByName, SortByCPUPrefix)
+}
+
+// SortTopRows sorts the rows in place, the busiest first (or by name), see ValidateSortBy.
+// Ties are broken by kind and name, so the order is stable across redraws.
+func SortTopRows(rows []TopRow, sortBy string) {
+	key := func(row TopRow) float64 {
+		switch sortBy {
+		case SortByMax:
+			return row.Max
+		case SortByName:
+			return 0
+		}
+		if strings.HasPrefix(sortBy, SortByCPUPrefix) {
+			cpuid, _ := strconv.Atoi(strings.TrimPrefix(sortBy, SortByCPUPrefix))
+			return row.Rates[cpuid]
+		}
+		return row.Total
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		ki, kj := key(rows[i]), key(rows[j])
+		if ki != kj {
+			return ki > kj
+		}
+		if rows[i].Kind != rows[j].Kind {
+			return rows[i].Kind < rows[j].Kind
+		}
+		return lessIRQName(rows[i].Name, rows[j].Name)
+	})
+}
+
+// numeric IRQs sort by number, before the named ones
+func lessIRQName(a, b string) bool {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return na < nb
+	case errA == nil:
+		return true
+	case errB == nil:
+		return false
+	}
+	return a < b
+}
+
+const (
+	topNameWidth = 40
+	topRateWidth = 9
+
+	ansiBold  = "\033[1m"
+	ansiRed   = "\033[31m"
+	ansiReset = "\033[0m"
+)
+
+// WriteTopTable renders the table: a column per cpu, the isolated ones marked with "*".
+// If highlight is true, the non-zero rates on the isolated cpus are highlighted using ANSI escapes.
+func WriteTopTable(w io.Writer, table TopTable, highlight bool) {
+	isolated := cpuset.New(table.Isolated...)
+
+	fmt.Fprintf(w, "%v - rates per second over %s on cpus %v (* isolated)\n\n", table.Timestamp.Format(time.RFC3339), table.Elapsed, cpuset.New(table.CPUs...))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%-8s %-*s %*s", "KIND", topNameWidth, "NAME", topRateWidth, "TOTAL")
+	for _, cpuid := range table.CPUs {
+		label := fmt.Sprintf("CPU%d", cpuid)
+		if isolated.Contains(cpuid) {
+			label += "*"
+		}
+		fmt.Fprintf(&sb, " %*s", topRateWidth, label)
+	}
+	fmt.Fprintln(w, sb.String())
+
+	for _, row := range table.Rows {
+		sb.Reset()
+		name := row.Name
+		if row.Device != "" {
+			name += " " + row.Device
+		}
+		if len(name) > topNameWidth {
+			name = name[:topNameWidth-1] + "~"
+		}
+		fmt.Fprintf(&sb, "%-8s %-*s %*.1f", row.Kind, topNameWidth, name, topRateWidth, row.Total)
+		for _, cpuid := range table.CPUs {
+			rate := row.Rates[cpuid]
+			cell := fmt.Sprintf(" %*.1f", topRateWidth, rate)
+			if highlight && rate > 0 && isolated.Contains(cpuid) {
+				cell = ansiBold + ansiRed + cell + ansiReset
+			}
+			sb.WriteString(cell)
+		}
+		fmt.Fprintln(w, sb.String())
+	}
+}
@@ -0,0 +1,115 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package irqs_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/irqs"
+)
+
+func TestTopRows(t *testing.T) {
+	delta := irqs.Stats{
+		0: irqs.Counter{"LOC": 1000, "142": 0, "9": 4},
+		1: irqs.Counter{"LOC": 200, "142": 60, "9": 0},
+		2: irqs.Counter{"LOC": 4000, "142": 2},
+	}
+	softDelta := irqs.Stats{
+		0: irqs.Counter{"TIMER": 10},
+		1: irqs.Counter{"TIMER": 300, "NET_RX": 80},
+	}
+	devices := map[int]irqs.Device{
+		142: {IRQ: 142, Actions: []string{"eth0-rx-1"}},
+	}
+	cpus := cpuset.New(0, 1)
+
+	rows := irqs.TopRows(irqs.KindIRQ, delta, 2*time.Second, cpus, devices)
+	rows = append(rows, irqs.TopRows(irqs.KindSoftirq, softDelta, 2*time.Second, cpus, nil)...)
+
+	testCases := []struct {
+		sortBy   string
+		expected []string
+	}{
+		{sortBy: irqs.SortByTotal, expected: []string{"LOC", "TIMER", "NET_RX", "142", "9"}},
+		{sortBy: irqs.SortByMax, expected: []string{"LOC", "TIMER", "NET_RX", "142", "9"}},
+		{sortBy: "cpu:1", expected: []string{"TIMER", "LOC", "NET_RX", "142", "9"}},
+		{sortBy: irqs.SortByName, expected: []string{"9", "142", "LOC", "NET_RX", "TIMER"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.sortBy, func(t *testing.T) {
+			if err := irqs.ValidateSortBy(tc.sortBy); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			irqs.SortTopRows(rows, tc.sortBy)
+			var got []string
+			for _, row := range rows {
+				got = append(got, row.Name)
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("got %v expected %v", got, tc.expected)
+			}
+		})
+	}
+
+	irqs.SortTopRows(rows, irqs.SortByTotal)
+	if rows[0].Total != 600 || rows[0].Rates[0] != 500 || rows[0].Max != 500 {
+		t.Errorf("unexpected rates: %+v", rows[0])
+	}
+	if rows[3].Device != "eth0-rx-1" {
+		t.Errorf("unexpected device: %+v", rows[3])
+	}
+
+	for _, sortBy := range []string{"", "busiest", "cpu:", "cpu:x"} {
+		if err := irqs.ValidateSortBy(sortBy); err == nil {
+			t.Errorf("ValidateSortBy(%q) succeeded", sortBy)
+		}
+	}
+}
+
+func TestWriteTopTable(t *testing.T) {
+	table := irqs.TopTable{
+		Timestamp: time.Date(2024, 3, 11, 10, 0, 0, 0, time.UTC),
+		Elapsed:   "1s",
+		CPUs:      []int{0, 1},
+		Isolated:  []int{1},
+		Rows: []irqs.TopRow{
+			{Kind: irqs.KindIRQ, Name: "LOC", Rates: map[int]float64{0: 10, 1: 2}, Total: 12, Max: 10},
+		},
+	}
+
+	var buf bytes.Buffer
+	irqs.WriteTopTable(&buf, table, false)
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) < 4 || !strings.HasSuffix(lines[2], "CPU0     CPU1*") || !strings.HasPrefix(lines[3], "IRQ      LOC") {
+		t.Errorf("unexpected table:\n%s", buf.String())
+	}
+	if strings.Contains(buf.String(), "\033[") {
+		t.Errorf("unexpected highlight:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	irqs.WriteTopTable(&buf, table, true)
+	if !strings.Contains(buf.String(), "\033[1m\033[31m       2.0\033[0m") {
+		t.Errorf("missing highlight:\n%q", buf.String())
+	}
+}
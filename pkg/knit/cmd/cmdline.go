@@ -0,0 +1,78 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openshift-kni/debug-tools/pkg/cmdline"
+)
+
+func NewCmdlineCommand(knitOpts *KnitOptions) *cobra.Command {
+	cmdlineCmd := &cobra.Command{
+		Use:   "cmdline",
+		Short: "audit the kernel boot parameters for low-latency tuning",
+		Long: `audit the kernel boot parameters for low-latency tuning.
+The parameters are checked against the isolated cpu set given with --cpulist.
+If --cpulist is not given, the isolated cpu set is inferred from isolcpus or nohz_full.
+Exits with code 2 if any error-level finding is reported.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return auditCmdline(cmd, knitOpts, args)
+		},
+		Args: cobra.NoArgs,
+	}
+	return cmdlineCmd
+}
+
+func auditCmdline(cmd *cobra.Command, knitOpts *KnitOptions, args []string) error {
+	ch := cmdline.NewWithFS(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot)
+	cl, err := ch.Read()
+	if err != nil {
+		return err
+	}
+
+	isolated := knitOpts.Cpus
+	if !cmd.Flags().Changed("cpulist") {
+		isolated, err = cmdline.IsolatedCPUs(cl)
+		if err != nil {
+			return fmt.Errorf("error inferring the isolated cpus: %v", err)
+		}
+		knitOpts.Log.Printf("inferred isolated cpus: %v", isolated)
+	}
+
+	report := cmdline.Audit(cl, isolated)
+
+	if knitOpts.JsonOutput {
+		json.NewEncoder(os.Stdout).Encode(report)
+	} else {
+		fmt.Printf("isolated cpus: %v\n", isolated)
+		for _, finding := range report.Findings {
+			fmt.Println(finding.String())
+		}
+	}
+	if report.HasErrors() {
+		return &ExitError{
+			Code: ExitCodeViolation,
+			Err:  fmt.Errorf("the kernel boot parameters are not suitable for low-latency"),
+		}
+	}
+	return nil
+}
@@ -19,18 +19,30 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 
-	"github.com/openshift-kni/debug-tools/pkg/procs"
+	corev1 "k8s.io/api/core/v1"
 	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/cmdline"
+	"github.com/openshift-kni/debug-tools/pkg/pinning"
+	"github.com/openshift-kni/debug-tools/pkg/procs"
 )
 
 type cpuAffOptions struct {
-	pidIdent string
+	pidIdent  string
+	podsFile  string
+	pod       string
+	namespace string
+	container string
+	rtOnly    bool
+	policies  []string
 }
 
 func NewCPUAffinityCommand(knitOpts *KnitOptions) *cobra.Command {
@@ -38,12 +50,22 @@ func NewCPUAffinityCommand(knitOpts *KnitOptions) *cobra.Command {
 	cpuAff := &cobra.Command{
 		Use:   "cpuaff",
 		Short: "show cpu thread affinities",
+		Long: `show cpu thread affinities and scheduling settings.
+SCHED_FIFO threads sharing isolated cpus with ksoftirqd or with other realtime threads are flagged.
+The isolated cpus are the ones given with --cpulist, or are inferred from isolcpus or nohz_full.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return showCPUAffinity(cmd, knitOpts, opts, args)
 		},
 		Args: cobra.NoArgs,
 	}
 	cpuAff.Flags().StringVarP(&opts.pidIdent, "pid", "p", "", "monitor only threads belonging to this pid (default is all).")
+	cpuAff.Flags().StringVar(&opts.podsFile, "pods-file", "", "resolve the pod and container names using this file (as produced by `kubectl get pods -o json`).")
+	cpuAff.Flags().StringVar(&opts.pod, "pod", "", "monitor only threads belonging to this pod, by name (requires --pods-file) or UID.")
+	cpuAff.Flags().StringVar(&opts.namespace, "namespace", "", "monitor only threads belonging to pods in this namespace (requires --pods-file).")
+	cpuAff.Flags().StringVar(&opts.container, "container", "", "monitor only threads belonging to this container, by name (requires --pods-file) or ID prefix.")
+	cpuAff.Flags().BoolVar(&opts.rtOnly, "rt-only", false, "monitor only threads with realtime scheduling policies (SCHED_FIFO, SCHED_RR, SCHED_DEADLINE).")
+	cpuAff.Flags().StringSliceVar(&opts.policies, "policy", nil, "monitor only threads with these scheduling policies (e.g. fifo,rr).")
+	cpuAff.MarkFlagsMutuallyExclusive("rt-only", "policy")
 	return cpuAff
 }
 
@@ -53,6 +75,13 @@ type runnable struct {
 	ProcessName string `json:"process"`
 	ThreadName  string `json:"thread"`
 	CPUAffinity []int  `json:"affinity"`
+	Policy      string `json:"policy,omitempty"`
+	RTPriority  int    `json:"rtPriority,omitempty"`
+	Nice        int    `json:"nice,omitempty"`
+	// Owner is set only for processes running in pods
+	Owner *pinning.Owner `json:"owner,omitempty"`
+	// SharesWith lists the TIDs of the realtime or ksoftirqd threads competing on isolated cpus
+	SharesWith []int `json:"sharesWith,omitempty"`
 }
 
 func (ru runnable) String() string {
@@ -60,27 +89,75 @@ func (ru runnable) String() string {
 	// "The thread name is a meaningful C language string, whose length is restricted to 16 characters,
 	// including the terminating null byte"
 	// for process names howevwver we just pick a "usually long enough" format value
-	return fmt.Sprintf("PID %6d (%-32s) TID %6d (%-16s) can run on %v", ru.PID, ru.ProcessName, ru.TID, ru.ThreadName, ru.CPUAffinity)
+	desc := fmt.Sprintf("PID %6d (%-32s) TID %6d (%-16s) can run on %v", ru.PID, ru.ProcessName, ru.TID, ru.ThreadName, ru.CPUAffinity)
+	if ru.Policy != "" {
+		desc += " " + procs.SchedInfo{Policy: procs.SchedPolicy(ru.Policy), RTPriority: ru.RTPriority, Nice: ru.Nice}.String()
+	}
+	if ru.Owner != nil {
+		desc += " pod " + ru.Owner.String()
+	}
+	if len(ru.SharesWith) > 0 {
+		desc += fmt.Sprintf(" WARNING: shares isolated cpus with TIDs %v", ru.SharesWith)
+	}
+	return desc
+}
+
+// matches tells if a process belongs to the pod and container selected by the user.
+// Unresolved pods are reported with their UID as name, see pinning.Resolver.
+func (opts *cpuAffOptions) matches(procInfo procs.PIDInfo, owner *pinning.Owner) bool {
+	if opts.pod == "" && opts.namespace == "" && opts.container == "" {
+		return true
+	}
+	if owner == nil {
+		return false
+	}
+	if opts.namespace != "" && owner.Ref.Namespace != opts.namespace {
+		return false
+	}
+	if opts.pod != "" && owner.Ref.Pod != opts.pod && procInfo.PodUID != opts.pod {
+		return false
+	}
+	if opts.container != "" && owner.Ref.Container != opts.container && !strings.HasPrefix(procInfo.ContainerID, opts.container) {
+		return false
+	}
+	return true
 }
 
 func showCPUAffinity(cmd *cobra.Command, knitOpts *KnitOptions, opts *cpuAffOptions, args []string) error {
-	ph := procs.New(knitOpts.Log, knitOpts.ProcFSRoot)
+	ph := procs.NewWithFS(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot)
 
+	selectedPID := 0
 	if opts.pidIdent != "" {
 		pid, err := strconv.Atoi(opts.pidIdent)
 		if err != nil {
 			return fmt.Errorf("error parsing %q: %v", opts.pidIdent, err)
 		}
-		procInfo, err := ph.FromPID(pid)
-		for tid, tidInfo := range procInfo.TIDs {
-			threadCpus := cpuset.New(tidInfo.Affinity...)
+		selectedPID = pid
+	}
 
-			cpus := threadCpus.Intersection(knitOpts.Cpus)
-			if cpus.Size() != 0 {
-				fmt.Printf("PID %6d TID %6d can run on %v\n", pid, tid, cpus.String())
-			}
+	if opts.namespace != "" && opts.podsFile == "" {
+		return fmt.Errorf("filtering by namespace requires --pods-file")
+	}
+	var pods []corev1.Pod
+	if opts.podsFile != "" {
+		data, err := ioutil.ReadFile(opts.podsFile)
+		if err != nil {
+			return fmt.Errorf("error reading the pods from %q: %v", opts.podsFile, err)
 		}
-		return nil
+		pods, err = pinning.ParsePodList(data)
+		if err != nil {
+			return fmt.Errorf("error parsing the pods from %q: %v", opts.podsFile, err)
+		}
+	}
+	resolver := pinning.NewResolverFromPods(pods)
+
+	policies := make(map[procs.SchedPolicy]bool)
+	for _, name := range opts.policies {
+		policy, err := procs.ParsePolicy(name)
+		if err != nil {
+			return err
+		}
+		policies[policy] = true
 	}
 
 	procInfos, err := ph.ListAll()
@@ -88,12 +165,35 @@ func showCPUAffinity(cmd *cobra.Command, knitOpts *KnitOptions, opts *cpuAffOpti
 		return fmt.Errorf("error getting process infos from %q: %v", knitOpts.ProcFSRoot, err)
 	}
 
+	if _, ok := procInfos[selectedPID]; selectedPID != 0 && !ok {
+		return fmt.Errorf("process %d not found in %q", selectedPID, knitOpts.ProcFSRoot)
+	}
+
+	// the contention is found among all the threads, even when only one pid is shown
+	sharing := make(map[int][]int)
+	for _, cont := range procs.FindRTContention(procInfos, isolatedCPUs(cmd, knitOpts)) {
+		sharing[cont.TID] = cont.SharesWith
+	}
+
 	var runnables []runnable
 	for _, pid := range sortedPids(procInfos) {
+		if selectedPID != 0 && pid != selectedPID {
+			continue
+		}
 		procInfo := procInfos[pid]
+		owner := podOwner(resolver, procInfo)
+		if !opts.matches(procInfo, owner) {
+			continue
+		}
 
 		for _, tid := range sortedTids(procInfo.TIDs) {
 			tidInfo := procInfo.TIDs[tid]
+			if opts.rtOnly && !tidInfo.Policy.IsRealtime() {
+				continue
+			}
+			if len(policies) > 0 && !policies[tidInfo.Policy] {
+				continue
+			}
 
 			threadCpus := cpuset.New(tidInfo.Affinity...)
 			cpus := threadCpus.Intersection(knitOpts.Cpus)
@@ -106,6 +206,11 @@ func showCPUAffinity(cmd *cobra.Command, knitOpts *KnitOptions, opts *cpuAffOpti
 				ProcessName: procInfo.Name,
 				ThreadName:  tidInfo.Name,
 				CPUAffinity: cpus.List(),
+				Policy:      string(tidInfo.Policy),
+				RTPriority:  tidInfo.RTPriority,
+				Nice:        tidInfo.Nice,
+				Owner:       owner,
+				SharesWith:  sharing[tid],
 			})
 		}
 	}
@@ -120,6 +225,53 @@ func showCPUAffinity(cmd *cobra.Command, knitOpts *KnitOptions, opts *cpuAffOpti
 	return nil
 }
 
+// isolatedCPUs returns the cpus given by the user, or the ones isolated on the kernel command line.
+// Failures are not critical, and yield an empty set.
+func isolatedCPUs(cmd *cobra.Command, knitOpts *KnitOptions) cpuset.CPUSet {
+	if cmd.Flags().Changed("cpulist") {
+		return knitOpts.Cpus
+	}
+	isolated := kernelIsolatedCPUs(knitOpts)
+	knitOpts.Log.Printf("inferred isolated cpus: %v", isolated)
+	return isolated
+}
+
+// kernelIsolatedCPUs returns the isolated cpus found in the kernel command line, or an empty set.
+func kernelIsolatedCPUs(knitOpts *KnitOptions) cpuset.CPUSet {
+	cl, err := cmdline.NewWithFS(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot).Read()
+	if err != nil {
+		knitOpts.Log.Printf("error reading the kernel command line: %v", err)
+		return cpuset.New()
+	}
+	isolated, err := cmdline.IsolatedCPUs(cl)
+	if err != nil {
+		knitOpts.Log.Printf("error inferring the isolated cpus: %v", err)
+		return cpuset.New()
+	}
+	return isolated
+}
+
+func podOwner(resolver *pinning.Resolver, procInfo procs.PIDInfo) *pinning.Owner {
+	if procInfo.ContainerID == "" && procInfo.PodUID == "" {
+		return nil
+	}
+	cgInfo := procs.CgroupInfoFromPath(procInfo.CgroupPath)
+	owner := resolver.Owner(procInfo.Name, cgInfo)
+	if owner.Kind == pinning.OwnerInfra && !cgInfo.Infra && cgInfo.ContainerID != "" {
+		// unresolved application container: show the short ID, like crictl does
+		owner.Kind = pinning.OwnerContainer
+		owner.Ref.Container = shortContainerID(cgInfo.ContainerID)
+	}
+	return &owner
+}
+
+func shortContainerID(containerID string) string {
+	if len(containerID) > 13 {
+		return containerID[:13]
+	}
+	return containerID
+}
+
 func sortedPids(procInfos map[int]procs.PIDInfo) []int {
 	pids := make([]int, len(procInfos))
 	for pid := range procInfos {
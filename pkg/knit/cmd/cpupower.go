@@ -0,0 +1,160 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/cpupower"
+)
+
+type cpuPowerOptions struct {
+	maxLatency uint64
+	watch      bool
+	period     string
+	maxRuns    int
+}
+
+func NewCPUPowerCommand(knitOpts *KnitOptions) *cobra.Command {
+	opts := &cpuPowerOptions{}
+	cpuPower := &cobra.Command{
+		Use:   "cpupower",
+		Short: "show the frequency governor and the idle states of the isolated cpus",
+		Long: `show the frequency governor and the idle states (C-states) of the isolated cpus.
+The governors which can lower the frequency, and the enabled idle states with exit latency higher than
+--max-latency, are reported as warnings. With --watch, the residency in the idle states is reported every period.
+The isolated cpus are the ones given with --cpulist. If --cpulist is not given, the isolated cpu set is inferred
+from isolcpus or nohz_full, and if none is found all the cpus are checked.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return showCPUPower(cmd, knitOpts, opts, args)
+		},
+		Args: cobra.NoArgs,
+	}
+	cpuPower.Flags().Uint64Var(&opts.maxLatency, "max-latency", 10, "maximum exit latency, in microseconds, of the enabled idle states.")
+	cpuPower.Flags().BoolVar(&opts.watch, "watch", false, "watch the residency in the idle states.")
+	cpuPower.Flags().IntVarP(&opts.maxRuns, "watch-times", "T", -1, "number of watch loops to perform, each every `watch-period`. Use -1 to run forever.")
+	cpuPower.Flags().StringVarP(&opts.period, "watch-period", "W", "1s", "period to poll the idle states.")
+	return cpuPower
+}
+
+func showCPUPower(cmd *cobra.Command, knitOpts *KnitOptions, opts *cpuPowerOptions, args []string) error {
+	cpus := isolatedCPUs(cmd, knitOpts)
+	if cpus.IsEmpty() {
+		cpus = knitOpts.Cpus
+	}
+
+	handler := cpupower.NewWithFS(knitOpts.Log, knitOpts.FS, knitOpts.SysFSRoot)
+	// the cpus not present are skipped, so from now on use only the ones we read
+	infos, err := handler.Read(cpus)
+	if err != nil {
+		return err
+	}
+	var cpuids []int
+	for _, ci := range infos {
+		cpuids = append(cpuids, ci.CPU)
+	}
+	cpus = cpuset.New(cpuids...)
+	if opts.watch {
+		return watchIdleStates(knitOpts, opts, handler, cpus, infos)
+	}
+
+	for idx := range infos {
+		infos[idx].Warnings = infos[idx].Check(opts.maxLatency)
+	}
+
+	if knitOpts.JsonOutput {
+		json.NewEncoder(os.Stdout).Encode(infos)
+		return nil
+	}
+
+	for _, ci := range infos {
+		if ci.Frequency == nil {
+			fmt.Printf("CPU %3d: cpufreq not available\n", ci.CPU)
+		} else {
+			fmt.Printf("CPU %3d: governor %s (%s), frequency %d-%d kHz (hardware %d-%d kHz), current %d kHz\n",
+				ci.CPU, ci.Frequency.Governor, ci.Frequency.Driver, ci.Frequency.Min, ci.Frequency.Max, ci.Frequency.HWMin, ci.Frequency.HWMax, ci.Frequency.Cur)
+		}
+		for _, st := range ci.IdleStates {
+			status := "enabled"
+			if st.Disabled {
+				status = "disabled"
+			}
+			fmt.Printf("  idle state %-8s latency %5dus %s\n", st.Name, st.Latency, status)
+		}
+		for _, warning := range ci.Warnings {
+			fmt.Printf("  WARNING: %s\n", warning)
+		}
+	}
+	return nil
+}
+
+func watchIdleStates(knitOpts *KnitOptions, opts *cpuPowerOptions, handler *cpupower.Handler, cpus cpuset.CPUSet, prevInfos []cpupower.CPUInfo) error {
+	if opts.maxRuns == 0 {
+		return nil
+	}
+	period, err := time.ParseDuration(opts.period)
+	if err != nil {
+		return err
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+
+	ticker := time.NewTicker(period)
+	prevTs := time.Now()
+	for iterCount := 1; ; iterCount++ {
+		var ts time.Time
+		select {
+		case <-c:
+			return nil
+		case ts = <-ticker.C:
+		}
+
+		lastInfos, err := handler.Read(cpus)
+		if err != nil {
+			return err
+		}
+		residency := cpupower.IdleResidency(prevInfos, lastInfos, ts.Sub(prevTs))
+
+		if knitOpts.JsonOutput {
+			json.NewEncoder(os.Stdout).Encode(struct {
+				Timestamp time.Time            `json:"timestamp"`
+				Residency []cpupower.Residency `json:"residency"`
+			}{
+				Timestamp: ts,
+				Residency: residency,
+			})
+		} else {
+			for _, res := range residency {
+				fmt.Printf("%v %s\n", ts.Format(time.RFC3339), res.String())
+			}
+		}
+
+		if opts.maxRuns > 0 && iterCount >= opts.maxRuns {
+			return nil
+		}
+		prevInfos, prevTs = lastInfos, ts
+	}
+}
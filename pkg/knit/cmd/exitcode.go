@@ -0,0 +1,54 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package cmd
+
+import (
+	"errors"
+)
+
+const (
+	// ExitCodeError is used for any failure running the command
+	ExitCodeError = 1
+	// ExitCodeViolation is used when the command ran successfully, but the checks it performed failed
+	ExitCodeViolation = 2
+)
+
+// ExitError carries the exit code the process should terminate with.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (ee *ExitError) Error() string {
+	return ee.Err.Error()
+}
+
+func (ee *ExitError) Unwrap() error {
+	return ee.Err
+}
+
+// ExitCode returns the process exit code for the error returned by a command.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return ExitCodeError
+}
@@ -0,0 +1,88 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openshift-kni/debug-tools/pkg/irqs"
+	softirqs "github.com/openshift-kni/debug-tools/pkg/irqs/soft"
+	"github.com/openshift-kni/debug-tools/pkg/metrics"
+)
+
+type exporterOptions struct {
+	listenAddress string
+	metricsPath   string
+}
+
+func NewExporterCommand(knitOpts *KnitOptions) *cobra.Command {
+	opts := &exporterOptions{}
+	exporter := &cobra.Command{
+		Use:   "exporter",
+		Short: "serve the IRQ and softirq counters as prometheus metrics",
+		Long: `serve the per-cpu IRQ and softirq counters as prometheus metrics, until a UNIX signal (SIGINT, SIGTERM) arrives.
+The cpus are labeled as isolated or housekeeping. The isolated cpus are the ones given with --cpulist,
+or are inferred from isolcpus or nohz_full.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return serveMetrics(cmd, knitOpts, opts, args)
+		},
+		Args: cobra.NoArgs,
+	}
+	exporter.Flags().StringVarP(&opts.listenAddress, "listen-address", "L", ":9730", "address to listen on for the HTTP requests.")
+	exporter.Flags().StringVar(&opts.metricsPath, "metrics-path", "/metrics", "path under which to serve the metrics.")
+	return exporter
+}
+
+func serveMetrics(cmd *cobra.Command, knitOpts *KnitOptions, opts *exporterOptions, args []string) error {
+	isolated := isolatedCPUs(cmd, knitOpts)
+	collector := metrics.NewInterruptsCollector(
+		knitOpts.Log,
+		irqs.NewWithFS(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot),
+		softirqs.NewWithFS(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot),
+		isolated,
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle(opts.metricsPath, metrics.NewHandler(knitOpts.Log, collector))
+	srv := &http.Server{
+		Addr:    opts.listenAddress,
+		Handler: mux,
+	}
+
+	exitSignal := make(chan os.Signal, 1)
+	signal.Notify(exitSignal, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-exitSignal
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	knitOpts.Log.Printf("serving metrics on %s%s (isolated cpus: %v)", opts.listenAddress, opts.metricsPath, isolated)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
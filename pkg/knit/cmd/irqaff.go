@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 
@@ -32,6 +33,7 @@ type irqAffOptions struct {
 	checkEffective  bool
 	checkSoftirqs   bool
 	showEmptySource bool
+	compare         bool
 }
 
 func NewIRQAffinityCommand(knitOpts *KnitOptions) *cobra.Command {
@@ -42,6 +44,8 @@ func NewIRQAffinityCommand(knitOpts *KnitOptions) *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if opts.checkSoftirqs {
 				return showSoftIRQAffinity(cmd, knitOpts, opts, args)
+			} else if opts.compare {
+				return compareIRQAffinity(cmd, knitOpts, opts, args)
 			} else {
 				return showIRQAffinity(cmd, knitOpts, opts, args)
 			}
@@ -51,17 +55,65 @@ func NewIRQAffinityCommand(knitOpts *KnitOptions) *cobra.Command {
 	irqAff.Flags().BoolVarP(&opts.checkEffective, "effective-affinity", "E", false, "check effective affinity.")
 	irqAff.Flags().BoolVarP(&opts.checkSoftirqs, "softirqs", "s", false, "check softirqs counters.")
 	irqAff.Flags().BoolVarP(&opts.showEmptySource, "show-empty-source", "e", false, "show infos if IRQ source is not reported.")
+	irqAff.Flags().BoolVarP(&opts.compare, "compare", "c", false, "compare the requested (smp_affinity) and the effective affinity.")
+	irqAff.MarkFlagsMutuallyExclusive("compare", "softirqs")
 	return irqAff
 }
 
 type irqAffinity struct {
-	IRQ         int    `json:"irq"`
-	Source      string `json:"source"`
-	CPUAffinity []int  `json:"affinity"`
+	IRQ         int          `json:"irq"`
+	Source      string       `json:"source"`
+	CPUAffinity []int        `json:"affinity"`
+	Device      *irqs.Device `json:"device,omitempty"`
+	Managed     bool         `json:"managed,omitempty"`
+	// Isolated are the isolated cpus the IRQ can run on
+	Isolated []int `json:"isolated,omitempty"`
 }
 
 func (ia irqAffinity) String() string {
-	return fmt.Sprintf("IRQ %3d [%24s]: can run on %v", ia.IRQ, ia.Source, ia.CPUAffinity)
+	desc := ia.Source
+	if ia.Device != nil {
+		desc = ia.Device.String()
+	}
+	res := fmt.Sprintf("IRQ %3d [%24s]: can run on %v", ia.IRQ, desc, ia.CPUAffinity)
+	if len(ia.Isolated) == 0 {
+		if ia.Managed {
+			res += " (managed)"
+		}
+		return res
+	}
+	if ia.Managed {
+		return res + fmt.Sprintf(" MANAGED on isolated cpus %v: affinity set by the kernel, see isolcpus=managed_irq", ia.Isolated)
+	}
+	return res + fmt.Sprintf(" WARNING: can run on isolated cpus %v", ia.Isolated)
+}
+
+type irqAffinityDiff struct {
+	IRQ       int          `json:"irq"`
+	Source    string       `json:"source"`
+	Device    *irqs.Device `json:"device,omitempty"`
+	Requested []int        `json:"requested"`
+	Effective []int        `json:"effective"`
+	// RequestedOnly and EffectiveOnly are the difference between the requested and the effective affinity
+	RequestedOnly []int `json:"requestedOnly,omitempty"`
+	EffectiveOnly []int `json:"effectiveOnly,omitempty"`
+	// Escaped are the checked cpus the IRQ effectively runs on, despite the requested affinity excludes them all
+	Escaped []int `json:"escaped,omitempty"`
+}
+
+func (iad irqAffinityDiff) String() string {
+	desc := iad.Source
+	if iad.Device != nil {
+		desc = iad.Device.String()
+	}
+	res := fmt.Sprintf("IRQ %3d [%24s]: requested %v effective %v", iad.IRQ, desc, iad.Requested, iad.Effective)
+	if len(iad.EffectiveOnly) > 0 {
+		res += fmt.Sprintf(" (not requested %v)", iad.EffectiveOnly)
+	}
+	if len(iad.Escaped) > 0 {
+		res += fmt.Sprintf(" WARNING: effectively runs on checked cpus %v", iad.Escaped)
+	}
+	return res
 }
 
 type softirqAffinity struct {
@@ -74,7 +126,7 @@ func (sa softirqAffinity) String() string {
 }
 
 func showIRQAffinity(cmd *cobra.Command, knitOpts *KnitOptions, opts *irqAffOptions, args []string) error {
-	ih := irqs.New(knitOpts.Log, knitOpts.ProcFSRoot)
+	ih := irqs.NewWithFS(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot).WithDebugFS(filepath.Join(knitOpts.SysFSRoot, "kernel", "debug"))
 
 	flags := uint(0)
 	if opts.checkEffective {
@@ -86,20 +138,29 @@ func showIRQAffinity(cmd *cobra.Command, knitOpts *KnitOptions, opts *irqAffOpti
 		return fmt.Errorf("error parsing irqs from %q: %v", knitOpts.ProcFSRoot, err)
 	}
 
+	devices := irqs.NewResolver(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot, knitOpts.SysFSRoot).Resolve()
+	isolated := isolatedCPUs(cmd, knitOpts)
+
 	var irqAffinities []irqAffinity
 	for _, irqInfo := range irqInfos {
 		cpus := irqInfo.CPUs.Intersection(knitOpts.Cpus)
 		if cpus.Size() == 0 {
 			continue
 		}
-		if irqInfo.Source == "" && !opts.showEmptySource {
-			continue
-		}
-		irqAffinities = append(irqAffinities, irqAffinity{
+		ia := irqAffinity{
 			IRQ:         irqInfo.IRQ,
 			Source:      irqInfo.Source,
 			CPUAffinity: cpus.List(),
-		})
+			Managed:     irqInfo.Managed,
+			Isolated:    cpus.Intersection(isolated).List(),
+		}
+		if dev, ok := devices[irqInfo.IRQ]; ok {
+			ia.Device = &dev
+		}
+		if ia.Source == "" && (ia.Device == nil || ia.Device.Name() == "") && !opts.showEmptySource {
+			continue
+		}
+		irqAffinities = append(irqAffinities, ia)
 	}
 
 	if knitOpts.JsonOutput {
@@ -112,8 +173,66 @@ func showIRQAffinity(cmd *cobra.Command, knitOpts *KnitOptions, opts *irqAffOpti
 	return nil
 }
 
+func compareIRQAffinity(cmd *cobra.Command, knitOpts *KnitOptions, opts *irqAffOptions, args []string) error {
+	ih := irqs.NewWithFS(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot)
+
+	requestedInfos, err := ih.ReadInfo(0)
+	if err != nil {
+		return fmt.Errorf("error parsing irqs from %q: %v", knitOpts.ProcFSRoot, err)
+	}
+	effectiveInfos, err := ih.ReadInfo(irqs.EffectiveAffinity)
+	if err != nil {
+		return fmt.Errorf("error parsing irqs from %q: %v", knitOpts.ProcFSRoot, err)
+	}
+	effectiveCPUs := make(map[int]cpuset.CPUSet)
+	for _, irqInfo := range effectiveInfos {
+		effectiveCPUs[irqInfo.IRQ] = irqInfo.CPUs
+	}
+
+	devices := irqs.NewResolver(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot, knitOpts.SysFSRoot).Resolve()
+
+	var irqDiffs []irqAffinityDiff
+	for _, irqInfo := range requestedInfos {
+		effective, ok := effectiveCPUs[irqInfo.IRQ]
+		if !ok {
+			continue
+		}
+		requested := irqInfo.CPUs
+		if requested.Intersection(knitOpts.Cpus).Size() == 0 && effective.Intersection(knitOpts.Cpus).Size() == 0 {
+			continue
+		}
+		iad := irqAffinityDiff{
+			IRQ:           irqInfo.IRQ,
+			Source:        irqInfo.Source,
+			Requested:     requested.List(),
+			Effective:     effective.List(),
+			RequestedOnly: requested.Difference(effective).List(),
+			EffectiveOnly: effective.Difference(requested).List(),
+		}
+		if requested.Intersection(knitOpts.Cpus).Size() == 0 {
+			iad.Escaped = effective.Intersection(knitOpts.Cpus).List()
+		}
+		if dev, ok := devices[irqInfo.IRQ]; ok {
+			iad.Device = &dev
+		}
+		if iad.Source == "" && (iad.Device == nil || iad.Device.Name() == "") && !opts.showEmptySource {
+			continue
+		}
+		irqDiffs = append(irqDiffs, iad)
+	}
+
+	if knitOpts.JsonOutput {
+		json.NewEncoder(os.Stdout).Encode(irqDiffs)
+	} else {
+		for _, irqDiff := range irqDiffs {
+			fmt.Println(irqDiff.String())
+		}
+	}
+	return nil
+}
+
 func showSoftIRQAffinity(cmd *cobra.Command, knitOpts *KnitOptions, opts *irqAffOptions, args []string) error {
-	sh := softirqs.New(knitOpts.Log, knitOpts.ProcFSRoot)
+	sh := softirqs.NewWithFS(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot)
 	info, err := sh.ReadInfo()
 
 	if err != nil {
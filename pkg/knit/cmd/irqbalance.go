@@ -0,0 +1,106 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/irqbalance"
+	"github.com/openshift-kni/debug-tools/pkg/irqs"
+)
+
+type irqBalanceOptions struct {
+	checkEffective bool
+}
+
+func NewIRQBalanceCommand(knitOpts *KnitOptions) *cobra.Command {
+	opts := &irqBalanceOptions{}
+	irqBalance := &cobra.Command{
+		Use:   "irqbalance",
+		Short: "check the irqbalance banned cpus against the isolated cpus and the IRQ affinities",
+		Long: `check the irqbalance banned cpus against the isolated cpus and the IRQ affinities.
+The configuration is read from the etc root (see --etc), in sysconfig/irqbalance or default/irqbalance.
+If no cpu is banned in the configuration, irqbalance bans the isolated cpus found in the kernel command line.
+The banned cpus are checked against the isolated cpu set given with --cpulist.
+If --cpulist is not given, the isolated cpu set is inferred from isolcpus or nohz_full.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return checkIRQBalance(cmd, knitOpts, opts, args)
+		},
+		Args: cobra.NoArgs,
+	}
+	irqBalance.Flags().BoolVarP(&opts.checkEffective, "effective-affinity", "E", false, "check effective affinity.")
+	return irqBalance
+}
+
+func checkIRQBalance(cmd *cobra.Command, knitOpts *KnitOptions, opts *irqBalanceOptions, args []string) error {
+	conf, err := irqbalance.NewWithFS(knitOpts.Log, knitOpts.FS, knitOpts.EtcRoot).Read()
+	if err != nil {
+		return fmt.Errorf("error reading the irqbalance configuration from %q: %v", knitOpts.EtcRoot, err)
+	}
+	conf = conf.WithKernelBanned(kernelIsolatedCPUs(knitOpts))
+
+	ih := irqs.NewWithFS(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot).WithDebugFS(filepath.Join(knitOpts.SysFSRoot, "kernel", "debug"))
+	flags := uint(0)
+	if opts.checkEffective {
+		flags |= irqs.EffectiveAffinity
+	}
+	irqInfos, err := ih.ReadInfo(flags)
+	if err != nil {
+		return fmt.Errorf("error parsing irqs from %q: %v", knitOpts.ProcFSRoot, err)
+	}
+
+	report := irqbalance.Check(conf, isolatedCPUs(cmd, knitOpts), irqInfos)
+
+	if knitOpts.JsonOutput {
+		json.NewEncoder(os.Stdout).Encode(report)
+		return nil
+	}
+
+	path := conf.Path
+	if path == "" {
+		path = "not found, using the defaults"
+	}
+	fmt.Printf("irqbalance configuration: %s\n", path)
+	fmt.Printf("banned cpus: %v (from %s)\n", conf.BannedCPUs, conf.BannedFrom)
+	if len(conf.BannedIRQs) > 0 {
+		fmt.Printf("banned IRQs: %v\n", conf.BannedIRQs)
+	}
+	if conf.PolicyScript != "" {
+		fmt.Printf("policy script: %s (may ban IRQs at runtime)\n", conf.PolicyScript)
+	}
+	if conf.BanScript != "" {
+		fmt.Printf("ban script: %s (may ban IRQs at runtime)\n", conf.BanScript)
+	}
+	fmt.Printf("isolated cpus: %v\n", cpuset.New(report.Isolated...))
+	if len(report.IsolatedNotBanned) > 0 {
+		fmt.Printf("WARNING: isolated cpus not banned: %v\n", cpuset.New(report.IsolatedNotBanned...))
+	}
+	if len(report.BannedNotIsolated) > 0 {
+		fmt.Printf("banned cpus not isolated: %v\n", cpuset.New(report.BannedNotIsolated...))
+	}
+	for _, iob := range report.IRQsOnBanned {
+		fmt.Println(iob.String())
+	}
+	return nil
+}
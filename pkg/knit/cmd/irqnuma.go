@@ -0,0 +1,127 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openshift-kni/debug-tools/pkg/irqs"
+	"github.com/openshift-kni/debug-tools/pkg/numalign"
+)
+
+type irqNUMAOptions struct {
+	remoteOnly bool
+}
+
+func NewIRQNUMACommand(knitOpts *KnitOptions) *cobra.Command {
+	opts := &irqNUMAOptions{}
+	irqNUMA := &cobra.Command{
+		Use:   "irqnuma",
+		Short: "show the NUMA locality of the device IRQs",
+		Long: `show the NUMA locality of the device IRQs.
+Compares the effective affinity of the IRQs served by PCI devices with the NUMA node of the devices,
+and reports the IRQs delivered to remote NUMA nodes, and the per-device local and remote IRQ counts.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return showIRQNUMALocality(cmd, knitOpts, opts, args)
+		},
+		Args: cobra.NoArgs,
+	}
+	irqNUMA.Flags().BoolVarP(&opts.remoteOnly, "remote-only", "r", false, "show only the IRQs delivered to remote NUMA nodes.")
+	return irqNUMA
+}
+
+func showIRQNUMALocality(cmd *cobra.Command, knitOpts *KnitOptions, opts *irqNUMAOptions, args []string) error {
+	ih := irqs.NewWithFS(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot)
+	irqInfos, err := ih.ReadInfo(irqs.EffectiveAffinity)
+	if err != nil {
+		return fmt.Errorf("error parsing irqs from %q: %v", knitOpts.ProcFSRoot, err)
+	}
+
+	var infos []irqs.Info
+	for _, irqInfo := range irqInfos {
+		if irqInfo.CPUs.Intersection(knitOpts.Cpus).Size() == 0 {
+			continue
+		}
+		infos = append(infos, irqInfo)
+	}
+
+	cpusPerNUMA, err := numalign.GetCPUsPerNUMANode(knitOpts.FS, filepath.Join(knitOpts.SysFSRoot, "devices", "system", "node"))
+	if err != nil {
+		return fmt.Errorf("error reading the NUMA nodes from %q: %v", knitOpts.SysFSRoot, err)
+	}
+	cpuNodes := numalign.MakeCPUsToNUMANodeMap(cpusPerNUMA)
+
+	devices := irqs.NewResolver(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot, knitOpts.SysFSRoot).Resolve()
+	deviceNodes := readDeviceNodes(knitOpts, devices)
+
+	report := irqs.CheckLocality(infos, devices, deviceNodes, cpuNodes)
+	if opts.remoteOnly {
+		remotes := []irqs.Locality{}
+		for _, loc := range report.IRQs {
+			if loc.Remote {
+				remotes = append(remotes, loc)
+			}
+		}
+		report.IRQs = remotes
+	}
+
+	if knitOpts.JsonOutput {
+		json.NewEncoder(os.Stdout).Encode(report)
+	} else {
+		for _, loc := range report.IRQs {
+			fmt.Printf("%s\n", loc)
+		}
+		fmt.Printf("\nIRQ NUMA locality by device\n")
+		for _, dl := range report.Devices {
+			fmt.Printf("%s\n", dl)
+		}
+	}
+	return nil
+}
+
+// readDeviceNodes reads the NUMA node of the PCI devices serving the IRQs. Failures are not critical,
+// the IRQs of the devices whose NUMA node is unknown are not reported.
+func readDeviceNodes(knitOpts *KnitOptions, devices map[int]irqs.Device) map[string]int {
+	seen := make(map[string]bool)
+	var addrs []string
+	for _, dev := range devices {
+		if dev.PCIAddress == "" || seen[dev.PCIAddress] {
+			continue
+		}
+		seen[dev.PCIAddress] = true
+		addrs = append(addrs, dev.PCIAddress)
+	}
+	sort.Strings(addrs)
+
+	sysPCIDir := filepath.Join(knitOpts.SysFSRoot, "bus", "pci", "devices")
+	deviceNodes := make(map[string]int)
+	for _, addr := range addrs {
+		nodes, err := numalign.GetPCIDeviceNUMANode(knitOpts.FS, sysPCIDir, []string{addr})
+		if err != nil {
+			knitOpts.Log.Printf("Error reading the NUMA node of %q: %v", addr, err)
+			continue
+		}
+		deviceNodes[addr] = nodes[addr]
+	}
+	return deviceNodes
+}
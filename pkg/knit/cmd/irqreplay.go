@@ -0,0 +1,68 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openshift-kni/debug-tools/pkg/irqs"
+)
+
+type irqReplayOptions struct {
+	verbose int
+}
+
+func NewIRQReplayCommand(knitOpts *KnitOptions) *cobra.Command {
+	opts := &irqReplayOptions{}
+	irqReplay := &cobra.Command{
+		Use:   "irqreplay <recording>",
+		Short: "replay the IRQ/softirq counters recorded by irqwatch",
+		Long: `replay the IRQ/softirq counters recorded by irqwatch --record.
+The recording holds the counters of all the cpus, so it can be replayed with any --cpulist,
+verbosiness and output format.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return replayIRQs(cmd, knitOpts, opts, args)
+		},
+		Args: cobra.ExactArgs(1),
+	}
+	irqReplay.Flags().IntVarP(&opts.verbose, "verbose", "v", 1, "verbosiness amount.")
+	return irqReplay
+}
+
+func replayIRQs(cmd *cobra.Command, knitOpts *KnitOptions, opts *irqReplayOptions, args []string) error {
+	src, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	rec, err := irqs.ReadRecording(src)
+	if err != nil {
+		return fmt.Errorf("error reading the recording %q: %v", args[0], err)
+	}
+
+	var reporter irqs.Reporter
+	if rec.Header.Kind == irqs.KindSoftirq {
+		reporter = irqs.NewSoftirqReporter(os.Stdout, knitOpts.JsonOutput, opts.verbose, knitOpts.Cpus)
+	} else {
+		reporter = irqs.NewDeviceReporter(os.Stdout, knitOpts.JsonOutput, opts.verbose, knitOpts.Cpus, rec.Header.Devices)
+	}
+	return rec.Replay(reporter)
+}
@@ -0,0 +1,162 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/irqs"
+	softirqs "github.com/openshift-kni/debug-tools/pkg/irqs/soft"
+)
+
+type irqTopOptions struct {
+	period   string
+	maxRuns  int
+	rows     int
+	sortBy   string
+	isolated string
+}
+
+func NewIRQTopCommand(knitOpts *KnitOptions) *cobra.Command {
+	opts := &irqTopOptions{}
+	irqTop := &cobra.Command{
+		Use:   "irqtop",
+		Short: "show the busiest IRQs and softirqs per cpu, top-style",
+		Long: `show the busiest IRQs and softirqs per cpu, top-style.
+The table is redrawn every period, with the cpus as columns and the per-second rates of the busiest IRQs
+and softirqs as rows. The isolated cpus are marked with "*", and the activity on them is highlighted on terminals.
+The isolated cpus are inferred from isolcpus or nohz_full, unless given with --isolated.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return showIRQTop(cmd, knitOpts, opts, args)
+		},
+		Args: cobra.NoArgs,
+	}
+	irqTop.Flags().IntVarP(&opts.maxRuns, "watch-times", "T", -1, "number of redraws to perform, each every `watch-period`. Use -1 to run forever.")
+	irqTop.Flags().StringVarP(&opts.period, "watch-period", "W", "1s", "period to poll the counters.")
+	irqTop.Flags().IntVarP(&opts.rows, "rows", "n", 20, "number of rows to show. Use 0 to show all.")
+	irqTop.Flags().StringVar(&opts.sortBy, "sort", irqs.SortByTotal, "sort the rows by total, max (per cpu), name or cpu:N rate.")
+	irqTop.Flags().StringVar(&opts.isolated, "isolated", "", "isolated cpus to highlight.")
+	return irqTop
+}
+
+func showIRQTop(cmd *cobra.Command, knitOpts *KnitOptions, opts *irqTopOptions, args []string) error {
+	if opts.maxRuns == 0 {
+		return nil
+	}
+	period, err := time.ParseDuration(opts.period)
+	if err != nil {
+		return err
+	}
+	if err := irqs.ValidateSortBy(opts.sortBy); err != nil {
+		return err
+	}
+	isolated := kernelIsolatedCPUs(knitOpts)
+	if opts.isolated != "" {
+		isolated, err = cpuset.Parse(opts.isolated)
+		if err != nil {
+			return fmt.Errorf("error parsing %q: %v", opts.isolated, err)
+		}
+	}
+
+	readIRQStats := irqs.NewWithFS(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot).ReadStats
+	readSoftirqStats := softirqs.NewWithFS(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot).ReadStats
+	devices := irqs.NewResolver(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot, knitOpts.SysFSRoot).Resolve()
+
+	prevIRQStats, err := readIRQStats()
+	if err != nil {
+		return err
+	}
+	prevSoftirqStats, err := readSoftirqStats()
+	if err != nil {
+		return err
+	}
+
+	// the cpus are the columns, so we show only the existing ones
+	var cpuids []int
+	for cpuid := range prevIRQStats {
+		cpuids = append(cpuids, cpuid)
+	}
+	cpus := cpuset.New(cpuids...).Intersection(knitOpts.Cpus)
+
+	isTerminal := false
+	if fi, err := os.Stdout.Stat(); err == nil {
+		isTerminal = (fi.Mode() & os.ModeCharDevice) != 0
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+
+	ticker := time.NewTicker(period)
+	prevTs := time.Now()
+	for iterCount := 1; ; iterCount++ {
+		var ts time.Time
+		select {
+		case <-c:
+			return nil
+		case ts = <-ticker.C:
+		}
+
+		lastIRQStats, err := readIRQStats()
+		if err != nil {
+			return err
+		}
+		lastSoftirqStats, err := readSoftirqStats()
+		if err != nil {
+			return err
+		}
+
+		elapsed := ts.Sub(prevTs)
+		rows := irqs.TopRows(irqs.KindIRQ, prevIRQStats.Delta(lastIRQStats), elapsed, cpus, devices)
+		rows = append(rows, irqs.TopRows(irqs.KindSoftirq, prevSoftirqStats.Delta(lastSoftirqStats), elapsed, cpus, nil)...)
+		irqs.SortTopRows(rows, opts.sortBy)
+		if opts.rows > 0 && len(rows) > opts.rows {
+			rows = rows[:opts.rows]
+		}
+		table := irqs.TopTable{
+			Timestamp: ts,
+			Elapsed:   elapsed.Round(time.Millisecond).String(),
+			CPUs:      cpus.List(),
+			Isolated:  isolated.Intersection(cpus).List(),
+			Rows:      rows,
+		}
+
+		if knitOpts.JsonOutput {
+			json.NewEncoder(os.Stdout).Encode(table)
+		} else {
+			if isTerminal {
+				// move to the top left corner and clear the screen
+				fmt.Print("\033[H\033[2J")
+			} else if iterCount > 1 {
+				fmt.Println()
+			}
+			irqs.WriteTopTable(os.Stdout, table, isTerminal)
+		}
+
+		if opts.maxRuns > 0 && iterCount >= opts.maxRuns {
+			return nil
+		}
+		prevIRQStats, prevSoftirqStats, prevTs = lastIRQStats, lastSoftirqStats, ts
+	}
+}
@@ -17,6 +17,9 @@
 package cmd
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"os"
 	"os/signal"
 	"time"
@@ -24,19 +27,27 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/openshift-kni/debug-tools/pkg/irqs"
+	softirqs "github.com/openshift-kni/debug-tools/pkg/irqs/soft"
 )
 
 type irqWatchOptions struct {
-	period  string
-	maxRuns int
-	verbose int
+	period    string
+	maxRuns   int
+	verbose   int
+	softirqs  bool
+	rules     []string
+	rulesFile string
+	record    string
 }
 
 func NewIRQWatchCommand(knitOpts *KnitOptions) *cobra.Command {
 	opts := &irqWatchOptions{}
 	irqWatch := &cobra.Command{
 		Use:   "irqwatch",
-		Short: "watch IRQ counters",
+		Short: "watch IRQ/softirq counters",
+		Long: `watch IRQ/softirq counters.
+If rules are given, they are checked at each period and over the whole run, and knit exits with code 2
+if any rule is violated. Rules bound the rate of IRQs on cpus, "devices" matches all the device IRQs.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return watchIRQs(cmd, knitOpts, opts, args)
 		},
@@ -45,6 +56,10 @@ func NewIRQWatchCommand(knitOpts *KnitOptions) *cobra.Command {
 	irqWatch.Flags().IntVarP(&opts.maxRuns, "watch-times", "T", -1, "number of watch loops to perform, each every `watch-period`. Use -1 to run forever.")
 	irqWatch.Flags().StringVarP(&opts.period, "watch-period", "W", "1s", "period to poll IRQ counters.")
 	irqWatch.Flags().IntVarP(&opts.verbose, "verbose", "v", 1, "verbosiness amount.")
+	irqWatch.Flags().BoolVarP(&opts.softirqs, "softirqs", "s", false, "watch softirqs counters.")
+	irqWatch.Flags().StringArrayVarP(&opts.rules, "rule", "r", nil, "fail if the IRQ rate exceeds the rule, in the form IRQS[@CPUS]=MAXRATE (e.g. devices=0, LOC@2-7=1000). Can be repeated.")
+	irqWatch.Flags().StringVarP(&opts.rulesFile, "rules-file", "R", "", "read the rules from this YAML file.")
+	irqWatch.Flags().StringVar(&opts.record, "record", "", "record all the samples into this file, for later analysis with irqreplay.")
 	return irqWatch
 }
 
@@ -59,38 +74,77 @@ func watchIRQs(cmd *cobra.Command, knitOpts *KnitOptions, opts *irqWatchOptions,
 		return err
 	}
 
+	rules, err := loadIRQRules(opts)
+	if err != nil {
+		return err
+	}
+	violations := 0
+
 	var initStats irqs.Stats
 	var prevStats irqs.Stats
 	var lastStats irqs.Stats
 
-	ih := irqs.New(knitOpts.Log, knitOpts.ProcFSRoot)
+	var devices map[int]irqs.Device
+	var reporter irqs.Reporter
+	header := irqs.RecordingHeader{Kind: irqs.KindIRQ}
+	readStats := irqs.NewWithFS(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot).ReadStats
+	if opts.softirqs {
+		header.Kind = irqs.KindSoftirq
+		readStats = softirqs.NewWithFS(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot).ReadStats
+		reporter = irqs.NewSoftirqReporter(os.Stdout, knitOpts.JsonOutput, opts.verbose, knitOpts.Cpus)
+	} else {
+		devices = irqs.NewResolver(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot, knitOpts.SysFSRoot).Resolve()
+		header.Devices = devices
+		reporter = irqs.NewDeviceReporter(os.Stdout, knitOpts.JsonOutput, opts.verbose, knitOpts.Cpus, devices)
+	}
+
+	var recorder *irqs.Recorder
+	if opts.record != "" {
+		dst, err := os.Create(opts.record)
+		if err != nil {
+			return err
+		}
+		defer dst.Close()
+		recorder, err = irqs.NewRecorder(dst, header)
+		if err != nil {
+			return fmt.Errorf("error recording into %q: %v", opts.record, err)
+		}
+	}
 
 	initTs := time.Now()
-	initStats, err = ih.ReadStats()
+	initStats, err = readStats()
 	if err != nil {
 		return err
 	}
+	if err := recordSample(recorder, initTs, initStats); err != nil {
+		return err
+	}
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
 
 	prevStats = initStats.Clone()
 	ticker := time.NewTicker(period)
-	reporter := irqs.NewReporter(os.Stdout, knitOpts.JsonOutput, opts.verbose, knitOpts.Cpus)
 
 	done := false
 	iterCount := 1
+	prevTs := initTs
 	for {
 		select {
 		case <-c:
 			done = true
 		case t := <-ticker.C:
-			lastStats, err = ih.ReadStats()
+			lastStats, err = readStats()
 			if err != nil {
 				return err
 			}
+			if err := recordSample(recorder, t, lastStats); err != nil {
+				return err
+			}
 			reporter.Delta(t, prevStats, lastStats)
+			violations += reportViolations(knitOpts, &t, header.Kind, devices, irqs.CheckRules(rules, prevStats.Delta(lastStats), t.Sub(prevTs), knitOpts.Cpus))
 			prevStats = lastStats
+			prevTs = t
 		}
 
 		if done {
@@ -103,5 +157,70 @@ func watchIRQs(cmd *cobra.Command, knitOpts *KnitOptions, opts *irqWatchOptions,
 	}
 
 	reporter.Summary(initTs, initStats, lastStats)
+	if lastStats != nil {
+		// prevTs is the timestamp of lastStats: the clock keeps running while waiting for the next tick
+		violations += reportViolations(knitOpts, nil, header.Kind, devices, irqs.CheckRules(rules, initStats.Delta(lastStats), prevTs.Sub(initTs), knitOpts.Cpus))
+	}
+	if violations > 0 {
+		return &ExitError{
+			Code: ExitCodeViolation,
+			Err:  fmt.Errorf("found %d IRQ rule violations", violations),
+		}
+	}
+	return nil
+}
+
+func recordSample(recorder *irqs.Recorder, ts time.Time, stats irqs.Stats) error {
+	if recorder == nil {
+		return nil
+	}
+	if err := recorder.Record(ts, stats); err != nil {
+		return fmt.Errorf("error recording the sample: %v", err)
+	}
 	return nil
 }
+
+func loadIRQRules(opts *irqWatchOptions) ([]irqs.Rule, error) {
+	var rules []irqs.Rule
+	if opts.rulesFile != "" {
+		data, err := ioutil.ReadFile(opts.rulesFile)
+		if err != nil {
+			return nil, err
+		}
+		rules, err = irqs.ParseRules(data)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing the rules from %q: %v", opts.rulesFile, err)
+		}
+	}
+	for _, text := range opts.rules {
+		rule, err := irqs.ParseRule(text)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+type irqViolation struct {
+	// Timestamp is nil for the violations over the whole run
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+	irqs.Violation
+}
+
+func reportViolations(knitOpts *KnitOptions, ts *time.Time, kind string, devices map[int]irqs.Device, violations []irqs.Violation) int {
+	for _, viol := range violations {
+		viol.Kind = kind
+		if dev, ok := irqs.LookupDevice(devices, viol.IRQ); ok {
+			viol.Device = dev.String()
+		}
+		if knitOpts.JsonOutput {
+			json.NewEncoder(os.Stdout).Encode(irqViolation{Timestamp: ts, Violation: viol})
+		} else if ts != nil {
+			fmt.Printf("%v %s\n", *ts, viol.String())
+		} else {
+			fmt.Println(viol.String())
+		}
+	}
+	return len(violations)
+}
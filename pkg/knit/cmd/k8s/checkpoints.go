@@ -0,0 +1,199 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	kubeletpodresourcesv1 "k8s.io/kubelet/pkg/apis/podresources/v1"
+	cpuset "k8s.io/utils/cpuset"
+
+	kube "github.com/openshift-kni/debug-tools/pkg/k8s_imported"
+	"github.com/openshift-kni/debug-tools/pkg/knit/cmd"
+	"github.com/openshift-kni/debug-tools/pkg/kubeletstate"
+)
+
+type checkpointsOptions struct {
+	kubeletDir string
+	crossCheck bool
+	podResFile string
+	socketPath string
+}
+
+func NewCheckpointsCommand(knitOpts *cmd.KnitOptions) *cobra.Command {
+	opts := &checkpointsOptions{}
+	checkpoints := &cobra.Command{
+		Use:   "checkpoints",
+		Short: "show the kubelet cpu manager and memory manager checkpoints",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return showCheckpoints(cmd, knitOpts, opts, args)
+		},
+		Args: cobra.NoArgs,
+	}
+	checkpoints.Flags().StringVarP(&opts.kubeletDir, "kubelet-dir", "K", kubeletstate.DefaultKubeletDir, "kubelet state directory.")
+	checkpoints.Flags().BoolVarP(&opts.crossCheck, "crosscheck", "X", false, "crosscheck the checkpoints against the podresources API List output.")
+	checkpoints.Flags().StringVarP(&opts.podResFile, "podres-file", "F", "", "read the podresources List output from this file (as produced by `knit podres`) instead of querying the kubelet.")
+	checkpoints.Flags().StringVarP(&opts.socketPath, "socket-path", "R", defaultSocketPath, "podresources API socket path.")
+	return checkpoints
+}
+
+type checkpointsReport struct {
+	CPUManager    *kubeletstate.CPUManagerState    `json:"cpuManager,omitempty"`
+	MemoryManager *kubeletstate.MemoryManagerState `json:"memoryManager,omitempty"`
+	Findings      []kubeletstate.Finding           `json:"findings,omitempty"`
+}
+
+func showCheckpoints(cmd *cobra.Command, knitOpts *cmd.KnitOptions, opts *checkpointsOptions, args []string) error {
+	kh := kubeletstate.NewWithFS(knitOpts.Log, knitOpts.FS, opts.kubeletDir)
+
+	report := checkpointsReport{}
+	cpuState, cpuErr := kh.ReadCPUManagerState()
+	if cpuErr != nil {
+		knitOpts.Log.Printf("error reading the cpu manager state from %q: %v", opts.kubeletDir, cpuErr)
+	} else {
+		report.CPUManager = cpuState
+		report.Findings = append(report.Findings, cpuState.Validate()...)
+	}
+	memState, memErr := kh.ReadMemoryManagerState()
+	if memErr != nil {
+		knitOpts.Log.Printf("error reading the memory manager state from %q: %v", opts.kubeletDir, memErr)
+	} else {
+		report.MemoryManager = memState
+	}
+	if cpuErr != nil && memErr != nil {
+		return fmt.Errorf("error reading the kubelet checkpoints from %q: %v", opts.kubeletDir, cpuErr)
+	}
+
+	if opts.crossCheck {
+		podRes, err := readPodResources(opts.podResFile, opts.socketPath)
+		if err != nil {
+			// the checkpoints are still worth showing: often they are the only source of truth left
+			fmt.Fprintf(os.Stderr, "error reading the podresources data, crosscheck skipped: %v\n", err)
+		} else {
+			report.Findings = append(report.Findings, kubeletstate.CrossCheck(cpuState, memState, podResourcesContainers(podRes))...)
+		}
+	}
+
+	if knitOpts.JsonOutput {
+		json.NewEncoder(os.Stdout).Encode(report)
+	} else {
+		printCheckpointsReport(report)
+	}
+	return nil
+}
+
+// readPodResources gets the podresources List data from the given file, if any, or from the kubelet.
+func readPodResources(podResFile, socketPath string) (*ListPodResourcesResponse, error) {
+	if podResFile != "" {
+		data, err := ioutil.ReadFile(podResFile)
+		if err != nil {
+			return nil, err
+		}
+		var podRes ListPodResourcesResponse
+		if err := json.Unmarshal(data, &podRes); err != nil {
+			return nil, err
+		}
+		return &podRes, nil
+	}
+
+	cli, conn, err := kube.GetV1Client(socketPath, defaultPodResourcesTimeout, defaultPodResourcesMaxSize)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	resp, err := cli.List(context.TODO(), &kubeletpodresourcesv1.ListPodResourcesRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return getListPodResourcesResponse(resp), nil
+}
+
+func podResourcesContainers(podRes *ListPodResourcesResponse) []kubeletstate.PodResourcesContainer {
+	var containers []kubeletstate.PodResourcesContainer
+	for _, pr := range podRes.PodResources {
+		for _, cnt := range pr.Containers {
+			prc := kubeletstate.PodResourcesContainer{
+				Namespace: pr.Namespace,
+				PodName:   pr.Name,
+				Name:      cnt.Name,
+			}
+			for _, cpuID := range cnt.CpuIds {
+				prc.CPUs = append(prc.CPUs, int(cpuID))
+			}
+			for _, mem := range cnt.Memory {
+				block := kubeletstate.MemoryBlock{
+					Type: mem.MemoryType,
+					Size: mem.Size_,
+				}
+				if mem.Topology != nil {
+					for _, node := range mem.Topology.Nodes {
+						if node.ID != nil {
+							block.NUMAAffinity = append(block.NUMAAffinity, int(*node.ID))
+						}
+					}
+				}
+				prc.Memory = append(prc.Memory, block)
+			}
+			containers = append(containers, prc)
+		}
+	}
+	return containers
+}
+
+func printCheckpointsReport(report checkpointsReport) {
+	if st := report.CPUManager; st != nil {
+		fmt.Printf("cpu manager policy %q default cpuset %s\n", st.PolicyName, cpuset.New(st.DefaultCPUs...).String())
+		for _, cc := range st.Assignments {
+			fmt.Printf("  %s\n", cc.String())
+		}
+	}
+	if st := report.MemoryManager; st != nil {
+		fmt.Printf("memory manager policy %q\n", st.PolicyName)
+		var nodes []int
+		for node := range st.MachineState {
+			nodes = append(nodes, node)
+		}
+		sort.Ints(nodes)
+		for _, node := range nodes {
+			nodeState := st.MachineState[node]
+			var memTypes []string
+			for memType := range nodeState.MemoryMap {
+				memTypes = append(memTypes, memType)
+			}
+			sort.Strings(memTypes)
+			for _, memType := range memTypes {
+				table := nodeState.MemoryMap[memType]
+				fmt.Printf("  NUMA node %d %-16s total %12d reserved %12d free %12d\n", node, memType, table.TotalMemSize, table.Reserved, table.Free)
+			}
+		}
+		for _, cm := range st.Assignments {
+			for _, block := range cm.Blocks {
+				fmt.Printf("  pod %s container %s: %s %d bytes on NUMA nodes %v\n", cm.PodUID, cm.ContainerName, block.Type, block.Size, block.NUMAAffinity)
+			}
+		}
+	}
+	for _, finding := range report.Findings {
+		fmt.Println(finding.String())
+	}
+}
@@ -0,0 +1,100 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openshift-kni/debug-tools/pkg/hugepages"
+	"github.com/openshift-kni/debug-tools/pkg/knit/cmd"
+	"github.com/openshift-kni/debug-tools/pkg/numalign"
+)
+
+type hugepagesOptions struct {
+	crossCheck bool
+	podResFile string
+	socketPath string
+}
+
+func NewHugepagesCommand(knitOpts *cmd.KnitOptions) *cobra.Command {
+	opts := &hugepagesOptions{}
+	hp := &cobra.Command{
+		Use:   "hugepages",
+		Short: "show the hugepages per NUMA node, and check the containers hugepages are aligned with their cpus",
+		Long: `show the total, free and surplus hugepages per size and per NUMA node, and the system-wide reserved hugepages.
+With --crosscheck, the hugepages allocated to the containers are read from the podresources API List output,
+and the ones coming from NUMA nodes different from the ones of the container exclusive cpus are flagged.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return showHugepages(cmd, knitOpts, opts, args)
+		},
+		Args: cobra.NoArgs,
+	}
+	hp.Flags().BoolVarP(&opts.crossCheck, "crosscheck", "X", false, "crosscheck the hugepages against the podresources API List output.")
+	hp.Flags().StringVarP(&opts.podResFile, "podres-file", "F", "", "read the podresources List output from this file (as produced by `knit podres`) instead of querying the kubelet.")
+	hp.Flags().StringVarP(&opts.socketPath, "socket-path", "R", defaultSocketPath, "podresources API socket path.")
+	return hp
+}
+
+type hugepagesReport struct {
+	hugepages.Inventory
+	Allocations []hugepages.Allocation `json:"allocations,omitempty"`
+}
+
+func showHugepages(cmd *cobra.Command, knitOpts *cmd.KnitOptions, opts *hugepagesOptions, args []string) error {
+	inv, err := hugepages.NewWithFS(knitOpts.Log, knitOpts.FS, knitOpts.SysFSRoot).Read()
+	if err != nil {
+		return fmt.Errorf("error reading the hugepages from %q: %v", knitOpts.SysFSRoot, err)
+	}
+	report := hugepagesReport{Inventory: inv}
+
+	if opts.crossCheck {
+		podRes, err := readPodResources(opts.podResFile, opts.socketPath)
+		if err != nil {
+			// the inventory is still worth showing
+			fmt.Fprintf(os.Stderr, "error reading the podresources data, crosscheck skipped: %v\n", err)
+		} else {
+			cpusPerNUMA, err := numalign.GetCPUsPerNUMANode(knitOpts.FS, filepath.Join(knitOpts.SysFSRoot, "devices", "system", "node"))
+			if err != nil {
+				return fmt.Errorf("error reading the NUMA nodes from %q: %v", knitOpts.SysFSRoot, err)
+			}
+			report.Allocations = hugepages.Check(podResourcesContainers(podRes), numalign.MakeCPUsToNUMANodeMap(cpusPerNUMA))
+		}
+	}
+
+	if knitOpts.JsonOutput {
+		json.NewEncoder(os.Stdout).Encode(report)
+		return nil
+	}
+
+	for _, np := range report.Nodes {
+		for _, pool := range np.Pools {
+			fmt.Printf("NUMA node %d: %s\n", np.Node, pool.String())
+		}
+	}
+	for _, pool := range report.System {
+		fmt.Printf("system     : %s\n", pool.String())
+	}
+	for _, alloc := range report.Allocations {
+		fmt.Println(alloc.String())
+	}
+	return nil
+}
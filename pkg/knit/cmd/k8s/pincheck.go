@@ -0,0 +1,146 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/knit/cmd"
+	"github.com/openshift-kni/debug-tools/pkg/pinning"
+	"github.com/openshift-kni/debug-tools/pkg/procs"
+)
+
+type pinCheckOptions struct {
+	podResFile            string
+	socketPath            string
+	podsFile              string
+	nodeName              string
+	includePerCPUKthreads bool
+}
+
+func NewPinCheckCommand(knitOpts *cmd.KnitOptions) *cobra.Command {
+	opts := &pinCheckOptions{}
+	pinCheck := &cobra.Command{
+		Use:   "pincheck",
+		Short: "verify the container threads run only on their exclusive cpus, and nothing else does",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return checkPinning(cmd, knitOpts, opts, args)
+		},
+		Args: cobra.NoArgs,
+	}
+	pinCheck.Flags().StringVarP(&opts.podResFile, "podres-file", "F", "", "read the podresources List output from this file (as produced by `knit podres`) instead of querying the kubelet.")
+	pinCheck.Flags().StringVarP(&opts.socketPath, "socket-path", "R", defaultSocketPath, "podresources API socket path.")
+	pinCheck.Flags().StringVar(&opts.podsFile, "pods-file", "", "read the pods from this file (as produced by `kubectl get pods -o json`) instead of querying the apiserver.")
+	pinCheck.Flags().StringVar(&opts.nodeName, "node-name", "", "node name to get the pods from.")
+	pinCheck.Flags().BoolVar(&opts.includePerCPUKthreads, "include-percpu-kthreads", false, "report also the per-cpu kernel threads (ksoftirqd/N, kworker/N...).")
+	return pinCheck
+}
+
+func checkPinning(cmd *cobra.Command, knitOpts *cmd.KnitOptions, opts *pinCheckOptions, args []string) error {
+	podRes, err := readPodResources(opts.podResFile, opts.socketPath)
+	if err != nil {
+		return fmt.Errorf("error reading the podresources data: %v", err)
+	}
+	pods, err := readPods(opts.podsFile, opts.nodeName)
+	if err != nil {
+		return fmt.Errorf("error reading the pods: %v", err)
+	}
+
+	ph := procs.NewWithFS(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot)
+	procInfos, err := ph.ListAll()
+	if err != nil {
+		return fmt.Errorf("error getting process infos from %q: %v", knitOpts.ProcFSRoot, err)
+	}
+
+	resolver := pinning.NewResolverFromPods(pods)
+	var processes []pinning.Process
+	for _, procInfo := range procInfos {
+		processes = append(processes, pinning.Process{
+			Info:  procInfo,
+			Owner: resolver.Owner(procInfo.Name, procs.CgroupInfoFromPath(procInfo.CgroupPath)),
+		})
+	}
+
+	violations := pinning.Check(allocationsFromPodResources(podRes), processes, pinning.Options{
+		IncludePerCPUKthreads: opts.includePerCPUKthreads,
+	})
+
+	if knitOpts.JsonOutput {
+		json.NewEncoder(os.Stdout).Encode(violations)
+	} else {
+		for _, violation := range violations {
+			fmt.Println(violation.String())
+		}
+	}
+	return nil
+}
+
+func allocationsFromPodResources(podRes *ListPodResourcesResponse) []pinning.Allocation {
+	var allocs []pinning.Allocation
+	for _, pr := range podRes.PodResources {
+		for _, cnt := range pr.Containers {
+			if len(cnt.CpuIds) == 0 {
+				continue
+			}
+			var cpus []int
+			for _, cpuID := range cnt.CpuIds {
+				cpus = append(cpus, int(cpuID))
+			}
+			allocs = append(allocs, pinning.Allocation{
+				ContainerRef: pinning.ContainerRef{
+					Namespace: pr.Namespace,
+					Pod:       pr.Name,
+					Container: cnt.Name,
+				},
+				CPUs: cpuset.New(cpus...),
+			})
+		}
+	}
+	return allocs
+}
+
+// readPods gets the pods from the given file, if any, or from the apiserver.
+func readPods(podsFile, nodeName string) ([]corev1.Pod, error) {
+	if podsFile != "" {
+		data, err := ioutil.ReadFile(podsFile)
+		if err != nil {
+			return nil, err
+		}
+		return pinning.ParsePodList(data)
+	}
+
+	clientset, err := getClientSetFromClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get clientset: %w", err)
+	}
+	podList, err := clientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{
+		FieldSelector: buildNodeFieldSelector(nodeName),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return podList.Items, nil
+}
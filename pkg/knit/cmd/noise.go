@@ -0,0 +1,158 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/irqs"
+	softirqs "github.com/openshift-kni/debug-tools/pkg/irqs/soft"
+	"github.com/openshift-kni/debug-tools/pkg/noise"
+	"github.com/openshift-kni/debug-tools/pkg/procs"
+)
+
+type noiseOptions struct {
+	window       string
+	top          int
+	workloadPIDs []int
+}
+
+func NewNoiseCommand(knitOpts *KnitOptions) *cobra.Command {
+	opts := &noiseOptions{}
+	noiseCmd := &cobra.Command{
+		Use:   "noise",
+		Short: "report what disturbed the cpus over a time window",
+		Long: `report what disturbed the cpus given with --cpulist over a time window.
+For each cpu, the IRQs, the softirqs and the context switches of the non-workload threads are ranked,
+together with the time spent serving IRQs and softirqs, and the time stolen by the hypervisor.
+The workload threads are the ones running in pod containers, or the ones of the processes given
+with --workload-pid. The kernel threads are always reported.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return showNoise(cmd, knitOpts, opts, args)
+		},
+		Args: cobra.NoArgs,
+	}
+	noiseCmd.Flags().StringVarP(&opts.window, "window", "W", "10s", "time window to sample the counters.")
+	noiseCmd.Flags().IntVar(&opts.top, "top", 10, "number of sources to report per cpu. Use 0 to report all.")
+	noiseCmd.Flags().IntSliceVar(&opts.workloadPIDs, "workload-pid", nil, "pids of the workload processes, in addition to the pod containers.")
+	return noiseCmd
+}
+
+func showNoise(cmd *cobra.Command, knitOpts *KnitOptions, opts *noiseOptions, args []string) error {
+	window, err := time.ParseDuration(opts.window)
+	if err != nil {
+		return err
+	}
+
+	ih := irqs.NewWithFS(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot)
+	sh := softirqs.NewWithFS(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot)
+	ph := procs.NewWithFS(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot)
+
+	readSample := func() (noise.Sample, error) {
+		var err error
+		sample := noise.Sample{Timestamp: time.Now()}
+		if sample.IRQs, err = ih.ReadStats(); err != nil {
+			return sample, err
+		}
+		if sample.Softirqs, err = sh.ReadStats(); err != nil {
+			return sample, err
+		}
+		if sample.CPUTimes, err = ph.ReadCPUTimes(); err != nil {
+			return sample, err
+		}
+		if sample.Threads, err = ph.ReadSchedStats(knitOpts.Cpus); err != nil {
+			return sample, err
+		}
+		return sample, nil
+	}
+
+	prev, err := readSample()
+	if err != nil {
+		return err
+	}
+
+	// report only the existing cpus
+	var cpuids []int
+	for cpuid := range prev.CPUTimes {
+		cpuids = append(cpuids, cpuid)
+	}
+	cpus := cpuset.New(cpuids...).Intersection(knitOpts.Cpus)
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	// interrupting cuts the window short, we still report what we have got so far
+	select {
+	case <-c:
+	case <-time.After(window):
+	}
+
+	last, err := readSample()
+	if err != nil {
+		return err
+	}
+
+	report := noise.Analyze(prev, last, noise.Options{
+		CPUs:       cpus,
+		Isolated:   kernelIsolatedCPUs(knitOpts),
+		Devices:    irqs.NewResolver(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot, knitOpts.SysFSRoot).Resolve(),
+		IsWorkload: workloadDetector(knitOpts, ph, opts.workloadPIDs),
+		MaxSources: opts.top,
+	})
+
+	if knitOpts.JsonOutput {
+		json.NewEncoder(os.Stdout).Encode(report)
+		return nil
+	}
+	fmt.Printf("noise over %s on cpus %v\n", report.Window, cpus)
+	for _, cn := range report.CPUs {
+		fmt.Println()
+		fmt.Print(cn.String())
+	}
+	return nil
+}
+
+// workloadDetector tells if a thread belongs to a pod container or to one of the given pids.
+// The cgroup is read once per process.
+func workloadDetector(knitOpts *KnitOptions, ph *procs.Handler, workloadPIDs []int) func(tc procs.ThreadCounters) bool {
+	workload := make(map[int]bool)
+	for _, pid := range workloadPIDs {
+		workload[pid] = true
+	}
+	return func(tc procs.ThreadCounters) bool {
+		if tc.Kernel {
+			return false
+		}
+		if isWorkload, ok := workload[tc.PID]; ok {
+			return isWorkload
+		}
+		cgInfo, err := ph.ReadCgroup(tc.PID)
+		if err != nil {
+			knitOpts.Log.Printf("error reading the cgroup of pid %d: %v", tc.PID, err)
+		}
+		isWorkload := err == nil && cgInfo.ContainerID != "" && !cgInfo.Infra
+		workload[tc.PID] = isWorkload
+		return isWorkload
+	}
+}
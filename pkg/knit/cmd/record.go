@@ -0,0 +1,60 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+)
+
+// setupRecording wraps the filesystem access so all the files read by the command
+// are recorded, and saved as fixture once the command completes. The fixture is
+// rooted like a snapshot, so it can be replayed using --snapshot.
+// Must be called once the fs roots are final.
+func setupRecording(knitOpts *KnitOptions) {
+	rec := fswrap.NewRecorder(knitOpts.FS, map[string]string{
+		knitOpts.ProcFSRoot: "proc",
+		knitOpts.SysFSRoot:  "sys",
+		knitOpts.EtcRoot:    "etc",
+	})
+	knitOpts.FS = rec
+
+	// finalizers run even if the command fails, so we can record fixtures for failures too
+	cobra.OnFinalize(func() {
+		if err := writeFixture(knitOpts.RecordFixture, rec.Fixture()); err != nil {
+			fmt.Fprintf(os.Stderr, "error recording fixture into %q: %v\n", knitOpts.RecordFixture, err)
+			return
+		}
+		knitOpts.Log.Printf("recorded fixture into %q", knitOpts.RecordFixture)
+	})
+}
+
+func writeFixture(fixturePath string, fixture *fswrap.MemFS) error {
+	dst, err := os.Create(fixturePath)
+	if err != nil {
+		return err
+	}
+	if err := fixture.WriteTar(dst); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
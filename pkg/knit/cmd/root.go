@@ -25,16 +25,22 @@ import (
 	"github.com/spf13/cobra"
 
 	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
 )
 
 type KnitOptions struct {
-	Cpus       cpuset.CPUSet
-	ProcFSRoot string
-	SysFSRoot  string
-	JsonOutput bool
-	Debug      bool
-	Log        *log.Logger
-	cpuList    string
+	Cpus          cpuset.CPUSet
+	ProcFSRoot    string
+	SysFSRoot     string
+	EtcRoot       string
+	Snapshot      string
+	RecordFixture string
+	JsonOutput    bool
+	Debug         bool
+	Log           *log.Logger
+	FS            fswrap.FSWrapper
+	cpuList       string
 }
 
 func ShowHelp(cmd *cobra.Command, args []string) error {
@@ -64,6 +70,17 @@ func NewRootCommand(extraCmds ...NewCommandFunc) *cobra.Command {
 			} else {
 				knitOpts.Log = log.New(ioutil.Discard, "", 0)
 			}
+
+			if knitOpts.Snapshot != "" {
+				if err := setupSnapshot(cmd, knitOpts); err != nil {
+					return err
+				}
+			}
+
+			knitOpts.FS = fswrap.LinuxFS{Log: knitOpts.Log}
+			if knitOpts.RecordFixture != "" {
+				setupRecording(knitOpts)
+			}
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -77,13 +94,25 @@ func NewRootCommand(extraCmds ...NewCommandFunc) *cobra.Command {
 	root.PersistentFlags().StringVarP(&knitOpts.cpuList, "cpulist", "C", "0-16383", "isolated cpu set to check (see man (7) cpuset - List format")
 	root.PersistentFlags().StringVarP(&knitOpts.ProcFSRoot, "procfs", "P", "/proc", "procfs root")
 	root.PersistentFlags().StringVarP(&knitOpts.SysFSRoot, "sysfs", "S", "/sys", "sysfs root")
+	root.PersistentFlags().StringVar(&knitOpts.EtcRoot, "etc", "/etc", "configuration (etc) root")
+	root.PersistentFlags().StringVar(&knitOpts.Snapshot, "snapshot", "", "run against the given snapshot (tarball or unpacked tree). Overrides procfs, sysfs and etc roots.")
+	root.PersistentFlags().StringVar(&knitOpts.RecordFixture, "record-fixture", "", "record the files read by the command into the given fixture tarball, replayable using --snapshot.")
 	root.PersistentFlags().BoolVarP(&knitOpts.Debug, "debug", "D", false, "enable debug log")
 	root.PersistentFlags().BoolVarP(&knitOpts.JsonOutput, "json", "J", false, "output as JSON")
 
 	root.AddCommand(
+		NewCmdlineCommand(knitOpts),
 		NewCPUAffinityCommand(knitOpts),
+		NewCPUPowerCommand(knitOpts),
+		NewExporterCommand(knitOpts),
 		NewIRQAffinityCommand(knitOpts),
+		NewIRQBalanceCommand(knitOpts),
+		NewIRQNUMACommand(knitOpts),
+		NewIRQReplayCommand(knitOpts),
+		NewIRQTopCommand(knitOpts),
 		NewIRQWatchCommand(knitOpts),
+		NewNoiseCommand(knitOpts),
+		NewSchedWatchCommand(knitOpts),
 		NewWaitCommand(knitOpts),
 	)
 	for _, extraCmd := range extraCmds {
@@ -0,0 +1,111 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openshift-kni/debug-tools/pkg/procs"
+)
+
+type schedWatchOptions struct {
+	period  string
+	maxRuns int
+	verbose int
+}
+
+func NewSchedWatchCommand(knitOpts *KnitOptions) *cobra.Command {
+	opts := &schedWatchOptions{}
+	schedWatch := &cobra.Command{
+		Use:   "schedwatch",
+		Short: "watch the context switches and the migrations of the threads",
+		Long: `watch the voluntary and involuntary context switches and the migrations of the threads
+which can run on the cpus given with --cpulist. The migrations are reported only on kernels
+built with CONFIG_SCHED_DEBUG.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return watchSched(cmd, knitOpts, opts, args)
+		},
+		Args: cobra.NoArgs,
+	}
+	schedWatch.Flags().IntVarP(&opts.maxRuns, "watch-times", "T", -1, "number of watch loops to perform, each every `watch-period`. Use -1 to run forever.")
+	schedWatch.Flags().StringVarP(&opts.period, "watch-period", "W", "1s", "period to poll the scheduler counters.")
+	schedWatch.Flags().IntVarP(&opts.verbose, "verbose", "v", 1, "verbosiness amount.")
+	return schedWatch
+}
+
+func watchSched(cmd *cobra.Command, knitOpts *KnitOptions, opts *schedWatchOptions, args []string) error {
+	if opts.maxRuns == 0 {
+		return nil
+	}
+
+	var err error
+	period, err := time.ParseDuration(opts.period)
+	if err != nil {
+		return err
+	}
+
+	var initStats procs.SchedStats
+	var prevStats procs.SchedStats
+	var lastStats procs.SchedStats
+
+	ph := procs.NewWithFS(knitOpts.Log, knitOpts.FS, knitOpts.ProcFSRoot)
+
+	initTs := time.Now()
+	initStats, err = ph.ReadSchedStats(knitOpts.Cpus)
+	if err != nil {
+		return err
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+
+	prevStats = initStats.Clone()
+	lastStats = initStats.Clone()
+	ticker := time.NewTicker(period)
+	reporter := procs.NewReporter(os.Stdout, knitOpts.JsonOutput, opts.verbose, knitOpts.Cpus)
+
+	done := false
+	iterCount := 1
+	for {
+		select {
+		case <-c:
+			done = true
+		case t := <-ticker.C:
+			lastStats, err = ph.ReadSchedStats(knitOpts.Cpus)
+			if err != nil {
+				return err
+			}
+			reporter.Delta(t, prevStats, lastStats)
+			prevStats = lastStats
+		}
+
+		if done {
+			break
+		}
+		if opts.maxRuns > 0 && iterCount >= opts.maxRuns {
+			break
+		}
+		iterCount++
+	}
+
+	reporter.Summary(initTs, initStats, lastStats)
+	return nil
+}
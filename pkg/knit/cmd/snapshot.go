@@ -0,0 +1,74 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jaypipes/ghw/pkg/snapshot"
+	"github.com/spf13/cobra"
+)
+
+// setupSnapshot rewires the fs roots to point inside the snapshot.
+// The snapshot can be either a packed tarball, which is unpacked in a temporary
+// directory removed once the command completes, or a directory holding an
+// already unpacked snapshot, which is used in place.
+func setupSnapshot(cmd *cobra.Command, knitOpts *KnitOptions) error {
+	for _, name := range []string{"procfs", "sysfs", "etc"} {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--snapshot and --%s are mutually exclusive", name)
+		}
+	}
+
+	fi, err := os.Stat(knitOpts.Snapshot)
+	if err != nil {
+		return fmt.Errorf("error accessing snapshot %q: %v", knitOpts.Snapshot, err)
+	}
+
+	snapshotRoot := knitOpts.Snapshot
+	if !fi.IsDir() {
+		snapshotRoot, err = snapshot.Unpack(knitOpts.Snapshot)
+		if err != nil {
+			cleanupSnapshot(knitOpts, snapshotRoot)
+			return fmt.Errorf("error unpacking snapshot %q: %v", knitOpts.Snapshot, err)
+		}
+		knitOpts.Log.Printf("unpacked snapshot %q at %q", knitOpts.Snapshot, snapshotRoot)
+
+		// finalizers run even if the command fails, unlike the PostRun hooks
+		cobra.OnFinalize(func() {
+			cleanupSnapshot(knitOpts, snapshotRoot)
+		})
+	}
+
+	knitOpts.ProcFSRoot = filepath.Join(snapshotRoot, "proc")
+	knitOpts.SysFSRoot = filepath.Join(snapshotRoot, "sys")
+	knitOpts.EtcRoot = filepath.Join(snapshotRoot, "etc")
+	return nil
+}
+
+func cleanupSnapshot(knitOpts *KnitOptions, snapshotRoot string) {
+	if snapshotRoot == "" {
+		return
+	}
+	if err := snapshot.Cleanup(snapshotRoot); err != nil {
+		knitOpts.Log.Printf("error removing unpacked snapshot at %q: %v", snapshotRoot, err)
+		return
+	}
+	knitOpts.Log.Printf("removed unpacked snapshot at %q", snapshotRoot)
+}
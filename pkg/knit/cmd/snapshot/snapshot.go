@@ -0,0 +1,59 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package snapshot
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openshift-kni/debug-tools/pkg/knit/cmd"
+	"github.com/openshift-kni/debug-tools/pkg/snapshot"
+)
+
+type snapshotOptions struct {
+	scrub bool
+}
+
+func NewSnapshotCommand(knitOpts *cmd.KnitOptions) *cobra.Command {
+	opts := &snapshotOptions{}
+	snap := &cobra.Command{
+		Use:   "snapshot <output.tgz>",
+		Short: "capture a snapshot of the node data consumed by knit",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return makeSnapshot(cmd, knitOpts, opts, args)
+		},
+		Args: cobra.ExactArgs(1),
+	}
+	snap.Flags().BoolVar(&opts.scrub, "scrub", false, "remove machine-identifiable data from the snapshot")
+	return snap
+}
+
+func makeSnapshot(cmd *cobra.Command, knitOpts *cmd.KnitOptions, opts *snapshotOptions, args []string) error {
+	snapOpts := snapshot.Options{
+		ProcFSRoot: knitOpts.ProcFSRoot,
+		SysFSRoot:  knitOpts.SysFSRoot,
+		EtcRoot:    knitOpts.EtcRoot,
+		Scrub:      opts.scrub,
+	}
+	knitOpts.Log.Printf("snapshot: %s", snapOpts)
+
+	if err := snapshot.PackFrom(knitOpts.Log, args[0], snapOpts); err != nil {
+		return fmt.Errorf("error creating snapshot %q: %v", args[0], err)
+	}
+	return nil
+}
@@ -35,7 +35,8 @@ func waitForever(dwOpts *waitOpts) error {
 		ioutil.WriteFile(dwOpts.healthFile, message, 0644) // intentionally ignore error
 	}
 
-	exitSignal := make(chan os.Signal)
+	// signal.Notify does not block sending, so a signal delivered before we wait on an unbuffered channel is lost
+	exitSignal := make(chan os.Signal, 1)
 	signal.Notify(exitSignal, syscall.SIGINT, syscall.SIGTERM)
 	<-exitSignal
 	return nil
@@ -0,0 +1,201 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package kubeletstate
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	cpuset "k8s.io/utils/cpuset"
+)
+
+const (
+	ManagerCPU    = "cpu"
+	ManagerMemory = "memory"
+)
+
+// Finding is an inconsistency found in the kubelet state, or between the kubelet state
+// and the podresources API.
+type Finding struct {
+	Manager   string `json:"manager"`
+	Container string `json:"container"`
+	Message   string `json:"message"`
+}
+
+func (fi Finding) String() string {
+	return fmt.Sprintf("[%-6s] %s: %s", fi.Manager, fi.Container, fi.Message)
+}
+
+// PodResourcesContainer is the subset of the podresources API List data about a container
+// we need to crosscheck the kubelet state.
+type PodResourcesContainer struct {
+	Namespace string        `json:"namespace"`
+	PodName   string        `json:"podName"`
+	Name      string        `json:"name"`
+	CPUs      []int         `json:"cpus,omitempty"`
+	Memory    []MemoryBlock `json:"memory,omitempty"`
+}
+
+func (prc PodResourcesContainer) ref() string {
+	return fmt.Sprintf("pod %s/%s container %s", prc.Namespace, prc.PodName, prc.Name)
+}
+
+// Validate checks the internal consistency of the cpu manager state: the exclusive cpus must not
+// be shared among containers, nor be part of the default cpuset.
+func (st *CPUManagerState) Validate() []Finding {
+	var findings []Finding
+	defCpus := cpuset.New(st.DefaultCPUs...)
+	owners := make(map[int]string)
+	for _, cc := range st.Assignments {
+		ref := containerRef(cc.PodUID, cc.ContainerName, cc.ContainerID)
+		cpus := cpuset.New(cc.CPUs...)
+		if overlap := cpus.Intersection(defCpus); !overlap.IsEmpty() {
+			findings = append(findings, Finding{
+				Manager:   ManagerCPU,
+				Container: ref,
+				Message:   fmt.Sprintf("exclusive cpus %s are also in the default cpuset %s", overlap.String(), defCpus.String()),
+			})
+		}
+		for _, cpu := range cc.CPUs {
+			owner, ok := owners[cpu]
+			if !ok {
+				owners[cpu] = ref
+				continue
+			}
+			findings = append(findings, Finding{
+				Manager:   ManagerCPU,
+				Container: ref,
+				Message:   fmt.Sprintf("exclusive cpu %d is also assigned to %s", cpu, owner),
+			})
+		}
+	}
+	return findings
+}
+
+// CrossCheck compares the kubelet state against the podresources API data. The checkpoints
+// identify the pods by UID, while podresources identifies them by namespace and name, so
+// containers are matched by name and assigned resources. Both the states can be nil, to skip
+// the check of the corresponding manager.
+func CrossCheck(cpuState *CPUManagerState, memState *MemoryManagerState, podRes []PodResourcesContainer) []Finding {
+	var findings []Finding
+	if cpuState != nil {
+		findings = append(findings, crossCheckCPUs(cpuState, podRes)...)
+	}
+	if memState != nil {
+		findings = append(findings, crossCheckMemory(memState, podRes)...)
+	}
+	return findings
+}
+
+func crossCheckCPUs(st *CPUManagerState, podRes []PodResourcesContainer) []Finding {
+	var findings []Finding
+	matched := make([]bool, len(podRes))
+	for _, cc := range st.Assignments {
+		idx := findContainer(podRes, matched, cc.ContainerName, func(prc PodResourcesContainer) bool {
+			return cpuset.New(prc.CPUs...).Equals(cpuset.New(cc.CPUs...))
+		})
+		if idx != -1 {
+			matched[idx] = true
+			continue
+		}
+		findings = append(findings, Finding{
+			Manager:   ManagerCPU,
+			Container: containerRef(cc.PodUID, cc.ContainerName, cc.ContainerID),
+			Message:   fmt.Sprintf("exclusive cpus %v not reported by podresources", cc.CPUs),
+		})
+	}
+	for idx, prc := range podRes {
+		if matched[idx] || len(prc.CPUs) == 0 {
+			continue
+		}
+		findings = append(findings, Finding{
+			Manager:   ManagerCPU,
+			Container: prc.ref(),
+			Message:   fmt.Sprintf("exclusive cpus %v reported by podresources are missing from the checkpoint", prc.CPUs),
+		})
+	}
+	return findings
+}
+
+func crossCheckMemory(st *MemoryManagerState, podRes []PodResourcesContainer) []Finding {
+	var findings []Finding
+	matched := make([]bool, len(podRes))
+	for _, cm := range st.Assignments {
+		blocks := normalizeBlocks(cm.Blocks)
+		idx := findContainer(podRes, matched, cm.ContainerName, func(prc PodResourcesContainer) bool {
+			return reflect.DeepEqual(normalizeBlocks(prc.Memory), blocks)
+		})
+		if idx != -1 {
+			matched[idx] = true
+			continue
+		}
+		findings = append(findings, Finding{
+			Manager:   ManagerMemory,
+			Container: containerRef(cm.PodUID, cm.ContainerName, ""),
+			Message:   fmt.Sprintf("memory blocks %v not reported by podresources", cm.Blocks),
+		})
+	}
+	for idx, prc := range podRes {
+		if matched[idx] || len(prc.Memory) == 0 {
+			continue
+		}
+		findings = append(findings, Finding{
+			Manager:   ManagerMemory,
+			Container: prc.ref(),
+			Message:   fmt.Sprintf("memory blocks %v reported by podresources are missing from the checkpoint", prc.Memory),
+		})
+	}
+	return findings
+}
+
+// findContainer returns the index of the first unmatched container with the given name
+// satisfying `equal`, or -1. Old checkpoints lack the container names, so an empty name matches any.
+func findContainer(podRes []PodResourcesContainer, matched []bool, name string, equal func(prc PodResourcesContainer) bool) int {
+	for idx, prc := range podRes {
+		if matched[idx] {
+			continue
+		}
+		if name != "" && prc.Name != name {
+			continue
+		}
+		if equal(prc) {
+			return idx
+		}
+	}
+	return -1
+}
+
+func normalizeBlocks(blocks []MemoryBlock) []MemoryBlock {
+	ret := make([]MemoryBlock, 0, len(blocks))
+	for _, block := range blocks {
+		numaAffinity := append([]int{}, block.NUMAAffinity...)
+		sort.Ints(numaAffinity)
+		ret = append(ret, MemoryBlock{
+			NUMAAffinity: numaAffinity,
+			Type:         block.Type,
+			Size:         block.Size,
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Type != ret[j].Type {
+			return ret[i].Type < ret[j].Type
+		}
+		return ret[i].Size < ret[j].Size
+	})
+	return ret
+}
@@ -0,0 +1,263 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package kubeletstate
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+)
+
+const (
+	DefaultKubeletDir = "/var/lib/kubelet"
+
+	CPUManagerStateFile    = "cpu_manager_state"
+	MemoryManagerStateFile = "memory_manager_state"
+)
+
+// ContainerCPUs is an exclusive cpu assignment recorded by the kubelet cpu manager.
+type ContainerCPUs struct {
+	PodUID        string `json:"podUID,omitempty"`
+	ContainerName string `json:"containerName,omitempty"`
+	// ContainerID is set only by old (v1) checkpoints, which lack PodUID and ContainerName.
+	ContainerID string `json:"containerID,omitempty"`
+	CPUs        []int  `json:"cpus"`
+}
+
+func (cc ContainerCPUs) String() string {
+	return fmt.Sprintf("%-40s can run on %v", containerRef(cc.PodUID, cc.ContainerName, cc.ContainerID), cc.CPUs)
+}
+
+// CPUManagerState is the content of the kubelet cpu manager checkpoint.
+type CPUManagerState struct {
+	PolicyName  string          `json:"policyName"`
+	DefaultCPUs []int           `json:"defaultCpus"`
+	Assignments []ContainerCPUs `json:"assignments,omitempty"`
+}
+
+// MemoryBlock is a memory (or hugepages) allocation recorded by the kubelet memory manager.
+type MemoryBlock struct {
+	NUMAAffinity []int  `json:"numaAffinity"`
+	Type         string `json:"type"`
+	Size         uint64 `json:"size"`
+}
+
+// MemoryTable is the accounting of a memory type on a NUMA node.
+type MemoryTable struct {
+	TotalMemSize   uint64 `json:"total"`
+	SystemReserved uint64 `json:"systemReserved"`
+	Allocatable    uint64 `json:"allocatable"`
+	Reserved       uint64 `json:"reserved"`
+	Free           uint64 `json:"free"`
+}
+
+// NUMANodeMemory is the memory manager state of a NUMA node.
+type NUMANodeMemory struct {
+	NumberOfAssignments int                     `json:"numberOfAssignments"`
+	MemoryMap           map[string]*MemoryTable `json:"memoryMap"`
+	Cells               []int                   `json:"cells"`
+}
+
+// ContainerMemory is the set of memory blocks assigned to a container.
+type ContainerMemory struct {
+	PodUID        string        `json:"podUID"`
+	ContainerName string        `json:"containerName"`
+	Blocks        []MemoryBlock `json:"blocks"`
+}
+
+// MemoryManagerState is the content of the kubelet memory manager checkpoint.
+type MemoryManagerState struct {
+	PolicyName   string                  `json:"policyName"`
+	MachineState map[int]*NUMANodeMemory `json:"machineState,omitempty"`
+	Assignments  []ContainerMemory       `json:"assignments,omitempty"`
+}
+
+// the on-disk formats, see k/k pkg/kubelet/cm/{cpu,memory}manager/state/checkpoint.go
+// we don't verify the checksums, because we want to inspect the checkpoints even if they are corrupted.
+
+type cpuManagerCheckpointV2 struct {
+	PolicyName    string                       `json:"policyName"`
+	DefaultCPUSet string                       `json:"defaultCpuSet"`
+	Entries       map[string]map[string]string `json:"entries,omitempty"`
+	Checksum      uint64                       `json:"checksum"`
+}
+
+type cpuManagerCheckpointV1 struct {
+	PolicyName    string            `json:"policyName"`
+	DefaultCPUSet string            `json:"defaultCpuSet"`
+	Entries       map[string]string `json:"entries,omitempty"`
+	Checksum      uint64            `json:"checksum"`
+}
+
+type memoryManagerCheckpoint struct {
+	PolicyName   string                              `json:"policyName"`
+	MachineState map[int]*NUMANodeMemory             `json:"machineState"`
+	Entries      map[string]map[string][]MemoryBlock `json:"entries,omitempty"`
+	Checksum     uint64                              `json:"checksum"`
+}
+
+// ParseCPUManagerState decodes the cpu manager checkpoint. Both the current (v2) and the
+// old (v1, keyed by container ID) formats are supported.
+func ParseCPUManagerState(data []byte) (*CPUManagerState, error) {
+	var cpV2 cpuManagerCheckpointV2
+	errV2 := json.Unmarshal(data, &cpV2)
+	if errV2 == nil {
+		return fromCPUManagerCheckpointV2(cpV2)
+	}
+	var cpV1 cpuManagerCheckpointV1
+	if err := json.Unmarshal(data, &cpV1); err != nil {
+		return nil, fmt.Errorf("malformed cpu manager checkpoint: %v", errV2)
+	}
+	return fromCPUManagerCheckpointV1(cpV1)
+}
+
+func fromCPUManagerCheckpointV2(cp cpuManagerCheckpointV2) (*CPUManagerState, error) {
+	defCpus, err := cpuset.Parse(cp.DefaultCPUSet)
+	if err != nil {
+		return nil, fmt.Errorf("malformed default cpuset %q: %v", cp.DefaultCPUSet, err)
+	}
+	st := CPUManagerState{
+		PolicyName:  cp.PolicyName,
+		DefaultCPUs: defCpus.List(),
+	}
+	for podUID, containers := range cp.Entries {
+		for containerName, cpuList := range containers {
+			cpus, err := cpuset.Parse(cpuList)
+			if err != nil {
+				return nil, fmt.Errorf("malformed cpuset %q for pod %q container %q: %v", cpuList, podUID, containerName, err)
+			}
+			st.Assignments = append(st.Assignments, ContainerCPUs{
+				PodUID:        podUID,
+				ContainerName: containerName,
+				CPUs:          cpus.List(),
+			})
+		}
+	}
+	sortContainerCPUs(st.Assignments)
+	return &st, nil
+}
+
+func fromCPUManagerCheckpointV1(cp cpuManagerCheckpointV1) (*CPUManagerState, error) {
+	defCpus, err := cpuset.Parse(cp.DefaultCPUSet)
+	if err != nil {
+		return nil, fmt.Errorf("malformed default cpuset %q: %v", cp.DefaultCPUSet, err)
+	}
+	st := CPUManagerState{
+		PolicyName:  cp.PolicyName,
+		DefaultCPUs: defCpus.List(),
+	}
+	for containerID, cpuList := range cp.Entries {
+		cpus, err := cpuset.Parse(cpuList)
+		if err != nil {
+			return nil, fmt.Errorf("malformed cpuset %q for container %q: %v", cpuList, containerID, err)
+		}
+		st.Assignments = append(st.Assignments, ContainerCPUs{
+			ContainerID: containerID,
+			CPUs:        cpus.List(),
+		})
+	}
+	sortContainerCPUs(st.Assignments)
+	return &st, nil
+}
+
+// ParseMemoryManagerState decodes the memory manager checkpoint.
+func ParseMemoryManagerState(data []byte) (*MemoryManagerState, error) {
+	var cp memoryManagerCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("malformed memory manager checkpoint: %v", err)
+	}
+	st := MemoryManagerState{
+		PolicyName:   cp.PolicyName,
+		MachineState: cp.MachineState,
+	}
+	for podUID, containers := range cp.Entries {
+		for containerName, blocks := range containers {
+			st.Assignments = append(st.Assignments, ContainerMemory{
+				PodUID:        podUID,
+				ContainerName: containerName,
+				Blocks:        blocks,
+			})
+		}
+	}
+	sort.Slice(st.Assignments, func(i, j int) bool {
+		if st.Assignments[i].PodUID != st.Assignments[j].PodUID {
+			return st.Assignments[i].PodUID < st.Assignments[j].PodUID
+		}
+		return st.Assignments[i].ContainerName < st.Assignments[j].ContainerName
+	})
+	return &st, nil
+}
+
+type Handler struct {
+	log        *log.Logger
+	kubeletDir string
+	fs         fswrap.FSWrapper
+}
+
+func New(logger *log.Logger, kubeletDir string) *Handler {
+	return NewWithFS(logger, fswrap.LinuxFS{Log: logger}, kubeletDir)
+}
+
+// NewWithFS creates a Handler which reads the kubelet state, rooted at kubeletDir, from fsys.
+func NewWithFS(logger *log.Logger, fsys fswrap.FSWrapper, kubeletDir string) *Handler {
+	return &Handler{
+		log:        logger,
+		kubeletDir: kubeletDir,
+		fs:         fsys,
+	}
+}
+
+func (handler *Handler) ReadCPUManagerState() (*CPUManagerState, error) {
+	data, err := handler.fs.ReadFile(filepath.Join(handler.kubeletDir, CPUManagerStateFile))
+	if err != nil {
+		return nil, err
+	}
+	return ParseCPUManagerState(data)
+}
+
+func (handler *Handler) ReadMemoryManagerState() (*MemoryManagerState, error) {
+	data, err := handler.fs.ReadFile(filepath.Join(handler.kubeletDir, MemoryManagerStateFile))
+	if err != nil {
+		return nil, err
+	}
+	return ParseMemoryManagerState(data)
+}
+
+func sortContainerCPUs(ccs []ContainerCPUs) {
+	sort.Slice(ccs, func(i, j int) bool {
+		if ccs[i].PodUID != ccs[j].PodUID {
+			return ccs[i].PodUID < ccs[j].PodUID
+		}
+		if ccs[i].ContainerName != ccs[j].ContainerName {
+			return ccs[i].ContainerName < ccs[j].ContainerName
+		}
+		return ccs[i].ContainerID < ccs[j].ContainerID
+	})
+}
+
+func containerRef(podUID, containerName, containerID string) string {
+	if containerID != "" {
+		return "container " + containerID
+	}
+	return fmt.Sprintf("pod %s container %s", podUID, containerName)
+}
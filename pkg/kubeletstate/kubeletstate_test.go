@@ -0,0 +1,187 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package kubeletstate_test
+
+import (
+	"io/ioutil"
+	"log"
+	"reflect"
+	"testing"
+
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+	"github.com/openshift-kni/debug-tools/pkg/kubeletstate"
+)
+
+var nullLog = log.New(ioutil.Discard, "", 0)
+
+const cpuManagerStateV2 = `{"policyName":"static","defaultCpuSet":"0-1,6-7","entries":{"5d3b9a5c-5a4c-4b8e-9a6c-1f0d6c7f8a11":{"app":"2-3","sidecar":"4"}},"checksum":1353318690}`
+
+const cpuManagerStateV1 = `{"policyName":"static","defaultCpuSet":"0-1,6-7","entries":{"a1b2c3d4e5f6":"2-3"},"checksum":2453318690}`
+
+const memoryManagerState = `{
+  "policyName":"Static",
+  "machineState":{
+    "0":{"numberOfAssignments":2,"memoryMap":{
+      "hugepages-1Gi":{"total":4294967296,"systemReserved":0,"allocatable":4294967296,"reserved":2147483648,"free":2147483648},
+      "memory":{"total":33554432000,"systemReserved":1073741824,"allocatable":32480690176,"reserved":1073741824,"free":31406948352}},
+      "cells":[0]},
+    "1":{"numberOfAssignments":0,"memoryMap":{
+      "memory":{"total":33554432000,"systemReserved":0,"allocatable":33554432000,"reserved":0,"free":33554432000}},
+      "cells":[1]}
+  },
+  "entries":{"5d3b9a5c-5a4c-4b8e-9a6c-1f0d6c7f8a11":{"app":[
+    {"numaAffinity":[0],"type":"memory","size":1073741824},
+    {"numaAffinity":[0],"type":"hugepages-1Gi","size":2147483648}]}},
+  "checksum":163710462
+}`
+
+func TestReadCPUManagerState(t *testing.T) {
+	fsys := fswrap.NewMemFS(map[string]string{
+		"/var/lib/kubelet/cpu_manager_state": cpuManagerStateV2,
+	})
+	st, err := kubeletstate.NewWithFS(nullLog, fsys, kubeletstate.DefaultKubeletDir).ReadCPUManagerState()
+	if err != nil {
+		t.Fatalf("ReadCPUManagerState failed: %v", err)
+	}
+	expected := &kubeletstate.CPUManagerState{
+		PolicyName:  "static",
+		DefaultCPUs: []int{0, 1, 6, 7},
+		Assignments: []kubeletstate.ContainerCPUs{
+			{PodUID: "5d3b9a5c-5a4c-4b8e-9a6c-1f0d6c7f8a11", ContainerName: "app", CPUs: []int{2, 3}},
+			{PodUID: "5d3b9a5c-5a4c-4b8e-9a6c-1f0d6c7f8a11", ContainerName: "sidecar", CPUs: []int{4}},
+		},
+	}
+	if !reflect.DeepEqual(st, expected) {
+		t.Errorf("state mismatch got %+v expected %+v", st, expected)
+	}
+	if findings := st.Validate(); len(findings) != 0 {
+		t.Errorf("unexpected findings: %v", findings)
+	}
+}
+
+func TestParseCPUManagerStateV1(t *testing.T) {
+	st, err := kubeletstate.ParseCPUManagerState([]byte(cpuManagerStateV1))
+	if err != nil {
+		t.Fatalf("ParseCPUManagerState failed: %v", err)
+	}
+	expected := []kubeletstate.ContainerCPUs{
+		{ContainerID: "a1b2c3d4e5f6", CPUs: []int{2, 3}},
+	}
+	if !reflect.DeepEqual(st.Assignments, expected) {
+		t.Errorf("assignments mismatch got %+v expected %+v", st.Assignments, expected)
+	}
+}
+
+func TestParseCPUManagerStateMalformed(t *testing.T) {
+	for _, data := range []string{
+		`{"policyName":"static","defaultCpuSet":"0-x"}`,
+		`{"policyName":"static","defaultCpuSet":"0-1","entries":{"uid":{"app":"foo"}}}`,
+		`not json`,
+	} {
+		if _, err := kubeletstate.ParseCPUManagerState([]byte(data)); err == nil {
+			t.Errorf("expected error parsing %q", data)
+		}
+	}
+}
+
+func TestValidateCPUManagerState(t *testing.T) {
+	st := &kubeletstate.CPUManagerState{
+		PolicyName:  "static",
+		DefaultCPUs: []int{0, 1, 2},
+		Assignments: []kubeletstate.ContainerCPUs{
+			{PodUID: "uid-1", ContainerName: "app", CPUs: []int{2, 3}},
+			{PodUID: "uid-2", ContainerName: "app", CPUs: []int{3, 4}},
+		},
+	}
+	findings := st.Validate()
+	if len(findings) != 2 {
+		t.Fatalf("expected 2 findings got %v", findings)
+	}
+	if findings[0].Container != "pod uid-1 container app" || findings[1].Container != "pod uid-2 container app" {
+		t.Errorf("unexpected findings: %v", findings)
+	}
+}
+
+func TestReadMemoryManagerState(t *testing.T) {
+	fsys := fswrap.NewMemFS(map[string]string{
+		"/var/lib/kubelet/memory_manager_state": memoryManagerState,
+	})
+	st, err := kubeletstate.NewWithFS(nullLog, fsys, kubeletstate.DefaultKubeletDir).ReadMemoryManagerState()
+	if err != nil {
+		t.Fatalf("ReadMemoryManagerState failed: %v", err)
+	}
+	if st.PolicyName != "Static" || len(st.MachineState) != 2 {
+		t.Fatalf("unexpected state: %+v", st)
+	}
+	if free := st.MachineState[0].MemoryMap["hugepages-1Gi"].Free; free != 2147483648 {
+		t.Errorf("unexpected hugepages free on node 0: %d", free)
+	}
+	if len(st.Assignments) != 1 || len(st.Assignments[0].Blocks) != 2 {
+		t.Errorf("unexpected assignments: %+v", st.Assignments)
+	}
+}
+
+func TestCrossCheck(t *testing.T) {
+	cpuState, err := kubeletstate.ParseCPUManagerState([]byte(cpuManagerStateV2))
+	if err != nil {
+		t.Fatalf("ParseCPUManagerState failed: %v", err)
+	}
+	memState, err := kubeletstate.ParseMemoryManagerState([]byte(memoryManagerState))
+	if err != nil {
+		t.Fatalf("ParseMemoryManagerState failed: %v", err)
+	}
+
+	podRes := []kubeletstate.PodResourcesContainer{
+		{
+			Namespace: "default",
+			PodName:   "dpdk",
+			Name:      "app",
+			CPUs:      []int{3, 2},
+			Memory: []kubeletstate.MemoryBlock{
+				{NUMAAffinity: []int{0}, Type: "hugepages-1Gi", Size: 2147483648},
+				{NUMAAffinity: []int{0}, Type: "memory", Size: 1073741824},
+			},
+		},
+		{
+			Namespace: "default",
+			PodName:   "dpdk",
+			Name:      "sidecar",
+			CPUs:      []int{4},
+		},
+	}
+	if findings := kubeletstate.CrossCheck(cpuState, memState, podRes); len(findings) != 0 {
+		t.Errorf("unexpected findings: %v", findings)
+	}
+
+	podRes[1].CPUs = []int{5}
+	podRes[0].Memory[0].NUMAAffinity = []int{1}
+	podRes = append(podRes, kubeletstate.PodResourcesContainer{
+		Namespace: "default",
+		PodName:   "other",
+		Name:      "app",
+	})
+	findings := kubeletstate.CrossCheck(cpuState, memState, podRes)
+	expected := []kubeletstate.Finding{
+		{Manager: kubeletstate.ManagerCPU, Container: "pod 5d3b9a5c-5a4c-4b8e-9a6c-1f0d6c7f8a11 container sidecar", Message: "exclusive cpus [4] not reported by podresources"},
+		{Manager: kubeletstate.ManagerCPU, Container: "pod default/dpdk container sidecar", Message: "exclusive cpus [5] reported by podresources are missing from the checkpoint"},
+		{Manager: kubeletstate.ManagerMemory, Container: "pod 5d3b9a5c-5a4c-4b8e-9a6c-1f0d6c7f8a11 container app", Message: "memory blocks [{[0] memory 1073741824} {[0] hugepages-1Gi 2147483648}] not reported by podresources"},
+		{Manager: kubeletstate.ManagerMemory, Container: "pod default/dpdk container app", Message: "memory blocks [{[1] hugepages-1Gi 2147483648} {[0] memory 1073741824}] reported by podresources are missing from the checkpoint"},
+	}
+	if !reflect.DeepEqual(findings, expected) {
+		t.Errorf("findings mismatch\ngot      %v\nexpected %v", findings, expected)
+	}
+}
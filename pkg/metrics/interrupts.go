@@ -0,0 +1,147 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package metrics
+
+import (
+	"log"
+	"sort"
+	"strconv"
+
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/irqs"
+	softirqs "github.com/openshift-kni/debug-tools/pkg/irqs/soft"
+)
+
+const (
+	CPUSetIsolated     = "isolated"
+	CPUSetHousekeeping = "housekeeping"
+)
+
+// InterruptsCollector collects the per-cpu IRQ and softirq counters.
+type InterruptsCollector struct {
+	log      *log.Logger
+	ih       *irqs.Handler
+	sh       *softirqs.Handler
+	isolated cpuset.CPUSet
+}
+
+func NewInterruptsCollector(logger *log.Logger, ih *irqs.Handler, sh *softirqs.Handler, isolated cpuset.CPUSet) *InterruptsCollector {
+	return &InterruptsCollector{
+		log:      logger,
+		ih:       ih,
+		sh:       sh,
+		isolated: isolated,
+	}
+}
+
+func (ic *InterruptsCollector) Collect() ([]Family, error) {
+	stats, err := ic.ih.ReadStats()
+	if err != nil {
+		return nil, err
+	}
+	softInfo, err := ic.sh.ReadInfo()
+	if err != nil {
+		return nil, err
+	}
+	return []Family{
+		ic.irqFamily(stats, ic.irqSources()),
+		ic.softirqFamily(softInfo),
+	}, nil
+}
+
+func (ic *InterruptsCollector) irqFamily(stats irqs.Stats, sources map[string]string) Family {
+	fam := Family{
+		Name: "knit_irq_total",
+		Help: "Interrupts served per cpu and IRQ, from /proc/interrupts.",
+		Type: TypeCounter,
+	}
+	for _, cpu := range sortedCPUs(stats) {
+		counter := stats[cpu]
+		names := make([]string, 0, len(counter))
+		for name := range counter {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fam.Samples = append(fam.Samples, Sample{
+				Labels: map[string]string{
+					"cpu":    strconv.Itoa(cpu),
+					"cpuset": ic.cpuSetName(cpu),
+					"irq":    name,
+					"source": sources[name],
+				},
+				Value: float64(counter[name]),
+			})
+		}
+	}
+	return fam
+}
+
+func (ic *InterruptsCollector) softirqFamily(info *softirqs.Info) Family {
+	fam := Family{
+		Name: "knit_softirq_total",
+		Help: "Softirqs served per cpu and type, from /proc/softirqs.",
+		Type: TypeCounter,
+	}
+	for _, name := range softirqs.Names() {
+		for cpu, count := range info.Counters[name] {
+			fam.Samples = append(fam.Samples, Sample{
+				Labels: map[string]string{
+					"cpu":     strconv.Itoa(cpu),
+					"cpuset":  ic.cpuSetName(cpu),
+					"softirq": name,
+				},
+				Value: float64(count),
+			})
+		}
+	}
+	return fam
+}
+
+// irqSources maps the IRQ numbers to their sources. Failures are not critical, and yield empty sources.
+func (ic *InterruptsCollector) irqSources() map[string]string {
+	sources := make(map[string]string)
+	infos, err := ic.ih.ReadInfo(0)
+	if err != nil {
+		ic.log.Printf("error reading the IRQ sources: %v", err)
+		return sources
+	}
+	for _, info := range infos {
+		if info.Source == "" {
+			continue
+		}
+		sources[strconv.Itoa(info.IRQ)] = info.Source
+	}
+	return sources
+}
+
+func (ic *InterruptsCollector) cpuSetName(cpu int) string {
+	if ic.isolated.Contains(cpu) {
+		return CPUSetIsolated
+	}
+	return CPUSetHousekeeping
+}
+
+func sortedCPUs(stats irqs.Stats) []int {
+	cpus := make([]int, 0, len(stats))
+	for cpu := range stats {
+		cpus = append(cpus, cpu)
+	}
+	sort.Ints(cpus)
+	return cpus
+}
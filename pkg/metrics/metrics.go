@@ -0,0 +1,126 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+// Package metrics exposes the knit data in the prometheus text exposition format.
+// See https://prometheus.io/docs/instrumenting/exposition_formats/
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type MetricType string
+
+const (
+	TypeCounter MetricType = "counter"
+	TypeGauge   MetricType = "gauge"
+)
+
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// Family is a set of samples sharing the same metric name.
+type Family struct {
+	Name    string
+	Help    string
+	Type    MetricType
+	Samples []Sample
+}
+
+type Collector interface {
+	Collect() ([]Family, error)
+}
+
+// Write writes the families in the text exposition format. Labels are sorted by name.
+func Write(w io.Writer, families []Family) error {
+	bw := bufio.NewWriter(w)
+	for _, fam := range families {
+		fmt.Fprintf(bw, "# HELP %s %s\n", fam.Name, escape(fam.Help, false))
+		fmt.Fprintf(bw, "# TYPE %s %s\n", fam.Name, fam.Type)
+		for _, sample := range fam.Samples {
+			bw.WriteString(fam.Name)
+			writeLabels(bw, sample.Labels)
+			bw.WriteString(" ")
+			bw.WriteString(strconv.FormatFloat(sample.Value, 'g', -1, 64))
+			bw.WriteString("\n")
+		}
+	}
+	return bw.Flush()
+}
+
+func writeLabels(bw *bufio.Writer, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	bw.WriteString("{")
+	for idx, name := range names {
+		if idx > 0 {
+			bw.WriteString(",")
+		}
+		fmt.Fprintf(bw, "%s=\"%s\"", name, escape(labels[name], true))
+	}
+	bw.WriteString("}")
+}
+
+func escape(s string, quotes bool) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	if quotes {
+		s = strings.ReplaceAll(s, `"`, `\"`)
+	}
+	return s
+}
+
+// NewHandler creates a http.Handler serving the metrics from all the collectors.
+// Any collector failure fails the whole scrape, to never serve partial data.
+func NewHandler(logger *log.Logger, collectors ...Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var families []Family
+		for _, coll := range collectors {
+			fams, err := coll.Collect()
+			if err != nil {
+				logger.Printf("error collecting metrics: %v", err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			families = append(families, fams...)
+		}
+		var buf bytes.Buffer
+		if err := Write(&buf, families); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", ContentType)
+		w.Write(buf.Bytes())
+	})
+}
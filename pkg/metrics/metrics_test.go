@@ -0,0 +1,115 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package metrics_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+	"github.com/openshift-kni/debug-tools/pkg/irqs"
+	softirqs "github.com/openshift-kni/debug-tools/pkg/irqs/soft"
+	"github.com/openshift-kni/debug-tools/pkg/metrics"
+)
+
+var nullLog = log.New(ioutil.Discard, "", 0)
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	err := metrics.Write(&buf, []metrics.Family{
+		{
+			Name: "knit_test_total",
+			Help: "Test counter,\nwith a newline.",
+			Type: metrics.TypeCounter,
+			Samples: []metrics.Sample{
+				{Labels: map[string]string{"source": `eth"0\`, "cpu": "1"}, Value: 42},
+				{Value: 0.5},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	expected := `# HELP knit_test_total Test counter,\nwith a newline.
+# TYPE knit_test_total counter
+knit_test_total{cpu="1",source="eth\"0\\"} 42
+knit_test_total 0.5
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("output mismatch\ngot:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestInterruptsCollector(t *testing.T) {
+	fsys := fswrap.NewMemFS(map[string]string{
+		"/proc/interrupts": "           CPU0       CPU1\n" +
+			" 24:         10          5  PCI-MSI  eth0-rx-0\n" +
+			"NMI:          1          2   Non-maskable interrupts\n",
+		"/proc/softirqs": "                    CPU0       CPU1\n" +
+			"          HI:          1          0\n" +
+			"       TIMER:        100        200\n",
+		"/proc/irq/24/smp_affinity_list": "0-1\n",
+	})
+	fsys.AddDir("/proc/irq/24/eth0-rx-0")
+
+	coll := metrics.NewInterruptsCollector(nullLog, irqs.NewWithFS(nullLog, fsys, "/proc"), softirqs.NewWithFS(nullLog, fsys, "/proc"), cpuset.New(1))
+	srv := httptest.NewServer(metrics.NewHandler(nullLog, coll))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != metrics.ContentType {
+		t.Fatalf("unexpected response: %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading the response failed: %v", err)
+	}
+	got := string(data)
+
+	for _, line := range []string{
+		`knit_irq_total{cpu="0",cpuset="housekeeping",irq="24",source="eth0-rx-0"} 10`,
+		`knit_irq_total{cpu="1",cpuset="isolated",irq="24",source="eth0-rx-0"} 5`,
+		`knit_irq_total{cpu="1",cpuset="isolated",irq="NMI",source=""} 2`,
+		`knit_softirq_total{cpu="1",cpuset="isolated",softirq="TIMER"} 200`,
+		`knit_softirq_total{cpu="0",cpuset="housekeeping",softirq="HI"} 1`,
+	} {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, got)
+		}
+	}
+}
+
+func TestInterruptsCollectorFailure(t *testing.T) {
+	fsys := fswrap.NewMemFS(nil)
+	coll := metrics.NewInterruptsCollector(nullLog, irqs.NewWithFS(nullLog, fsys, "/proc"), softirqs.NewWithFS(nullLog, fsys, "/proc"), cpuset.New())
+	rec := httptest.NewRecorder()
+	metrics.NewHandler(nullLog, coll).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("unexpected status %d", rec.Code)
+	}
+}
@@ -0,0 +1,211 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+// Package noise tells what disturbed the cpus over a time window, combining the IRQ, softirq,
+// cpu time and scheduler counters.
+package noise
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/irqs"
+	"github.com/openshift-kni/debug-tools/pkg/procs"
+)
+
+// KindThread is the kind of the thread sources, the IRQ and softirq sources use irqs.KindIRQ and irqs.KindSoftirq
+const KindThread = "THREAD"
+
+// Sample holds all the counters read at a given time.
+type Sample struct {
+	Timestamp time.Time
+	IRQs      irqs.Stats
+	Softirqs  irqs.Stats
+	CPUTimes  procs.CPUTimes
+	Threads   procs.SchedStats
+}
+
+// Source is something which disturbed a cpu. Count is the number of IRQs or softirqs served,
+// or the number of context switches of the thread.
+type Source struct {
+	Kind   string  `json:"kind"`
+	Name   string  `json:"name"`
+	Detail string  `json:"detail,omitempty"`
+	Count  uint64  `json:"count"`
+	Rate   float64 `json:"rate"`
+}
+
+func (so Source) String() string {
+	name := so.Name
+	if so.Detail != "" {
+		name += " [" + so.Detail + "]"
+	}
+	return fmt.Sprintf("%-7s %-48s %10d %10.2f/s", so.Kind, name, so.Count, so.Rate)
+}
+
+// CPUNoise is what disturbed a cpu, the sources ranked by count.
+type CPUNoise struct {
+	CPU      int  `json:"cpu"`
+	Isolated bool `json:"isolated,omitempty"`
+	// the time spent serving IRQs, softirqs, and stolen by the hypervisor, in percent of the window
+	IRQTime     float64 `json:"irqTime"`
+	SoftirqTime float64 `json:"softirqTime"`
+	StealTime   float64 `json:"stealTime"`
+	// CtxSwitches are the context switches of the non-workload threads which ran on the cpu
+	CtxSwitches uint64   `json:"ctxSwitches"`
+	Events      uint64   `json:"events"`
+	Sources     []Source `json:"sources"`
+}
+
+// Report is the noise of each cpu over the window.
+type Report struct {
+	Window string     `json:"window"`
+	CPUs   []CPUNoise `json:"cpus"`
+}
+
+// Options tunes the analysis.
+type Options struct {
+	CPUs     cpuset.CPUSet
+	Isolated cpuset.CPUSet
+	// Devices describe the IRQs, see irqs.Resolver
+	Devices map[int]irqs.Device
+	// IsWorkload tells if the thread belongs to the workload, hence is not noise.
+	// If nil, all the threads are noise.
+	IsWorkload func(tc procs.ThreadCounters) bool
+	// MaxSources limits the number of sources per cpu, 0 means all.
+	MaxSources int
+}
+
+// Analyze ranks the sources which disturbed the cpus between the two samples.
+// The threads are accounted on the cpu they last ran on, if they were switched during the window
+// or are runnable at its end: threads bouncing across cpus are accounted only once.
+func Analyze(prev, last Sample, opts Options) Report {
+	elapsed := last.Timestamp.Sub(prev.Timestamp)
+	secs := elapsed.Seconds()
+	if secs <= 0 {
+		secs = 1
+	}
+	irqDelta := prev.IRQs.Delta(last.IRQs)
+	softirqDelta := prev.Softirqs.Delta(last.Softirqs)
+	timesDelta := prev.CPUTimes.Delta(last.CPUTimes)
+	threadsDelta := prev.Threads.Delta(last.Threads)
+
+	report := Report{
+		Window: elapsed.Round(time.Millisecond).String(),
+		CPUs:   []CPUNoise{},
+	}
+	cpuNoises := make(map[int]*CPUNoise)
+	for _, cpuid := range opts.CPUs.List() {
+		cn := &CPUNoise{
+			CPU:      cpuid,
+			Isolated: opts.Isolated.Contains(cpuid),
+			Sources:  []Source{},
+		}
+		if times, ok := timesDelta[cpuid]; ok && times.Total() > 0 {
+			total := float64(times.Total())
+			cn.IRQTime = 100 * float64(times.IRQ) / total
+			cn.SoftirqTime = 100 * float64(times.SoftIRQ) / total
+			cn.StealTime = 100 * float64(times.Steal) / total
+		}
+		for name, count := range irqDelta[cpuid] {
+			if count == 0 {
+				continue
+			}
+			so := Source{Kind: irqs.KindIRQ, Name: name, Count: count, Rate: float64(count) / secs}
+			if dev, ok := irqs.LookupDevice(opts.Devices, name); ok {
+				so.Detail = dev.String()
+			}
+			cn.Sources = append(cn.Sources, so)
+		}
+		for name, count := range softirqDelta[cpuid] {
+			if count == 0 {
+				continue
+			}
+			cn.Sources = append(cn.Sources, Source{Kind: irqs.KindSoftirq, Name: name, Count: count, Rate: float64(count) / secs})
+		}
+		cpuNoises[cpuid] = cn
+	}
+
+	for _, tc := range threadsDelta {
+		cn, ok := cpuNoises[tc.CPU]
+		if !ok {
+			continue
+		}
+		if opts.IsWorkload != nil && opts.IsWorkload(tc) {
+			continue
+		}
+		count := tc.VoluntaryCtxSwitches + tc.NonvoluntaryCtxSwitches
+		if count == 0 && tc.State != "R" {
+			continue
+		}
+		detail := fmt.Sprintf("pid %d tid %d", tc.PID, tc.TID)
+		if tc.Kernel {
+			detail = fmt.Sprintf("kernel tid %d", tc.TID)
+		}
+		if tc.State == "R" {
+			detail += ", runnable"
+		}
+		cn.CtxSwitches += count
+		cn.Sources = append(cn.Sources, Source{Kind: KindThread, Name: tc.Name, Detail: detail, Count: count, Rate: float64(count) / secs})
+	}
+
+	for _, cpuid := range opts.CPUs.List() {
+		cn := cpuNoises[cpuid]
+		for _, so := range cn.Sources {
+			cn.Events += so.Count
+		}
+		sortSources(cn.Sources)
+		if opts.MaxSources > 0 && len(cn.Sources) > opts.MaxSources {
+			cn.Sources = cn.Sources[:opts.MaxSources]
+		}
+		report.CPUs = append(report.CPUs, *cn)
+	}
+	return report
+}
+
+func sortSources(sources []Source) {
+	sort.Slice(sources, func(i, j int) bool {
+		if sources[i].Count != sources[j].Count {
+			return sources[i].Count > sources[j].Count
+		}
+		if sources[i].Kind != sources[j].Kind {
+			return sources[i].Kind < sources[j].Kind
+		}
+		if sources[i].Name != sources[j].Name {
+			return sources[i].Name < sources[j].Name
+		}
+		return sources[i].Detail < sources[j].Detail
+	})
+}
+
+// String renders the noise of a cpu, a line per source.
+func (cn CPUNoise) String() string {
+	var sb strings.Builder
+	isolated := ""
+	if cn.Isolated {
+		isolated = " (isolated)"
+	}
+	fmt.Fprintf(&sb, "CPU %d%s: %d events, irq time %.2f%% softirq time %.2f%% steal time %.2f%%, %d context switches\n",
+		cn.CPU, isolated, cn.Events, cn.IRQTime, cn.SoftirqTime, cn.StealTime, cn.CtxSwitches)
+	for _, so := range cn.Sources {
+		fmt.Fprintf(&sb, "  %s\n", so.String())
+	}
+	return sb.String()
+}
@@ -0,0 +1,142 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package noise_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/irqs"
+	"github.com/openshift-kni/debug-tools/pkg/noise"
+	"github.com/openshift-kni/debug-tools/pkg/procs"
+)
+
+func TestAnalyze(t *testing.T) {
+	ts := time.Date(2024, 3, 11, 10, 0, 0, 0, time.UTC)
+	prev := noise.Sample{
+		Timestamp: ts,
+		IRQs: irqs.Stats{
+			2: irqs.Counter{"LOC": 100, "142": 10},
+			3: irqs.Counter{"LOC": 100, "142": 0},
+		},
+		Softirqs: irqs.Stats{
+			2: irqs.Counter{"TIMER": 50, "NET_RX": 5},
+			3: irqs.Counter{"TIMER": 50, "NET_RX": 0},
+		},
+		CPUTimes: procs.CPUTimes{
+			2: {User: 1000, Idle: 1000},
+			3: {User: 1000, Idle: 1000},
+		},
+		Threads: procs.SchedStats{
+			10: {PID: 10, TID: 10, Name: "ksoftirqd/2", CPU: 2, State: "S", Kernel: true, VoluntaryCtxSwitches: 5},
+			20: {PID: 20, TID: 21, Name: "app", CPU: 2, State: "R", NonvoluntaryCtxSwitches: 10},
+			30: {PID: 30, TID: 30, Name: "chronyd", CPU: 3, State: "S", VoluntaryCtxSwitches: 1},
+			40: {PID: 40, TID: 40, Name: "idler", CPU: 3, State: "S"},
+			60: {PID: 60, TID: 60, Name: "gone", CPU: 3, State: "S", VoluntaryCtxSwitches: 1000},
+		},
+	}
+	last := noise.Sample{
+		Timestamp: ts.Add(2 * time.Second),
+		IRQs: irqs.Stats{
+			2: irqs.Counter{"LOC": 2100, "142": 410},
+			3: irqs.Counter{"LOC": 102, "142": 0},
+		},
+		Softirqs: irqs.Stats{
+			2: irqs.Counter{"TIMER": 250, "NET_RX": 405},
+			3: irqs.Counter{"TIMER": 52, "NET_RX": 0},
+		},
+		CPUTimes: procs.CPUTimes{
+			2: {User: 1160, Idle: 1000, IRQ: 20, SoftIRQ: 20},
+			3: {User: 1198, Idle: 1000, Steal: 2},
+		},
+		Threads: procs.SchedStats{
+			10: {PID: 10, TID: 10, Name: "ksoftirqd/2", CPU: 2, State: "S", Kernel: true, VoluntaryCtxSwitches: 305},
+			20: {PID: 20, TID: 21, Name: "app", CPU: 2, State: "R", NonvoluntaryCtxSwitches: 5000},
+			30: {PID: 30, TID: 30, Name: "chronyd", CPU: 3, State: "R", VoluntaryCtxSwitches: 1},
+			40: {PID: 40, TID: 40, Name: "idler", CPU: 3, State: "S"},
+			50: {PID: 50, TID: 50, Name: "newcomer", CPU: 3, State: "S", VoluntaryCtxSwitches: 3},
+			// the TID is recycled within the window
+			60: {PID: 60, TID: 60, Name: "recycled", CPU: 3, State: "S", VoluntaryCtxSwitches: 1},
+		},
+	}
+
+	report := noise.Analyze(prev, last, noise.Options{
+		CPUs:     cpuset.New(2, 3),
+		Isolated: cpuset.New(2, 3),
+		Devices: map[int]irqs.Device{
+			142: {IRQ: 142, Actions: []string{"eth0-rx-1"}},
+		},
+		IsWorkload: func(tc procs.ThreadCounters) bool {
+			return tc.PID == 20
+		},
+	})
+
+	if report.Window != "2s" || len(report.CPUs) != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	cn := report.CPUs[0]
+	if cn.CPU != 2 || !cn.Isolated || cn.IRQTime != 10 || cn.SoftirqTime != 10 || cn.StealTime != 0 {
+		t.Errorf("unexpected cpu noise: %+v", cn)
+	}
+	if cn.CtxSwitches != 300 || cn.Events != 2000+400+200+400+300 {
+		t.Errorf("unexpected counters: %+v", cn)
+	}
+	expected := []noise.Source{
+		{Kind: irqs.KindIRQ, Name: "LOC", Count: 2000, Rate: 1000},
+		{Kind: irqs.KindIRQ, Name: "142", Detail: "eth0-rx-1", Count: 400, Rate: 200},
+		{Kind: irqs.KindSoftirq, Name: "NET_RX", Count: 400, Rate: 200},
+		{Kind: noise.KindThread, Name: "ksoftirqd/2", Detail: "kernel tid 10", Count: 300, Rate: 150},
+		{Kind: irqs.KindSoftirq, Name: "TIMER", Count: 200, Rate: 100},
+	}
+	if !reflect.DeepEqual(cn.Sources, expected) {
+		t.Errorf("got sources %+v expected %+v", cn.Sources, expected)
+	}
+
+	cn = report.CPUs[1]
+	if cn.CPU != 3 || cn.StealTime != 1 {
+		t.Errorf("unexpected cpu noise: %+v", cn)
+	}
+	// the runnable thread is reported even if it did not switch
+	var names []string
+	for _, so := range cn.Sources {
+		names = append(names, so.Kind+"/"+so.Name)
+	}
+	expectedNames := []string{"THREAD/newcomer", "IRQ/LOC", "SOFTIRQ/TIMER", "THREAD/recycled", "THREAD/chronyd"}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("got sources %v expected %v", names, expectedNames)
+	}
+	if !strings.Contains(cn.String(), "chronyd [pid 30 tid 30, runnable]") {
+		t.Errorf("unexpected rendering:\n%s", cn.String())
+	}
+
+	report = noise.Analyze(prev, last, noise.Options{
+		CPUs:       cpuset.New(2),
+		MaxSources: 2,
+	})
+	if len(report.CPUs) != 1 || len(report.CPUs[0].Sources) != 2 || report.CPUs[0].Isolated {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	// without workload detection, all the threads are noise
+	if report.CPUs[0].CtxSwitches != 300+4990 || report.CPUs[0].Sources[0].Name != "app" {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
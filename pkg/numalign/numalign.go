@@ -0,0 +1,319 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2020 Red Hat, Inc.
+ */
+
+// Package numalign checks the cpus, the devices and the memory of a set of processes are aligned on a single NUMA node.
+package numalign
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+)
+
+const (
+	SysDevicesSystemNodeDir = "devices/system/node"
+	SysBusPCIDevicesDir     = "bus/pci/devices/"
+)
+
+type Resources struct {
+	CPUToNUMANode     map[int]int    `json:"cpus"`
+	PCIDevsToNUMANode map[string]int `json:"pcidevices"`
+	// MemNUMANodes are the NUMA nodes the processes can allocate memory from (Mems_allowed_list)
+	MemNUMANodes []int `json:"mems"`
+	// MemoryUsage is the memory the processes actually use, set only if the numa_maps were read
+	MemoryUsage *MemoryUsage `json:"memory,omitempty"`
+	// NUMANodes are all the NUMA nodes of the machine. If unknown, the devices on unknown node are not violations.
+	NUMANodes []int `json:"numanodes,omitempty"`
+}
+
+// the reasons of the alignment violations
+const (
+	ReasonSplitCPUs         = "split-cpus"
+	ReasonRemoteDevice      = "remote-device"
+	ReasonUnknownDeviceNode = "unknown-device-node"
+	ReasonRemoteMemory      = "remote-memory"
+)
+
+// the memory resources, see Violation
+const (
+	ResourceCPUs            = "cpus"
+	ResourceMemsAllowed     = "mems"
+	ResourceAnonymousMemory = "anonymous"
+	ResourceHugepages       = "hugepages"
+)
+
+// Violation tells why a resource is not aligned. Resource is one of the Resource* constants, or the PCI device address.
+type Violation struct {
+	Reason    string `json:"reason"`
+	Resource  string `json:"resource"`
+	NUMANodes []int  `json:"numanodes"`
+	Message   string `json:"message"`
+}
+
+func (vi Violation) String() string {
+	return fmt.Sprintf("[%s] %s: %s", vi.Reason, vi.Resource, vi.Message)
+}
+
+type Result struct {
+	Aligned    bool        `json:"aligned"`
+	NUMACellID int         `json:"numacellid"`
+	Resources  *Resources  `json:"resources"`
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+func (re Result) JSON() string {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.Encode(re)
+	return b.String()
+}
+
+// CPUSetMismatchError is returned when the processes checked together are allowed to run on different cpus.
+type CPUSetMismatchError struct {
+	RefPID  string
+	RefCPUs []int
+	PID     string
+	CPUs    []int
+}
+
+func (e *CPUSetMismatchError) Error() string {
+	return fmt.Sprintf("CPU: allowed set differs pid %q (%v) pid %q (%v)", e.RefPID, e.RefCPUs, e.PID, e.CPUs)
+}
+
+// CheckAlignment checks the cpus, the devices and the memory are all on a single NUMA cell.
+// NUMACellID is the cell of the cpus, or -1 if they are split across cells.
+// All the violations found are reported, not just the first one. If the cpus are split across cells,
+// there is no reference cell, so only the split and the devices on unknown node are reported.
+func (numaRes *Resources) CheckAlignment() Result {
+	ret := Result{
+		Aligned:    false,
+		NUMACellID: -1,
+		Resources:  numaRes,
+		Violations: []Violation{},
+	}
+
+	cpusPerNode := make(map[int][]int)
+	for cpuID, cpuNode := range numaRes.CPUToNUMANode {
+		cpusPerNode[cpuNode] = append(cpusPerNode[cpuNode], cpuID)
+	}
+	cpuNodes := sortedKeys(cpusPerNode)
+	if len(cpuNodes) > 1 {
+		var desc []string
+		for _, node := range cpuNodes {
+			desc = append(desc, fmt.Sprintf("%v on node %d", cpuset.New(cpusPerNode[node]...), node))
+		}
+		ret.Violations = append(ret.Violations, Violation{
+			Reason:    ReasonSplitCPUs,
+			Resource:  ResourceCPUs,
+			NUMANodes: cpuNodes,
+			Message:   fmt.Sprintf("CPUs %s", strings.Join(desc, ", ")),
+		})
+	} else if len(cpuNodes) == 1 {
+		ret.NUMACellID = cpuNodes[0]
+	}
+	// without a reference cell, only the cpu split is meaningful
+	split := len(cpuNodes) > 1
+
+	var devs []string
+	for dev := range numaRes.PCIDevsToNUMANode {
+		devs = append(devs, dev)
+	}
+	sort.Strings(devs)
+	for _, dev := range devs {
+		devNode := numaRes.PCIDevsToNUMANode[dev]
+		if devNode == -1 {
+			// on single-node machines, and when we don't know better, every device is local
+			if len(numaRes.NUMANodes) > 1 {
+				ret.Violations = append(ret.Violations, Violation{
+					Reason:    ReasonUnknownDeviceNode,
+					Resource:  dev,
+					NUMANodes: []int{devNode},
+					Message:   fmt.Sprintf("device %s on unknown node", dev),
+				})
+			}
+			continue
+		}
+		if !split && ret.NUMACellID != devNode {
+			ret.Violations = append(ret.Violations, Violation{
+				Reason:    ReasonRemoteDevice,
+				Resource:  dev,
+				NUMANodes: []int{devNode},
+				Message:   fmt.Sprintf("device %s on node %d, CPUs on node %d", dev, devNode, ret.NUMACellID),
+			})
+		}
+	}
+
+	if !split {
+		// without the memory manager pinning, the processes can allocate from every node, that's not a violation
+		if numaRes.IsMemoryRestricted() {
+			ret.Violations = append(ret.Violations, remoteMemory(ResourceMemsAllowed, "memory allowed", numaRes.MemNUMANodes, ret.NUMACellID)...)
+		}
+		if numaRes.MemoryUsage != nil {
+			ret.Violations = append(ret.Violations, remoteMemory(ResourceAnonymousMemory, "anonymous memory used", usedNodes(numaRes.MemoryUsage.Anonymous), ret.NUMACellID)...)
+			ret.Violations = append(ret.Violations, remoteMemory(ResourceHugepages, "hugepages used", usedNodes(numaRes.MemoryUsage.Hugepages), ret.NUMACellID)...)
+		}
+	}
+
+	ret.Aligned = len(ret.Violations) == 0
+	return ret
+}
+
+// IsMemoryRestricted tells if the processes can allocate memory only from a subset of the NUMA nodes
+// of the machine. If the NUMA nodes of the machine are unknown, the memory is not considered restricted.
+func (numaRes *Resources) IsMemoryRestricted() bool {
+	if len(numaRes.NUMANodes) == 0 {
+		return false
+	}
+	return !cpuset.New(numaRes.MemNUMANodes...).Equals(cpuset.New(numaRes.NUMANodes...))
+}
+
+func remoteMemory(resource, desc string, memNodes []int, cellID int) []Violation {
+	for _, memNode := range memNodes {
+		if memNode != cellID {
+			return []Violation{
+				{
+					Reason:    ReasonRemoteMemory,
+					Resource:  resource,
+					NUMANodes: memNodes,
+					Message:   fmt.Sprintf("%s on nodes %v, CPUs on node %d", desc, memNodes, cellID),
+				},
+			}
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[int][]int) []int {
+	keys := make([]int, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func (numaRes *Resources) JSON() string {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.Encode(numaRes)
+	return b.String()
+}
+
+func pidEntries(pids []string) []string {
+	var pidStrings []string
+	if len(pids) > 1 {
+		pidStrings = append(pidStrings, pids...)
+	} else {
+		pidStrings = append(pidStrings, "self")
+	}
+	return pidStrings
+}
+
+func NewResources(fs fswrap.FSWrapper, procfsRoot, sysfsRoot string, environ, pids []string) (*Resources, error) {
+	var err error
+
+	pciDevs := GetPCIDevicesFromEnv(environ)
+
+	pidStrings := pidEntries(pids)
+
+	var refCpuIDs []int
+	refCpuIDs, err = GetAllowedCPUList(fs, filepath.Join(procfsRoot, pidStrings[0], "status"))
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("CPU: allowed for %q: %v", pidStrings[0], refCpuIDs)
+
+	for _, pidString := range pidStrings[1:] {
+		cpuIDs, err := GetAllowedCPUList(fs, filepath.Join(procfsRoot, pidString, "status"))
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("CPU: allowed for %q: %v", pidString, cpuIDs)
+
+		if !reflect.DeepEqual(refCpuIDs, cpuIDs) {
+			return nil, &CPUSetMismatchError{
+				RefPID:  pidStrings[0],
+				RefCPUs: refCpuIDs,
+				PID:     pidString,
+				CPUs:    cpuIDs,
+			}
+		}
+	}
+
+	memNodes := cpuset.New()
+	for _, pidString := range pidStrings {
+		memIDs, err := GetAllowedMemList(fs, filepath.Join(procfsRoot, pidString, "status"))
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("MEM: allowed for %q: %v", pidString, memIDs)
+		memNodes = memNodes.Union(cpuset.New(memIDs...))
+	}
+
+	cpusPerNUMA, err := GetCPUsPerNUMANode(fs, filepath.Join(sysfsRoot, SysDevicesSystemNodeDir))
+	if err != nil {
+		return nil, err
+	}
+	var numaNodes []int
+	for node := range cpusPerNUMA {
+		numaNodes = append(numaNodes, node)
+	}
+	sort.Ints(numaNodes)
+
+	CPUToNUMANode, err := GetCPUToNUMANodeMap(fs, filepath.Join(sysfsRoot, SysDevicesSystemNodeDir), refCpuIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	NUMAPerDev, err := GetPCIDeviceToNumaNodeMap(fs, filepath.Join(sysfsRoot, SysBusPCIDevicesDir), pciDevs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Resources{
+		CPUToNUMANode:     CPUToNUMANode,
+		PCIDevsToNUMANode: NUMAPerDev,
+		MemNUMANodes:      memNodes.List(),
+		NUMANodes:         numaNodes,
+	}, nil
+
+}
+
+// ReadNUMAMaps reads the numa_maps of the given pids, to find the NUMA nodes their anonymous memory
+// and hugepages actually come from. CheckAlignment then considers them.
+func (numaRes *Resources) ReadNUMAMaps(fs fswrap.FSWrapper, procfsRoot string, pids []string) error {
+	usage := NewMemoryUsage()
+	for _, pidString := range pidEntries(pids) {
+		content, err := fs.ReadFile(filepath.Join(procfsRoot, pidString, "numa_maps"))
+		if err != nil {
+			return err
+		}
+		if err := usage.AddNUMAMaps(string(content)); err != nil {
+			return err
+		}
+	}
+	log.Printf("MEM: anonymous memory per node: %v hugepages per node: %v", usage.Anonymous, usage.Hugepages)
+	numaRes.MemoryUsage = usage
+	return nil
+}
@@ -0,0 +1,243 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2020 Red Hat, Inc.
+ */
+
+package numalign
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+)
+
+func TestResources(t *testing.T) {
+	type testCase struct {
+		name     string
+		env      []string
+		content  map[string]string
+		expected Result
+		reasons  []string
+	}
+
+	testCases := []testCase{
+		{
+			name: "single node",
+			env:  []string{"PCIDEVICE_IO_OPENSHIFT_KNI_CARD=0000:00:1f.0"},
+			content: map[string]string{
+				"/sys/devices/system/node/node0/cpulist":      "0-3",
+				"/sys/bus/pci/devices/0000:00:1f.0/numa_node": "0",
+				"/proc/self/status":                           fullStatus,
+			},
+			expected: Result{
+				Aligned:    true,
+				NUMACellID: 0,
+			},
+		},
+		{
+			name: "dual node",
+			env:  []string{"PCIDEVICE_IO_OPENSHIFT_KNI_CARD=0000:00:1f.0"},
+			content: map[string]string{
+				"/sys/devices/system/node/node0/cpulist":      "0-3",
+				"/sys/devices/system/node/node1/cpulist":      "4-7",
+				"/sys/bus/pci/devices/0000:00:1f.0/numa_node": "0",
+				"/proc/self/status":                           fullStatus,
+			},
+			expected: Result{
+				Aligned:    true,
+				NUMACellID: 0,
+			},
+		},
+		{
+			name: "memory not restricted",
+			content: map[string]string{
+				"/sys/devices/system/node/node0/cpulist": "0-3",
+				"/sys/devices/system/node/node1/cpulist": "4-7",
+				"/proc/self/status":                      strings.Replace(fullStatus, "Mems_allowed_list:\t0", "Mems_allowed_list:\t0-1", 1),
+			},
+			expected: Result{
+				Aligned:    true,
+				NUMACellID: 0,
+			},
+		},
+		{
+			name: "memory on remote node",
+			content: map[string]string{
+				"/sys/devices/system/node/node0/cpulist": "0-3",
+				"/sys/devices/system/node/node1/cpulist": "4-7",
+				"/sys/devices/system/node/node2/cpulist": "8-11",
+				"/proc/self/status":                      strings.Replace(fullStatus, "Mems_allowed_list:\t0", "Mems_allowed_list:\t0-1", 1),
+			},
+			expected: Result{
+				Aligned:    false,
+				NUMACellID: 0,
+			},
+			reasons: []string{ReasonRemoteMemory},
+		},
+		{
+			name: "hugepages on local node",
+			content: map[string]string{
+				"/sys/devices/system/node/node0/cpulist": "0-3",
+				"/sys/devices/system/node/node1/cpulist": "4-7",
+				"/proc/self/status":                      fullStatus,
+				"/proc/self/numa_maps":                   localNUMAMaps,
+			},
+			expected: Result{
+				Aligned:    true,
+				NUMACellID: 0,
+			},
+		},
+		{
+			name: "hugepages on remote node",
+			env:  []string{"PCIDEVICE_IO_OPENSHIFT_KNI_CARD=0000:00:1f.0"},
+			content: map[string]string{
+				"/sys/devices/system/node/node0/cpulist":      "0-3",
+				"/sys/devices/system/node/node1/cpulist":      "4-7",
+				"/sys/bus/pci/devices/0000:00:1f.0/numa_node": "0",
+				"/proc/self/status":                           fullStatus,
+				"/proc/self/numa_maps":                        localNUMAMaps + "7f0000000000 default file=/dev/hugepages/dpdk huge dirty=2 N1=2 kernelpagesize_kB=1048576\n",
+			},
+			expected: Result{
+				Aligned:    false,
+				NUMACellID: 0,
+			},
+			reasons: []string{ReasonRemoteMemory},
+		},
+		{
+			name: "split cpus",
+			env:  []string{"PCIDEVICE_IO_OPENSHIFT_KNI_CARD=0000:00:1f.0"},
+			content: map[string]string{
+				"/sys/devices/system/node/node0/cpulist":      "0-1",
+				"/sys/devices/system/node/node1/cpulist":      "2-3",
+				"/sys/bus/pci/devices/0000:00:1f.0/numa_node": "1",
+				"/proc/self/status":                           fullStatus,
+			},
+			expected: Result{
+				Aligned:    false,
+				NUMACellID: -1,
+			},
+			reasons: []string{ReasonSplitCPUs},
+		},
+		{
+			name: "remote and unknown devices",
+			env:  []string{"PCIDEVICE_IO_OPENSHIFT_KNI_CARD=0000:00:1f.0", "PCIDEVICE_IO_OPENSHIFT_KNI_VF=0000:00:1f.1"},
+			content: map[string]string{
+				"/sys/devices/system/node/node0/cpulist":      "0-3",
+				"/sys/devices/system/node/node1/cpulist":      "4-7",
+				"/sys/bus/pci/devices/0000:00:1f.0/numa_node": "1",
+				"/sys/bus/pci/devices/0000:00:1f.1/numa_node": "-1",
+				"/proc/self/status":                           fullStatus,
+			},
+			expected: Result{
+				Aligned:    false,
+				NUMACellID: 0,
+			},
+			reasons: []string{ReasonRemoteDevice, ReasonUnknownDeviceNode},
+		},
+		{
+			name: "unknown device node on single node",
+			env:  []string{"PCIDEVICE_IO_OPENSHIFT_KNI_CARD=0000:00:1f.0"},
+			content: map[string]string{
+				"/sys/devices/system/node/node0/cpulist":      "0-3",
+				"/sys/bus/pci/devices/0000:00:1f.0/numa_node": "-1",
+				"/proc/self/status":                           fullStatus,
+			},
+			expected: Result{
+				Aligned:    true,
+				NUMACellID: 0,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := fswrap.NewMemFS(tc.content)
+			numaRes, err := NewResources(fs, "/proc", "/sys", tc.env, []string{})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if _, ok := tc.content["/proc/self/numa_maps"]; ok {
+				if err := numaRes.ReadNUMAMaps(fs, "/proc", []string{}); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+			res := numaRes.CheckAlignment()
+			if res.Aligned != tc.expected.Aligned {
+				t.Errorf("alignment mismatch: got %v expected %v", res.Aligned, tc.expected.Aligned)
+			}
+			if res.NUMACellID != tc.expected.NUMACellID {
+				t.Errorf("NUMA cell ID mismatch: got %v expected %v", res.NUMACellID, tc.expected.NUMACellID)
+			}
+			var reasons []string
+			for _, vi := range res.Violations {
+				reasons = append(reasons, vi.Reason)
+			}
+			if !reflect.DeepEqual(reasons, tc.reasons) {
+				t.Errorf("violations mismatch: got %v expected %v", res.Violations, tc.reasons)
+			}
+		})
+	}
+}
+
+func TestResourcesCPUSetMismatch(t *testing.T) {
+	fs := fswrap.NewMemFS(map[string]string{
+		"/sys/devices/system/node/node0/cpulist": "0-3",
+		"/proc/1/status":                         fullStatus,
+		"/proc/2/status":                         minimalStatus,
+	})
+	_, err := NewResources(fs, "/proc", "/sys", nil, []string{"1", "2"})
+	var mismatchErr *CPUSetMismatchError
+	if !errors.As(err, &mismatchErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mismatchErr.PID != "2" || !reflect.DeepEqual(mismatchErr.CPUs, []int{0, 1}) {
+		t.Errorf("unexpected error details: %#v", mismatchErr)
+	}
+}
+
+func TestCheckAlignmentViolations(t *testing.T) {
+	numaRes := Resources{
+		CPUToNUMANode: map[int]int{0: 0, 1: 0, 4: 1},
+		MemNUMANodes:  []int{1},
+		NUMANodes:     []int{0, 1},
+	}
+	res := numaRes.CheckAlignment()
+	expected := []Violation{
+		{
+			Reason:    ReasonSplitCPUs,
+			Resource:  ResourceCPUs,
+			NUMANodes: []int{0, 1},
+			Message:   "CPUs 0-1 on node 0, 4 on node 1",
+		},
+	}
+	if res.Aligned || !reflect.DeepEqual(res.Violations, expected) {
+		t.Errorf("got %v %+v expected %+v", res.Aligned, res.Violations, expected)
+	}
+
+	numaRes.CPUToNUMANode = map[int]int{0: 0, 1: 0}
+	res = numaRes.CheckAlignment()
+	if len(res.Violations) != 1 || res.Violations[0].String() != "[remote-memory] mems: memory allowed on nodes [1], CPUs on node 0" {
+		t.Errorf("unexpected violations: %+v", res.Violations)
+	}
+}
+
+// the page cache on node 1 is shared, and doesn't make the process misaligned
+var localNUMAMaps string = `55d4a7a00000 default file=/usr/bin/testpmd mapped=40 N1=40 kernelpagesize_kB=4
+55d4a8c00000 default heap anon=120 dirty=120 N0=120 kernelpagesize_kB=4
+7f1000000000 default file=/dev/hugepages/rtemap_0 huge dirty=1 N0=1 kernelpagesize_kB=1048576
+7ffd5a1e0000 default stack anon=9 dirty=9 N0=9 kernelpagesize_kB=4
+`
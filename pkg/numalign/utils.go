@@ -0,0 +1,251 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2020 Red Hat, Inc.
+ */
+
+package numalign
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+
+	cpuset "k8s.io/utils/cpuset"
+)
+
+func splitCPUList(cpuList string) ([]int, error) {
+	cpus, err := cpuset.Parse(cpuList)
+	if err != nil {
+		return nil, err
+	}
+	return cpus.List(), nil
+}
+
+func GetAllowedCPUList(fs fswrap.FSWrapper, statusFile string) ([]int, error) {
+	return getStatusList(fs, statusFile, "Cpus_allowed_list")
+}
+
+// GetAllowedMemList returns the NUMA nodes the process can allocate memory from.
+func GetAllowedMemList(fs fswrap.FSWrapper, statusFile string) ([]int, error) {
+	return getStatusList(fs, statusFile, "Mems_allowed_list")
+}
+
+func getStatusList(fs fswrap.FSWrapper, statusFile, key string) ([]int, error) {
+	var ids []int
+	var err error
+	content, err := fs.ReadFile(statusFile)
+	if err != nil {
+		return ids, err
+	}
+	lines := strings.Split(string(content), "\n")
+	for _, line := range lines {
+		if strings.HasPrefix(line, key) {
+			pair := strings.SplitN(line, ":", 2)
+			return splitCPUList(strings.TrimSpace(pair[1]))
+		}
+	}
+	return ids, fmt.Errorf("malformed status file: %s", statusFile)
+}
+
+// MemoryUsage is the memory used per NUMA node, in kB.
+type MemoryUsage struct {
+	// Anonymous is the anonymous memory, the page cache is shared and not accounted
+	Anonymous map[int]uint64 `json:"anonymous,omitempty"`
+	Hugepages map[int]uint64 `json:"hugepages,omitempty"`
+}
+
+func NewMemoryUsage() *MemoryUsage {
+	return &MemoryUsage{
+		Anonymous: make(map[int]uint64),
+		Hugepages: make(map[int]uint64),
+	}
+}
+
+// Nodes returns the NUMA nodes the memory comes from.
+func (mu *MemoryUsage) Nodes() []int {
+	nodes := cpuset.New(usedNodes(mu.Anonymous)...).Union(cpuset.New(usedNodes(mu.Hugepages)...))
+	return nodes.List()
+}
+
+func usedNodes(usage map[int]uint64) []int {
+	var nodes []int
+	for node, kb := range usage {
+		if kb > 0 {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Ints(nodes)
+	return nodes
+}
+
+// AddNUMAMaps accounts the memory found in the content of /proc/<pid>/numa_maps.
+// Only the hugetlb and the anonymous mappings are considered.
+func (mu *MemoryUsage) AddNUMAMaps(data string) error {
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		huge, anon, file := false, false, false
+		pageSizeKB := uint64(4)
+		pages := make(map[int]uint64)
+		for _, field := range fields[2:] {
+			if field == "huge" {
+				huge = true
+				continue
+			}
+			pair := strings.SplitN(field, "=", 2)
+			if len(pair) != 2 {
+				continue
+			}
+			switch {
+			case pair[0] == "anon":
+				anon = true
+			case pair[0] == "file":
+				file = true
+			case pair[0] == "kernelpagesize_kB":
+				val, err := strconv.ParseUint(pair[1], 10, 64)
+				if err != nil {
+					return fmt.Errorf("malformed numa_maps page size %q: %v", field, err)
+				}
+				pageSizeKB = val
+			case strings.HasPrefix(pair[0], "N"):
+				node, err := strconv.Atoi(pair[0][1:])
+				if err != nil {
+					return fmt.Errorf("malformed numa_maps node %q: %v", field, err)
+				}
+				val, err := strconv.ParseUint(pair[1], 10, 64)
+				if err != nil {
+					return fmt.Errorf("malformed numa_maps page count %q: %v", field, err)
+				}
+				pages[node] = val
+			}
+		}
+		var usage map[int]uint64
+		switch {
+		case huge:
+			usage = mu.Hugepages
+		case anon && !file:
+			usage = mu.Anonymous
+		default:
+			continue
+		}
+		for node, count := range pages {
+			usage[node] += count * pageSizeKB
+		}
+	}
+	return nil
+}
+
+func GetCPUToNUMANodeMap(fs fswrap.FSWrapper, sysNodeDir string, cpuIDs []int) (map[int]int, error) {
+	cpusPerNUMA, err := GetCPUsPerNUMANode(fs, sysNodeDir)
+	if err != nil {
+		return nil, err
+	}
+	CPUToNUMANode := MakeCPUsToNUMANodeMap(cpusPerNUMA)
+
+	// filter out only the allowed CPUs
+	CPUMap := make(map[int]int)
+	for _, cpuID := range cpuIDs {
+		_, ok := CPUToNUMANode[cpuID]
+		if !ok {
+			return nil, fmt.Errorf("CPU %d not found on NUMA map: %v", cpuID, CPUToNUMANode)
+		}
+		CPUMap[cpuID] = CPUToNUMANode[cpuID]
+	}
+	return CPUMap, nil
+}
+
+func GetPCIDeviceToNumaNodeMap(fs fswrap.FSWrapper, sysBusPCIDir string, pciDevs []string) (map[string]int, error) {
+	if len(pciDevs) == 0 {
+		log.Printf("PCI: devices: none found - SKIP")
+		return make(map[string]int), nil
+	}
+	log.Printf("PCI: devices: detected  %s", strings.Join(pciDevs, " - "))
+
+	NUMAPerDev, err := GetPCIDeviceNUMANode(fs, sysBusPCIDir, pciDevs)
+	if err != nil {
+		return nil, err
+	}
+	return NUMAPerDev, nil
+}
+
+func GetPCIDeviceNUMANode(fs fswrap.FSWrapper, sysPCIDir string, devs []string) (map[string]int, error) {
+	NUMAPerDev := make(map[string]int)
+	for _, dev := range devs {
+		content, err := fs.ReadFile(filepath.Join(sysPCIDir, dev, "numa_node"))
+		if err != nil {
+			return nil, err
+		}
+		numacellID, err := strconv.Atoi(strings.TrimSpace(string(content)))
+		if err != nil {
+			return nil, err
+		}
+		NUMAPerDev[dev] = numacellID
+	}
+	return NUMAPerDev, nil
+}
+
+func GetCPUsPerNUMANode(fs fswrap.FSWrapper, sysfsdir string) (map[int][]int, error) {
+	pattern := filepath.Join(sysfsdir, "node*")
+	nodes, err := fs.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	cpusPerNUMA := make(map[int][]int)
+	for _, node := range nodes {
+		_, nodeID := filepath.Split(node)
+		numacellID, err := strconv.Atoi(strings.TrimSpace(nodeID[4:]))
+		if err != nil {
+			return nil, err
+		}
+		content, err := fs.ReadFile(filepath.Join(node, "cpulist"))
+		if err != nil {
+			return nil, err
+		}
+		cpuSet, err := splitCPUList(strings.TrimSpace(string(content)))
+		if err != nil {
+			return nil, err
+		}
+		cpusPerNUMA[numacellID] = cpuSet
+	}
+	return cpusPerNUMA, nil
+}
+
+func GetPCIDevicesFromEnv(environ []string) []string {
+	var pciDevs []string
+	for _, envVar := range environ {
+		if !strings.HasPrefix(envVar, "PCIDEVICE_") {
+			continue
+		}
+		pair := strings.SplitN(envVar, "=", 2)
+		pciDevs = append(pciDevs, pair[1])
+	}
+	return pciDevs
+}
+
+func MakeCPUsToNUMANodeMap(cpusPerNUMA map[int][]int) map[int]int {
+	CPUToNUMANode := make(map[int]int)
+	for numacellID, cpuSet := range cpusPerNUMA {
+		for _, cpu := range cpuSet {
+			CPUToNUMANode[cpu] = numacellID
+		}
+	}
+	return CPUToNUMANode
+}
@@ -17,11 +17,10 @@
 package numalign
 
 import (
-	"fmt"
 	"reflect"
 	"testing"
 
-	"github.com/openshift-kni/debug-tools/internal/pkg/vfs"
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
 )
 
 func TestGetPCIDevicesFromEnv(t *testing.T) {
@@ -64,7 +63,7 @@ func TestGetPCIDevicesFromEnv(t *testing.T) {
 func TestGetPCIDeviceToNumaNodeMap(t *testing.T) {
 	type testCase struct {
 		name        string
-		content     map[string]vfs.ReadFileResult
+		content     map[string]string
 		pciDevs     []string
 		expectedMap map[string]int
 	}
@@ -72,20 +71,16 @@ func TestGetPCIDeviceToNumaNodeMap(t *testing.T) {
 	testCases := []testCase{
 		{
 			name: "no device",
-			content: map[string]vfs.ReadFileResult{
-				"/sys/bus/pci/devices/0000:00:1f.0/numa_node": vfs.ReadFileResult{
-					Data: []byte("0"),
-				},
+			content: map[string]string{
+				"/sys/bus/pci/devices/0000:00:1f.0/numa_node": "0",
 			},
 			pciDevs:     []string{},
 			expectedMap: map[string]int{},
 		},
 		{
 			name: "single device",
-			content: map[string]vfs.ReadFileResult{
-				"/sys/bus/pci/devices/0000:00:1f.0/numa_node": vfs.ReadFileResult{
-					Data: []byte("0"),
-				},
+			content: map[string]string{
+				"/sys/bus/pci/devices/0000:00:1f.0/numa_node": "0",
 			},
 			pciDevs: []string{"0000:00:1f.0"},
 			expectedMap: map[string]int{
@@ -96,9 +91,7 @@ func TestGetPCIDeviceToNumaNodeMap(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			fs := vfs.FakeFS{
-				FileContents: tc.content,
-			}
+			fs := fswrap.NewMemFS(tc.content)
 			devMap, err := GetPCIDeviceToNumaNodeMap(fs, "/sys/bus/pci/devices", tc.pciDevs)
 			if err != nil {
 				t.Errorf("unexpected error: %v", err)
@@ -113,25 +106,15 @@ func TestGetPCIDeviceToNumaNodeMap(t *testing.T) {
 func TestGetCPUsPerNUMANode(t *testing.T) {
 	type testCase struct {
 		name         string
-		glob         map[string]vfs.GlobResult
-		content      map[string]vfs.ReadFileResult
+		content      map[string]string
 		expectedCPUs map[int][]int
 	}
 
 	testCases := []testCase{
 		{
 			name: "single node",
-			glob: map[string]vfs.GlobResult{
-				"/sys/devices/system/node/node*": vfs.GlobResult{
-					Matches: []string{
-						"/sys/devices/system/node/node0",
-					},
-				},
-			},
-			content: map[string]vfs.ReadFileResult{
-				"/sys/devices/system/node/node0/cpulist": vfs.ReadFileResult{
-					Data: []byte("0-3"),
-				},
+			content: map[string]string{
+				"/sys/devices/system/node/node0/cpulist": "0-3",
 			},
 			expectedCPUs: map[int][]int{
 				0: []int{0, 1, 2, 3},
@@ -139,21 +122,9 @@ func TestGetCPUsPerNUMANode(t *testing.T) {
 		},
 		{
 			name: "dual node",
-			glob: map[string]vfs.GlobResult{
-				"/sys/devices/system/node/node*": vfs.GlobResult{
-					Matches: []string{
-						"/sys/devices/system/node/node0",
-						"/sys/devices/system/node/node1",
-					},
-				},
-			},
-			content: map[string]vfs.ReadFileResult{
-				"/sys/devices/system/node/node0/cpulist": vfs.ReadFileResult{
-					Data: []byte("0-3"),
-				},
-				"/sys/devices/system/node/node1/cpulist": vfs.ReadFileResult{
-					Data: []byte("4-7"),
-				},
+			content: map[string]string{
+				"/sys/devices/system/node/node0/cpulist": "0-3",
+				"/sys/devices/system/node/node1/cpulist": "4-7",
 			},
 			expectedCPUs: map[int][]int{
 				0: []int{0, 1, 2, 3},
@@ -164,10 +135,7 @@ func TestGetCPUsPerNUMANode(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			fs := vfs.FakeFS{
-				FileContents: tc.content,
-				GlobResults:  tc.glob,
-			}
+			fs := fswrap.NewMemFS(tc.content)
 			cpusPerNuma, err := GetCPUsPerNUMANode(fs, "/sys/devices/system/node")
 			if err != nil {
 				t.Errorf("unexpected error: %v", err)
@@ -182,8 +150,7 @@ func TestGetCPUsPerNUMANode(t *testing.T) {
 func TestGetCPUToNUMANodeMap(t *testing.T) {
 	type testCase struct {
 		name        string
-		glob        map[string]vfs.GlobResult
-		content     map[string]vfs.ReadFileResult
+		content     map[string]string
 		allowedCPUs []int
 		expectedMap map[int]int
 	}
@@ -191,21 +158,9 @@ func TestGetCPUToNUMANodeMap(t *testing.T) {
 	testCases := []testCase{
 		{
 			name: "node aligned",
-			glob: map[string]vfs.GlobResult{
-				"/sys/devices/system/node/node*": vfs.GlobResult{
-					Matches: []string{
-						"/sys/devices/system/node/node0",
-						"/sys/devices/system/node/node1",
-					},
-				},
-			},
-			content: map[string]vfs.ReadFileResult{
-				"/sys/devices/system/node/node0/cpulist": vfs.ReadFileResult{
-					Data: []byte("0-3"),
-				},
-				"/sys/devices/system/node/node1/cpulist": vfs.ReadFileResult{
-					Data: []byte("4-7"),
-				},
+			content: map[string]string{
+				"/sys/devices/system/node/node0/cpulist": "0-3",
+				"/sys/devices/system/node/node1/cpulist": "4-7",
 			},
 			allowedCPUs: []int{1, 2},
 			expectedMap: map[int]int{
@@ -217,10 +172,7 @@ func TestGetCPUToNUMANodeMap(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			fs := vfs.FakeFS{
-				FileContents: tc.content,
-				GlobResults:  tc.glob,
-			}
+			fs := fswrap.NewMemFS(tc.content)
 			cpuMap, err := GetCPUToNUMANodeMap(fs, "/sys/devices/system/node", tc.allowedCPUs)
 			if err != nil {
 				t.Errorf("unexpected error: %v", err)
@@ -238,32 +190,26 @@ func TestGetAllowedCPUList(t *testing.T) {
 		status       string
 		expectedCPUs []int
 		wantError    bool
+		// missing makes reading the status file fail
+		missing bool
 	}
 	testCases := []testCase{
-		{"empty", "", nil, true},
-		{"minimal", minimalStatus, []int{0, 1}, false},
-		{"full", fullStatus, []int{0, 1, 2, 3}, false},
-		{"gibberish", gibberishStatus, nil, true},
-		{"malformed", malformedStatus, nil, true},
-	}
-
-	testCaseToReadFileResult := func(tc testCase) vfs.ReadFileResult {
-		ret := vfs.ReadFileResult{
-			Data: []byte(tc.status),
-		}
-		if tc.wantError {
-			ret.Err = fmt.Errorf("fake generic error")
-		}
-		return ret
+		{"empty", "", nil, true, false},
+		{"minimal", minimalStatus, []int{0, 1}, false, false},
+		{"full", fullStatus, []int{0, 1, 2, 3}, false, false},
+		{"gibberish", gibberishStatus, nil, true, false},
+		{"malformed", malformedStatus, nil, true, false},
+		{"missing", fullStatus, nil, true, true},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			filename := "/proc/self/status"
-			fs := vfs.FakeFS{
-				FileContents: map[string]vfs.ReadFileResult{
-					filename: testCaseToReadFileResult(tc),
-				},
+			fs := fswrap.NewMemFS(map[string]string{
+				filename: tc.status,
+			})
+			if tc.missing {
+				filename = "/proc/1/status"
 			}
 			cpus, err := GetAllowedCPUList(fs, filename)
 			if err == nil && tc.wantError {
@@ -348,3 +294,24 @@ Mems_allowed:	00000000,00000000,00000000,00000000,00000000,00000000,00000000,000
 Mems_allowed_list:	0
 voluntary_ctxt_switches:	1
 nonvoluntary_ctxt_switches:	0`
+
+func TestMemoryUsage(t *testing.T) {
+	usage := NewMemoryUsage()
+	if err := usage.AddNUMAMaps(localNUMAMaps); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &MemoryUsage{
+		Anonymous: map[int]uint64{0: 516},
+		Hugepages: map[int]uint64{0: 1048576},
+	}
+	if !reflect.DeepEqual(usage, expected) {
+		t.Errorf("got %#v expected %#v", usage, expected)
+	}
+	if nodes := usage.Nodes(); !reflect.DeepEqual(nodes, []int{0}) {
+		t.Errorf("unexpected nodes: %v", nodes)
+	}
+
+	if err := usage.AddNUMAMaps("7f0000000000 default anon=1 Nx=1\n"); err == nil {
+		t.Errorf("expected error, got none")
+	}
+}
@@ -0,0 +1,281 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package pinning
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/procs"
+)
+
+// ContainerRef identifies a container the way the podresources API does.
+type ContainerRef struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container,omitempty"`
+}
+
+func (cr ContainerRef) String() string {
+	if cr.Container == "" {
+		return cr.Namespace + "/" + cr.Pod
+	}
+	return cr.Namespace + "/" + cr.Pod + "/" + cr.Container
+}
+
+// Allocation is the set of exclusive cpus assigned to a container.
+type Allocation struct {
+	ContainerRef
+	CPUs cpuset.CPUSet
+}
+
+type OwnerKind string
+
+const (
+	// OwnerContainer is an application container
+	OwnerContainer OwnerKind = "container"
+	// OwnerInfra is a pod-level process which does not belong to any application container,
+	// like the pause container or the crio conmon.
+	OwnerInfra OwnerKind = "infra"
+	// OwnerHost is a process running outside any pod.
+	OwnerHost OwnerKind = "host"
+	// OwnerKernel is a kernel thread.
+	OwnerKernel OwnerKind = "kernel"
+)
+
+// Owner tells what a process belongs to.
+type Owner struct {
+	Kind OwnerKind    `json:"kind"`
+	Ref  ContainerRef `json:"ref,omitempty"`
+}
+
+func (ow Owner) String() string {
+	switch ow.Kind {
+	case OwnerContainer:
+		return ow.Ref.String()
+	case OwnerInfra:
+		return "infra:" + ow.Ref.String()
+	}
+	return string(ow.Kind)
+}
+
+// Resolver maps the cgroup membership of processes to the pods and containers running on the node.
+type Resolver struct {
+	containers map[string]ContainerRef // container ID -> container
+	pods       map[string]ContainerRef // pod UID -> pod
+}
+
+// NewResolverFromPods creates a Resolver from the pod objects, as returned by the apiserver.
+func NewResolverFromPods(pods []corev1.Pod) *Resolver {
+	res := &Resolver{
+		containers: make(map[string]ContainerRef),
+		pods:       make(map[string]ContainerRef),
+	}
+	for _, pod := range pods {
+		podRef := ContainerRef{
+			Namespace: pod.Namespace,
+			Pod:       pod.Name,
+		}
+		res.pods[string(pod.UID)] = podRef
+		statuses := append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+		statuses = append(statuses, pod.Status.ContainerStatuses...)
+		for _, cs := range statuses {
+			if cs.ContainerID == "" {
+				continue
+			}
+			cntRef := podRef
+			cntRef.Container = cs.Name
+			res.containers[trimRuntimeScheme(cs.ContainerID)] = cntRef
+		}
+	}
+	return res
+}
+
+// ParsePodList parses a PodList, as produced by `kubectl get pods -o json`.
+func ParsePodList(data []byte) ([]corev1.Pod, error) {
+	var podList corev1.PodList
+	if err := json.Unmarshal(data, &podList); err != nil {
+		return nil, err
+	}
+	return podList.Items, nil
+}
+
+// Owner resolves the owner of a process, given its name and cgroup membership.
+func (res *Resolver) Owner(name string, cgInfo procs.CgroupInfo) Owner {
+	if cgInfo.ContainerID != "" && !cgInfo.Infra {
+		if ref, ok := res.containers[cgInfo.ContainerID]; ok {
+			return Owner{Kind: OwnerContainer, Ref: ref}
+		}
+	}
+	if cgInfo.PodUID != "" || cgInfo.ContainerID != "" {
+		ref := ContainerRef{Namespace: "?", Pod: cgInfo.PodUID}
+		if podRef, ok := res.pods[cgInfo.PodUID]; ok {
+			ref = podRef
+		} else if cntRef, ok := res.containers[cgInfo.ContainerID]; ok {
+			ref = cntRef
+		}
+		return Owner{Kind: OwnerInfra, Ref: ref}
+	}
+	// kernel threads have no cmdline, so no name, and live in the root cgroup
+	if name == "" && (cgInfo.Path == "" || cgInfo.Path == "/") {
+		return Owner{Kind: OwnerKernel}
+	}
+	return Owner{Kind: OwnerHost}
+}
+
+type ViolationKind string
+
+const (
+	// ViolationMismatch is a container thread allowed to run outside the container allocation.
+	ViolationMismatch ViolationKind = "mismatch"
+	// ViolationForeign is a thread allowed to run on exclusive cpus allocated to other containers.
+	ViolationForeign ViolationKind = "foreign"
+)
+
+type Violation struct {
+	Kind        ViolationKind `json:"kind"`
+	PID         int           `json:"pid"`
+	TID         int           `json:"tid"`
+	ProcessName string        `json:"process"`
+	ThreadName  string        `json:"thread"`
+	Owner       Owner         `json:"owner"`
+	Affinity    []int         `json:"affinity"`
+	// Expected is the allocation of the owning container, set for mismatches.
+	Expected []int `json:"expected,omitempty"`
+	// Intruded are the exclusive cpus the thread is allowed onto, set for foreign threads.
+	Intruded []int `json:"intruded,omitempty"`
+	// Victims are the containers owning the intruded cpus, set for foreign threads.
+	Victims []string `json:"victims,omitempty"`
+}
+
+func (vi Violation) String() string {
+	desc := fmt.Sprintf("%-8s PID %6d (%-16s) TID %6d (%-16s) owner %-40s can run on %v", vi.Kind, vi.PID, vi.ProcessName, vi.TID, vi.ThreadName, vi.Owner.String(), vi.Affinity)
+	if vi.Kind == ViolationMismatch {
+		return desc + fmt.Sprintf(" expected within %v", vi.Expected)
+	}
+	return desc + fmt.Sprintf(" intruding %v of %s", vi.Intruded, strings.Join(vi.Victims, ","))
+}
+
+type Options struct {
+	// IncludePerCPUKthreads reports also the kernel threads bound to a single cpu, like
+	// ksoftirqd/N or kworker/N, which the kernel runs on every cpu by design.
+	IncludePerCPUKthreads bool
+}
+
+// Process is a process to check, along with its owner.
+type Process struct {
+	Info  procs.PIDInfo
+	Owner Owner
+}
+
+// Check reports the threads allowed to run outside the exclusive allocation of their container,
+// and the threads allowed onto exclusive cpus allocated to other containers.
+// Threads of containers with an exclusive allocation may run on a subset of it, like
+// DPDK applications pinning their threads on individual cpus.
+func Check(allocs []Allocation, processes []Process, opts Options) []Violation {
+	exclusive := cpuset.New()
+	allocByRef := make(map[ContainerRef]cpuset.CPUSet)
+	ownerOfCPU := make(map[int]string)
+	for _, alloc := range allocs {
+		exclusive = exclusive.Union(alloc.CPUs)
+		allocByRef[alloc.ContainerRef] = alloc.CPUs
+		for _, cpu := range alloc.CPUs.List() {
+			ownerOfCPU[cpu] = alloc.ContainerRef.String()
+		}
+	}
+
+	sort.Slice(processes, func(i, j int) bool {
+		return processes[i].Info.Pid < processes[j].Info.Pid
+	})
+
+	var violations []Violation
+	for _, proc := range processes {
+		ownCPUs, hasAlloc := cpuset.New(), false
+		if proc.Owner.Kind == OwnerContainer {
+			ownCPUs, hasAlloc = allocByRef[proc.Owner.Ref]
+		}
+
+		var tids []int
+		for tid := range proc.Info.TIDs {
+			tids = append(tids, tid)
+		}
+		sort.Ints(tids)
+
+		for _, tid := range tids {
+			tidInfo := proc.Info.TIDs[tid]
+			affinity := cpuset.New(tidInfo.Affinity...)
+			vi := Violation{
+				PID:         proc.Info.Pid,
+				TID:         tid,
+				ProcessName: proc.Info.Name,
+				ThreadName:  tidInfo.Name,
+				Owner:       proc.Owner,
+				Affinity:    tidInfo.Affinity,
+			}
+
+			if hasAlloc {
+				if !affinity.IsSubsetOf(ownCPUs) {
+					vi.Kind = ViolationMismatch
+					vi.Expected = ownCPUs.List()
+					violations = append(violations, vi)
+				}
+				continue
+			}
+
+			if proc.Owner.Kind == OwnerKernel && affinity.Size() == 1 && !opts.IncludePerCPUKthreads {
+				continue
+			}
+			intruded := affinity.Intersection(exclusive)
+			if intruded.IsEmpty() {
+				continue
+			}
+			vi.Kind = ViolationForeign
+			vi.Intruded = intruded.List()
+			vi.Victims = victimsOf(intruded, ownerOfCPU)
+			violations = append(violations, vi)
+		}
+	}
+	return violations
+}
+
+func victimsOf(cpus cpuset.CPUSet, ownerOfCPU map[int]string) []string {
+	seen := make(map[string]bool)
+	var victims []string
+	for _, cpu := range cpus.List() {
+		owner := ownerOfCPU[cpu]
+		if seen[owner] {
+			continue
+		}
+		seen[owner] = true
+		victims = append(victims, owner)
+	}
+	sort.Strings(victims)
+	return victims
+}
+
+// trimRuntimeScheme removes the runtime prefix from the container IDs reported in the pod status, like "cri-o://".
+func trimRuntimeScheme(containerID string) string {
+	if idx := strings.Index(containerID, "://"); idx >= 0 {
+		return containerID[idx+3:]
+	}
+	return containerID
+}
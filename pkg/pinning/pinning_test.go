@@ -0,0 +1,174 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package pinning_test
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/pinning"
+	"github.com/openshift-kni/debug-tools/pkg/procs"
+)
+
+const (
+	dpdkPodUID      = "8a2b6a4e-7c41-4b5e-a1f3-2d9e6c0b7f15"
+	dpdkContainerID = "4f1c3e8b5a0d9c7e6b2a1f0e9d8c7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c"
+	pauseID         = "0000000000000000000000000000000000000000000000000000000000000001"
+	sharedPodUID    = "11111111-2222-3333-4444-555555555555"
+	sharedID        = "0000000000000000000000000000000000000000000000000000000000000002"
+)
+
+var fakePods = []corev1.Pod{
+	{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "cnf", Name: "dpdk", UID: types.UID(dpdkPodUID)},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "app", ContainerID: "cri-o://" + dpdkContainerID},
+			},
+		},
+	},
+	{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: types.UID(sharedPodUID)},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "nginx", ContainerID: "cri-o://" + sharedID},
+			},
+		},
+	},
+}
+
+func TestResolverOwner(t *testing.T) {
+	res := pinning.NewResolverFromPods(fakePods)
+	testCases := []struct {
+		name     string
+		procName string
+		cgInfo   procs.CgroupInfo
+		expected pinning.Owner
+	}{
+		{
+			name:     "app container",
+			procName: "testpmd",
+			cgInfo:   procs.CgroupInfo{ContainerID: dpdkContainerID, PodUID: dpdkPodUID},
+			expected: pinning.Owner{Kind: pinning.OwnerContainer, Ref: pinning.ContainerRef{Namespace: "cnf", Pod: "dpdk", Container: "app"}},
+		},
+		{
+			name:     "pause container",
+			procName: "pause",
+			cgInfo:   procs.CgroupInfo{ContainerID: pauseID, PodUID: dpdkPodUID},
+			expected: pinning.Owner{Kind: pinning.OwnerInfra, Ref: pinning.ContainerRef{Namespace: "cnf", Pod: "dpdk"}},
+		},
+		{
+			name:     "conmon",
+			procName: "conmon",
+			cgInfo:   procs.CgroupInfo{ContainerID: dpdkContainerID, PodUID: dpdkPodUID, Infra: true},
+			expected: pinning.Owner{Kind: pinning.OwnerInfra, Ref: pinning.ContainerRef{Namespace: "cnf", Pod: "dpdk"}},
+		},
+		{
+			name:     "host process",
+			procName: "sshd",
+			cgInfo:   procs.CgroupInfo{Path: "/system.slice/sshd.service"},
+			expected: pinning.Owner{Kind: pinning.OwnerHost},
+		},
+		{
+			name:     "kernel thread",
+			cgInfo:   procs.CgroupInfo{Path: "/"},
+			expected: pinning.Owner{Kind: pinning.OwnerKernel},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := res.Owner(tc.procName, tc.cgInfo)
+			if got != tc.expected {
+				t.Errorf("owner mismatch got %+v expected %+v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dpdkRef := pinning.ContainerRef{Namespace: "cnf", Pod: "dpdk", Container: "app"}
+	webRef := pinning.ContainerRef{Namespace: "default", Pod: "web", Container: "nginx"}
+	allocs := []pinning.Allocation{
+		{ContainerRef: dpdkRef, CPUs: cpuset.New(4, 5, 6, 7)},
+	}
+	processes := []pinning.Process{
+		{
+			Info: procs.PIDInfo{Pid: 100, Name: "testpmd", TIDs: map[int]procs.TIDInfo{
+				100: {Tid: 100, Name: "testpmd", Affinity: []int{4, 5, 6, 7}},
+				101: {Tid: 101, Name: "lcore-worker-5", Affinity: []int{5}},
+				102: {Tid: 102, Name: "eal-intr-thread", Affinity: []int{0, 1, 2, 3, 4, 5, 6, 7}},
+			}},
+			Owner: pinning.Owner{Kind: pinning.OwnerContainer, Ref: dpdkRef},
+		},
+		{
+			Info: procs.PIDInfo{Pid: 200, Name: "nginx", TIDs: map[int]procs.TIDInfo{
+				200: {Tid: 200, Name: "nginx", Affinity: []int{0, 1, 2, 3}},
+			}},
+			Owner: pinning.Owner{Kind: pinning.OwnerContainer, Ref: webRef},
+		},
+		{
+			Info: procs.PIDInfo{Pid: 300, Name: "irqbalance", TIDs: map[int]procs.TIDInfo{
+				300: {Tid: 300, Name: "irqbalance", Affinity: []int{0, 1, 2, 3, 4, 5, 6, 7}},
+			}},
+			Owner: pinning.Owner{Kind: pinning.OwnerHost},
+		},
+		{
+			Info: procs.PIDInfo{Pid: 40, TIDs: map[int]procs.TIDInfo{
+				40: {Tid: 40, Name: "ksoftirqd/5", Affinity: []int{5}},
+			}},
+			Owner: pinning.Owner{Kind: pinning.OwnerKernel},
+		},
+	}
+
+	violations := pinning.Check(allocs, processes, pinning.Options{})
+	expected := []pinning.Violation{
+		{
+			Kind:        pinning.ViolationMismatch,
+			PID:         100,
+			TID:         102,
+			ProcessName: "testpmd",
+			ThreadName:  "eal-intr-thread",
+			Owner:       pinning.Owner{Kind: pinning.OwnerContainer, Ref: dpdkRef},
+			Affinity:    []int{0, 1, 2, 3, 4, 5, 6, 7},
+			Expected:    []int{4, 5, 6, 7},
+		},
+		{
+			Kind:        pinning.ViolationForeign,
+			PID:         300,
+			TID:         300,
+			ProcessName: "irqbalance",
+			ThreadName:  "irqbalance",
+			Owner:       pinning.Owner{Kind: pinning.OwnerHost},
+			Affinity:    []int{0, 1, 2, 3, 4, 5, 6, 7},
+			Intruded:    []int{4, 5, 6, 7},
+			Victims:     []string{"cnf/dpdk/app"},
+		},
+	}
+	if !reflect.DeepEqual(violations, expected) {
+		t.Errorf("violations mismatch\ngot      %+v\nexpected %+v", violations, expected)
+	}
+
+	violations = pinning.Check(allocs, processes, pinning.Options{IncludePerCPUKthreads: true})
+	if len(violations) != 3 || violations[0].PID != 40 {
+		t.Errorf("expected the per-cpu kthread to be reported, got %+v", violations)
+	}
+}
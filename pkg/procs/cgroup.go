@@ -0,0 +1,131 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package procs
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// CgroupEntry is a line of /proc/<pid>/cgroup, see man 7 cgroups.
+// On cgroup v2, the only entry has HierarchyID 0 and no controllers.
+type CgroupEntry struct {
+	HierarchyID string
+	Controllers []string
+	Path        string
+}
+
+// CgroupInfo is the container membership of a process, inferred from its cgroup.
+type CgroupInfo struct {
+	Path        string `json:"path,omitempty"`
+	ContainerID string `json:"containerID,omitempty"`
+	PodUID      string `json:"podUID,omitempty"`
+	// Infra is set for the per-container infra processes, like the crio conmon.
+	Infra bool `json:"infra,omitempty"`
+}
+
+var (
+	// crio-<id>.scope, crio-conmon-<id>.scope, cri-containerd-<id>.scope, docker-<id>.scope (systemd driver)
+	// or just <id> (cgroupfs driver)
+	containerScopeRE = regexp.MustCompile(`^(?:(crio-conmon|crio|cri-containerd|docker)-)?([0-9a-f]{64})(?:\.scope)?$`)
+	// kubepods-burstable-pod<uid>.slice (systemd driver, with '_' instead of '-') or pod<uid> (cgroupfs driver)
+	podSliceRE = regexp.MustCompile(`^(?:kubepods(?:-[a-z]+)?-)?pod([0-9a-f]{8}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{12})(?:\.slice)?$`)
+)
+
+// ParseCgroup parses the content of /proc/<pid>/cgroup. Malformed lines are skipped.
+func ParseCgroup(data string) []CgroupEntry {
+	var entries []CgroupEntry
+	for _, line := range strings.Split(data, "\n") {
+		items := strings.SplitN(strings.TrimSpace(line), ":", 3)
+		if len(items) != 3 {
+			continue
+		}
+		entry := CgroupEntry{
+			HierarchyID: items[0],
+			Path:        items[2],
+		}
+		if items[1] != "" {
+			entry.Controllers = strings.Split(items[1], ",")
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// CgroupInfoFromEntries infers the container membership from the cgroup entries. Works with cgroup v1,
+// v2 and hybrid setups: the v2 hierarchy is preferred, then the v1 cpuset controller, then any other.
+func CgroupInfoFromEntries(entries []CgroupEntry) CgroupInfo {
+	var candidates []CgroupEntry
+	for _, entry := range entries {
+		if entry.HierarchyID == "0" && len(entry.Controllers) == 0 {
+			candidates = append(candidates, entry)
+		}
+	}
+	for _, entry := range entries {
+		if hasController(entry, "cpuset") {
+			candidates = append(candidates, entry)
+		}
+	}
+	candidates = append(candidates, entries...)
+
+	for _, entry := range candidates {
+		info := CgroupInfoFromPath(entry.Path)
+		if info.ContainerID != "" || info.PodUID != "" {
+			return info
+		}
+	}
+	if len(candidates) > 0 {
+		return CgroupInfo{Path: candidates[0].Path}
+	}
+	return CgroupInfo{}
+}
+
+// CgroupInfoFromPath extracts the container ID and the pod UID from a cgroup path,
+// supporting both the systemd and the cgroupfs kubelet cgroup drivers.
+func CgroupInfoFromPath(path string) CgroupInfo {
+	info := CgroupInfo{Path: path}
+	for _, component := range strings.Split(filepath.Clean(path), "/") {
+		if match := podSliceRE.FindStringSubmatch(component); match != nil {
+			info.PodUID = strings.ReplaceAll(match[1], "_", "-")
+			continue
+		}
+		if match := containerScopeRE.FindStringSubmatch(component); match != nil {
+			info.ContainerID = match[2]
+			info.Infra = match[1] == "crio-conmon"
+		}
+	}
+	return info
+}
+
+func hasController(entry CgroupEntry, name string) bool {
+	for _, controller := range entry.Controllers {
+		if controller == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadCgroup reads the container membership of the given process.
+func (handler *Handler) ReadCgroup(pid int) (CgroupInfo, error) {
+	data, err := handler.fs.ReadFile(filepath.Join(handler.procfsRoot, procEntry(pid), "cgroup"))
+	if err != nil {
+		return CgroupInfo{}, err
+	}
+	return CgroupInfoFromEntries(ParseCgroup(string(data))), nil
+}
@@ -0,0 +1,116 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package procs_test
+
+import (
+	"testing"
+
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+	"github.com/openshift-kni/debug-tools/pkg/procs"
+)
+
+const (
+	fakeContainerID = "4f1c3e8b5a0d9c7e6b2a1f0e9d8c7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c"
+	fakePodUID      = "8a2b6a4e-7c41-4b5e-a1f3-2d9e6c0b7f15"
+)
+
+func TestCgroupInfo(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		expected procs.CgroupInfo
+	}{
+		{
+			name: "v2 crio systemd",
+			data: "0::/kubepods.slice/kubepods-pod8a2b6a4e_7c41_4b5e_a1f3_2d9e6c0b7f15.slice/crio-" + fakeContainerID + ".scope\n",
+			expected: procs.CgroupInfo{
+				Path:        "/kubepods.slice/kubepods-pod8a2b6a4e_7c41_4b5e_a1f3_2d9e6c0b7f15.slice/crio-" + fakeContainerID + ".scope",
+				ContainerID: fakeContainerID,
+				PodUID:      fakePodUID,
+			},
+		},
+		{
+			name: "v2 crio conmon",
+			data: "0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod8a2b6a4e_7c41_4b5e_a1f3_2d9e6c0b7f15.slice/crio-conmon-" + fakeContainerID + ".scope\n",
+			expected: procs.CgroupInfo{
+				Path:        "/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod8a2b6a4e_7c41_4b5e_a1f3_2d9e6c0b7f15.slice/crio-conmon-" + fakeContainerID + ".scope",
+				ContainerID: fakeContainerID,
+				PodUID:      fakePodUID,
+				Infra:       true,
+			},
+		},
+		{
+			name: "v1 containerd cgroupfs",
+			data: "12:pids:/kubepods/besteffort/pod" + fakePodUID + "/" + fakeContainerID + "\n" +
+				"11:cpuset:/kubepods/besteffort/pod" + fakePodUID + "/" + fakeContainerID + "\n" +
+				"1:name=systemd:/kubepods/besteffort/pod" + fakePodUID + "/" + fakeContainerID + "\n",
+			expected: procs.CgroupInfo{
+				Path:        "/kubepods/besteffort/pod" + fakePodUID + "/" + fakeContainerID,
+				ContainerID: fakeContainerID,
+				PodUID:      fakePodUID,
+			},
+		},
+		{
+			name: "hybrid containerd systemd",
+			data: "3:cpuset:/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod8a2b6a4e_7c41_4b5e_a1f3_2d9e6c0b7f15.slice/cri-containerd-" + fakeContainerID + ".scope\n" +
+				"0::/\n",
+			expected: procs.CgroupInfo{
+				Path:        "/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod8a2b6a4e_7c41_4b5e_a1f3_2d9e6c0b7f15.slice/cri-containerd-" + fakeContainerID + ".scope",
+				ContainerID: fakeContainerID,
+				PodUID:      fakePodUID,
+			},
+		},
+		{
+			name: "host process",
+			data: "0::/system.slice/sshd.service\n",
+			expected: procs.CgroupInfo{
+				Path: "/system.slice/sshd.service",
+			},
+		},
+		{
+			name:     "malformed",
+			data:     "garbage\n",
+			expected: procs.CgroupInfo{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := procs.CgroupInfoFromEntries(procs.ParseCgroup(tc.data))
+			if got != tc.expected {
+				t.Errorf("cgroup info mismatch got %+v expected %+v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestReadCgroup(t *testing.T) {
+	fsys := fswrap.NewMemFS(map[string]string{
+		"/proc/42/cgroup": "0::/kubepods.slice/kubepods-pod8a2b6a4e_7c41_4b5e_a1f3_2d9e6c0b7f15.slice/crio-" + fakeContainerID + ".scope\n",
+	})
+	ph := procs.NewWithFS(nullLog, fsys, "/proc")
+	info, err := ph.ReadCgroup(42)
+	if err != nil {
+		t.Fatalf("ReadCgroup failed: %v", err)
+	}
+	if info.ContainerID != fakeContainerID || info.PodUID != fakePodUID {
+		t.Errorf("unexpected cgroup info: %+v", info)
+	}
+	if _, err := ph.ReadCgroup(43); err == nil {
+		t.Errorf("ReadCgroup succeeded on a missing process")
+	}
+}
@@ -0,0 +1,114 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package procs
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CPUTime is the time a cpu spent in each state, in USER_HZ ticks, as reported by /proc/stat.
+type CPUTime struct {
+	User    uint64 `json:"user"`
+	Nice    uint64 `json:"nice"`
+	System  uint64 `json:"system"`
+	Idle    uint64 `json:"idle"`
+	IOWait  uint64 `json:"iowait"`
+	IRQ     uint64 `json:"irq"`
+	SoftIRQ uint64 `json:"softirq"`
+	Steal   uint64 `json:"steal"`
+}
+
+// Total is the sum of all the states. The guest time is already accounted as user time.
+func (ct CPUTime) Total() uint64 {
+	return ct.User + ct.Nice + ct.System + ct.Idle + ct.IOWait + ct.IRQ + ct.SoftIRQ + ct.Steal
+}
+
+// cpu id -> times
+type CPUTimes map[int]CPUTime
+
+// assume X is fresher than S. The times can go down, like iowait (see proc(5)) or on a cpu
+// brought back online: such states are accounted as 0.
+func (S CPUTimes) Delta(X CPUTimes) CPUTimes {
+	R := make(CPUTimes)
+	for cpuid, last := range X {
+		prev := S[cpuid]
+		R[cpuid] = CPUTime{
+			User:    tickDelta(prev.User, last.User),
+			Nice:    tickDelta(prev.Nice, last.Nice),
+			System:  tickDelta(prev.System, last.System),
+			Idle:    tickDelta(prev.Idle, last.Idle),
+			IOWait:  tickDelta(prev.IOWait, last.IOWait),
+			IRQ:     tickDelta(prev.IRQ, last.IRQ),
+			SoftIRQ: tickDelta(prev.SoftIRQ, last.SoftIRQ),
+			Steal:   tickDelta(prev.Steal, last.Steal),
+		}
+	}
+	return R
+}
+
+func tickDelta(prev, last uint64) uint64 {
+	if last < prev {
+		return 0
+	}
+	return last - prev
+}
+
+// ParseCPUTimes extracts the per-cpu times from the content of /proc/stat. The aggregated "cpu" line is skipped.
+func ParseCPUTimes(data string) (CPUTimes, error) {
+	times := make(CPUTimes)
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || !strings.HasPrefix(fields[0], "cpu") || fields[0] == "cpu" {
+			continue
+		}
+		cpuid, err := strconv.Atoi(strings.TrimPrefix(fields[0], "cpu"))
+		if err != nil {
+			return nil, fmt.Errorf("malformed cpu name %q: %v", fields[0], err)
+		}
+		// user nice system idle iowait irq softirq steal, older kernels may report less
+		var vals [8]uint64
+		for idx := 0; idx < len(vals) && idx+1 < len(fields); idx++ {
+			vals[idx], err = strconv.ParseUint(fields[idx+1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("malformed times for %q: %v", fields[0], err)
+			}
+		}
+		times[cpuid] = CPUTime{
+			User:    vals[0],
+			Nice:    vals[1],
+			System:  vals[2],
+			Idle:    vals[3],
+			IOWait:  vals[4],
+			IRQ:     vals[5],
+			SoftIRQ: vals[6],
+			Steal:   vals[7],
+		}
+	}
+	return times, nil
+}
+
+// ReadCPUTimes reads the per-cpu times from /proc/stat.
+func (handler *Handler) ReadCPUTimes() (CPUTimes, error) {
+	data, err := handler.fs.ReadFile(filepath.Join(handler.procfsRoot, "stat"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading stat from %q: %v", handler.procfsRoot, err)
+	}
+	return ParseCPUTimes(string(data))
+}
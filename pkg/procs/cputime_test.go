@@ -0,0 +1,65 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package procs_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openshift-kni/debug-tools/pkg/procs"
+)
+
+func TestParseCPUTimes(t *testing.T) {
+	data := `cpu  1000 10 500 90000 20 30 40 5 0 0
+cpu0 600 10 300 40000 10 25 30 5 0 0
+cpu3 400 0 200 50000 10 5 10 0 0 0
+intr 12345 0 0
+ctxt 987654
+`
+	times, err := procs.ParseCPUTimes(data)
+	if err != nil {
+		t.Fatalf("ParseCPUTimes failed: %v", err)
+	}
+	expected := procs.CPUTimes{
+		0: {User: 600, Nice: 10, System: 300, Idle: 40000, IOWait: 10, IRQ: 25, SoftIRQ: 30, Steal: 5},
+		3: {User: 400, System: 200, Idle: 50000, IOWait: 10, IRQ: 5, SoftIRQ: 10},
+	}
+	if !reflect.DeepEqual(times, expected) {
+		t.Errorf("times mismatch got %+v expected %+v", times, expected)
+	}
+
+	last := procs.CPUTimes{
+		0: {User: 700, Nice: 10, System: 300, Idle: 40090, IOWait: 10, IRQ: 30, SoftIRQ: 33, Steal: 7},
+	}
+	delta := times.Delta(last)
+	if got := delta[0]; got.Total() != 200 || got.IRQ != 5 || got.SoftIRQ != 3 || got.Steal != 2 {
+		t.Errorf("unexpected delta %+v", got)
+	}
+
+	// iowait can go down
+	last = procs.CPUTimes{
+		3: {User: 500, System: 200, Idle: 50100, IOWait: 4, IRQ: 5, SoftIRQ: 10},
+	}
+	delta = times.Delta(last)
+	if got := delta[3]; got.Total() != 200 || got.IOWait != 0 {
+		t.Errorf("unexpected delta %+v", got)
+	}
+
+	if _, err := procs.ParseCPUTimes("cpux 1 2 3\n"); err == nil {
+		t.Errorf("ParseCPUTimes succeeded on malformed data")
+	}
+}
@@ -33,12 +33,20 @@ type TIDInfo struct {
 	Tid      int    `json:"tid"`
 	Name     string `json:"name"`
 	Affinity []int  `json:"affinity"`
+	// scheduling setting, see SchedInfo
+	Policy     SchedPolicy `json:"policy,omitempty"`
+	RTPriority int         `json:"rtPriority,omitempty"`
+	Nice       int         `json:"nice,omitempty"`
 }
 
 type PIDInfo struct {
 	Pid  int             `json:"pid"`
 	Name string          `json:"name"`
 	TIDs map[int]TIDInfo `json:"threads"`
+	// cgroup membership, see CgroupInfo
+	CgroupPath  string `json:"cgroupPath,omitempty"`
+	ContainerID string `json:"containerID,omitempty"`
+	PodUID      string `json:"podUID,omitempty"`
 }
 
 type Handler struct {
@@ -48,10 +56,15 @@ type Handler struct {
 }
 
 func New(logger *log.Logger, procfsRoot string) *Handler {
+	return NewWithFS(logger, fswrap.LinuxFS{Log: logger}, procfsRoot)
+}
+
+// NewWithFS creates a Handler which reads the procfs content, rooted at procfsRoot, from fsys.
+func NewWithFS(logger *log.Logger, fsys fswrap.FSWrapper, procfsRoot string) *Handler {
 	return &Handler{
 		log:        logger,
 		procfsRoot: procfsRoot,
-		fs:         fswrap.FSWrapper{Log: logger},
+		fs:         fsys,
 	}
 }
 
@@ -97,6 +110,16 @@ func (handler *Handler) FromPID(pid int) (PIDInfo, error) {
 		handler.log.Printf("Error reading process name for pid %d: %v", pid, err)
 	}
 
+	cgInfo, err := handler.ReadCgroup(pid)
+	if err == nil {
+		pidInfo.CgroupPath = cgInfo.Path
+		pidInfo.ContainerID = cgInfo.ContainerID
+		pidInfo.PodUID = cgInfo.PodUID
+	} else {
+		// failures are not critical
+		handler.log.Printf("Error reading cgroup for pid %d: %v", pid, err)
+	}
+
 	tasksDir := filepath.Join(handler.procfsRoot, procEntry(pid), "task")
 	tidEntries, err := handler.fs.ReadDir(tasksDir)
 	if err != nil {
@@ -112,6 +135,15 @@ func (handler *Handler) FromPID(pid int) (PIDInfo, error) {
 			handler.log.Printf("Error parsing status for pid %d tid %s: %v", pid, tidEntry.Name(), err)
 			continue
 		}
+		schedInfo, err := handler.readSchedInfo(filepath.Join(tasksDir, tidEntry.Name()))
+		if err == nil {
+			info.Policy = schedInfo.Policy
+			info.RTPriority = schedInfo.RTPriority
+			info.Nice = schedInfo.Nice
+		} else {
+			// failures are not critical
+			handler.log.Printf("Error reading the scheduling setting for pid %d tid %s: %v", pid, tidEntry.Name(), err)
+		}
 		pidInfo.TIDs[info.Tid] = info
 	}
 
@@ -79,6 +79,55 @@ func TestSingleProcSingleThread(t *testing.T) {
 	}
 }
 
+func TestSingleProcInContainer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test")
+	if err != nil {
+		t.Fatalf("creating temp dir %v", err)
+	}
+	defer os.RemoveAll(dir) // clean up
+
+	cgroupPath := "/kubepods.slice/kubepods-pod8a2b6a4e_7c41_4b5e_a1f3_2d9e6c0b7f15.slice/crio-" + fakeContainerID + ".scope"
+	if err := makeFakeTree(dir, map[int]fakeEntry{
+		42: fakeEntry{
+			attrs: fakeAttrs{
+				"cmdline": "/usr/bin/sleep\x00inf",
+				"cgroup":  "0::" + cgroupPath + "\n",
+			},
+			tasks: map[int]fakeAttrs{
+				42: fakeAttrs{
+					"status": "Name:	sleep\nPid:	42\nCpus_allowed_list:	2-3\n",
+				},
+			},
+		},
+	}); err != nil {
+		t.Fatalf("populating temp dir %v", err)
+	}
+
+	ph := procs.New(nullLog, dir)
+	got, err := ph.FromPID(42)
+	if err != nil {
+		t.Errorf("FromPID(42) failed: %v", err)
+	}
+
+	expected := procs.PIDInfo{
+		Pid:  42,
+		Name: "sleep",
+		TIDs: map[int]procs.TIDInfo{
+			42: procs.TIDInfo{
+				Tid:      42,
+				Name:     "sleep",
+				Affinity: []int{2, 3},
+			},
+		},
+		CgroupPath:  cgroupPath,
+		ContainerID: fakeContainerID,
+		PodUID:      fakePodUID,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected return value: got=%#v expected=%#v", got, expected)
+	}
+}
+
 type fakeAttrs map[string]string
 
 type fakeEntry struct {
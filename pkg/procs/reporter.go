@@ -0,0 +1,133 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package procs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	cpuset "k8s.io/utils/cpuset"
+)
+
+type Reporter interface {
+	Delta(ts time.Time, prevStats, lastStats SchedStats)
+	Summary(initTs time.Time, prevStats, lastStats SchedStats)
+}
+
+func NewReporter(sink io.Writer, jsonOutput bool, verbose int, cpus cpuset.CPUSet) Reporter {
+	if jsonOutput {
+		return &reporterJSON{
+			verbose: verbose,
+			cpus:    cpus,
+			sink:    sink,
+		}
+	}
+	return &reporterText{
+		verbose: verbose,
+		cpus:    cpus,
+		sink:    sink,
+	}
+}
+
+type reporterText struct {
+	verbose int
+	cpus    cpuset.CPUSet
+	sink    io.Writer
+}
+
+func (rt *reporterText) Delta(ts time.Time, prevStats, lastStats SchedStats) {
+	if rt.verbose < 2 {
+		return
+	}
+	for _, tc := range nonZeroCounters(prevStats.Delta(lastStats)) {
+		fmt.Fprintf(rt.sink, "%v %s\n", ts, formatCounters(tc))
+	}
+}
+
+func (rt *reporterText) Summary(initTs time.Time, prevStats, lastStats SchedStats) {
+	if rt.verbose < 1 {
+		return
+	}
+	timeDelta := time.Now().Sub(initTs)
+
+	fmt.Fprintf(rt.sink, "\nscheduler summary on cpus %v after %v\n", rt.cpus, timeDelta)
+	for _, tc := range nonZeroCounters(prevStats.Delta(lastStats)) {
+		fmt.Fprintf(rt.sink, "%s\n", formatCounters(tc))
+	}
+}
+
+func formatCounters(tc ThreadCounters) string {
+	return fmt.Sprintf("PID=%d TID=%d (%s) CPU=%d voluntary=+%d nonvoluntary=+%d migrations=+%d", tc.PID, tc.TID, tc.Name, tc.CPU, tc.VoluntaryCtxSwitches, tc.NonvoluntaryCtxSwitches, tc.Migrations)
+}
+
+type reporterJSON struct {
+	verbose int
+	cpus    cpuset.CPUSet
+	sink    io.Writer
+}
+
+type schedDelta struct {
+	Timestamp time.Time        `json:"timestamp"`
+	Threads   []ThreadCounters `json:"threads"`
+}
+
+func (rj *reporterJSON) Delta(ts time.Time, prevStats, lastStats SchedStats) {
+	if rj.verbose < 2 {
+		return
+	}
+	res := schedDelta{
+		Timestamp: ts,
+		Threads:   nonZeroCounters(prevStats.Delta(lastStats)),
+	}
+	json.NewEncoder(rj.sink).Encode(res)
+}
+
+type schedSummary struct {
+	CPUs    string           `json:"cpus"`
+	Elapsed string           `json:"elapsed"`
+	Threads []ThreadCounters `json:"threads"`
+}
+
+func (rj *reporterJSON) Summary(initTs time.Time, prevStats, lastStats SchedStats) {
+	if rj.verbose < 1 {
+		return
+	}
+	res := schedSummary{
+		CPUs:    rj.cpus.String(),
+		Elapsed: time.Now().Sub(initTs).String(),
+		Threads: nonZeroCounters(prevStats.Delta(lastStats)),
+	}
+	json.NewEncoder(rj.sink).Encode(res)
+}
+
+// nonZeroCounters returns the threads with any non-zero counter, sorted by TID.
+func nonZeroCounters(stats SchedStats) []ThreadCounters {
+	res := []ThreadCounters{}
+	for _, tc := range stats {
+		if tc.IsZero() {
+			continue
+		}
+		res = append(res, tc)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].TID < res[j].TID
+	})
+	return res
+}
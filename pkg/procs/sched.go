@@ -0,0 +1,287 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package procs
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	cpuset "k8s.io/utils/cpuset"
+)
+
+type SchedPolicy string
+
+// see man 7 sched and include/uapi/linux/sched.h
+const (
+	SchedOther    SchedPolicy = "SCHED_OTHER"
+	SchedFIFO     SchedPolicy = "SCHED_FIFO"
+	SchedRR       SchedPolicy = "SCHED_RR"
+	SchedBatch    SchedPolicy = "SCHED_BATCH"
+	SchedIdle     SchedPolicy = "SCHED_IDLE"
+	SchedDeadline SchedPolicy = "SCHED_DEADLINE"
+)
+
+var schedPolicies = map[int]SchedPolicy{
+	0: SchedOther,
+	1: SchedFIFO,
+	2: SchedRR,
+	3: SchedBatch,
+	5: SchedIdle,
+	6: SchedDeadline,
+}
+
+// PolicyFromID translates the numeric scheduling policy, as found in procfs, into its name.
+func PolicyFromID(id int) SchedPolicy {
+	if policy, ok := schedPolicies[id]; ok {
+		return policy
+	}
+	return SchedPolicy(fmt.Sprintf("SCHED_%d", id))
+}
+
+// ParsePolicy parses a policy name, with or without the "SCHED_" prefix, case insensitive.
+func ParsePolicy(name string) (SchedPolicy, error) {
+	policy := SchedPolicy(strings.ToUpper(name))
+	if !strings.HasPrefix(string(policy), "SCHED_") {
+		policy = "SCHED_" + policy
+	}
+	for _, known := range schedPolicies {
+		if policy == known {
+			return policy, nil
+		}
+	}
+	return "", fmt.Errorf("unknown scheduling policy %q", name)
+}
+
+// IsRealtime tells if the policy belongs to the realtime (or deadline) scheduling classes,
+// which always preempt the SCHED_OTHER threads.
+func (sp SchedPolicy) IsRealtime() bool {
+	return sp == SchedFIFO || sp == SchedRR || sp == SchedDeadline
+}
+
+// SchedInfo is the scheduling setting of a thread.
+type SchedInfo struct {
+	Policy     SchedPolicy
+	RTPriority int
+	Nice       int
+}
+
+func (si SchedInfo) String() string {
+	if si.Policy.IsRealtime() {
+		return fmt.Sprintf("%s prio %d", si.Policy, si.RTPriority)
+	}
+	return fmt.Sprintf("%s nice %d", si.Policy, si.Nice)
+}
+
+// ParseStat extracts the scheduling setting from the content of /proc/<pid>/task/<tid>/stat. See man 5 proc.
+func ParseStat(data string) (SchedInfo, error) {
+	const (
+		niceIdx       = 19 - statFieldsOffset
+		rtPriorityIdx = 40 - statFieldsOffset
+		policyIdx     = 41 - statFieldsOffset
+	)
+	fields, err := statFields(data, policyIdx)
+	if err != nil {
+		return SchedInfo{}, err
+	}
+	var vals [3]int
+	for idx, fieldIdx := range []int{niceIdx, rtPriorityIdx, policyIdx} {
+		val, err := strconv.Atoi(fields[fieldIdx])
+		if err != nil {
+			return SchedInfo{}, fmt.Errorf("malformed stat field %d: %v", fieldIdx+statFieldsOffset, err)
+		}
+		vals[idx] = val
+	}
+	return SchedInfo{
+		Policy:     PolicyFromID(vals[2]),
+		RTPriority: vals[1],
+		Nice:       vals[0],
+	}, nil
+}
+
+// statFieldsOffset is the number of the first field returned by statFields, "state". See man 5 proc.
+const statFieldsOffset = 3
+
+// statFields splits the content of a stat file in fields, skipping the pid and the comm, which is enclosed
+// in parens and can contain both spaces and parens. At least maxIdx+1 fields are expected.
+func statFields(data string, maxIdx int) ([]string, error) {
+	off := strings.LastIndex(data, ")")
+	if off < 0 {
+		return nil, fmt.Errorf("malformed stat: missing comm")
+	}
+	fields := strings.Fields(data[off+1:])
+	if len(fields) <= maxIdx {
+		return nil, fmt.Errorf("malformed stat: found %d fields", len(fields)+statFieldsOffset-1)
+	}
+	return fields, nil
+}
+
+// ParseSched extracts the scheduling setting from the content of /proc/<pid>/task/<tid>/sched,
+// which reports the kernel priority rather than the user-visible values.
+func ParseSched(data string) (SchedInfo, error) {
+	var policyID, prio int
+	var foundPolicy, foundPrio bool
+	for _, line := range strings.Split(data, "\n") {
+		items := strings.SplitN(line, ":", 2)
+		if len(items) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(items[0])
+		if key != "policy" && key != "prio" {
+			continue
+		}
+		val, err := strconv.Atoi(strings.TrimSpace(items[1]))
+		if err != nil {
+			return SchedInfo{}, fmt.Errorf("malformed sched field %q: %v", key, err)
+		}
+		if key == "policy" {
+			policyID, foundPolicy = val, true
+		} else {
+			prio, foundPrio = val, true
+		}
+	}
+	if !foundPolicy || !foundPrio {
+		return SchedInfo{}, fmt.Errorf("malformed sched: missing policy or prio")
+	}
+	info := SchedInfo{
+		Policy: PolicyFromID(policyID),
+	}
+	// see kernel/sched/debug.c and include/linux/sched/prio.h
+	switch {
+	case info.Policy == SchedDeadline:
+		// prio is -1, rt priority is meaningless
+	case info.Policy.IsRealtime():
+		info.RTPriority = 99 - prio
+	default:
+		info.Nice = prio - 120
+	}
+	return info, nil
+}
+
+// ReadSched reads the scheduling setting of the given thread from its stat file, falling back to
+// its sched file, which is available only on kernels built with CONFIG_SCHED_DEBUG.
+func (handler *Handler) ReadSched(pid, tid int) (SchedInfo, error) {
+	return handler.readSchedInfo(filepath.Join(handler.procfsRoot, procEntry(pid), "task", fmt.Sprintf("%d", tid)))
+}
+
+func (handler *Handler) readSchedInfo(taskDir string) (SchedInfo, error) {
+	data, err := handler.fs.ReadFile(filepath.Join(taskDir, "stat"))
+	if err == nil {
+		return ParseStat(string(data))
+	}
+	data, errSched := handler.fs.ReadFile(filepath.Join(taskDir, "sched"))
+	if errSched != nil {
+		// the stat file is the primary source
+		return SchedInfo{}, err
+	}
+	return ParseSched(string(data))
+}
+
+// per-cpu kernel threads running in the stop class, or otherwise not competing with the
+// application threads, despite being reported as realtime.
+var rtHousekeepingPrefixes = []string{
+	"migration/",
+	"idle_inject/",
+	"watchdog/",
+}
+
+// Contention is a SCHED_FIFO thread which can run on isolated cpus together with other realtime threads,
+// or with the ksoftirqd threads, which it can starve.
+type Contention struct {
+	PID int
+	TID int
+	// CPUs are the isolated cpus shared with the other threads
+	CPUs cpuset.CPUSet
+	// TIDs of the other threads
+	SharesWith []int
+}
+
+// FindRTContention finds the SCHED_FIFO threads sharing any of the isolated cpus with ksoftirqd
+// or with other realtime threads. Contentions are sorted by TID.
+func FindRTContention(infos map[int]PIDInfo, isolated cpuset.CPUSet) []Contention {
+	type thread struct {
+		pid  int
+		tid  int
+		cpus cpuset.CPUSet
+	}
+	var fifos []thread
+	competitors := make(map[int][]thread) // isolated cpu -> threads
+	for pid, pidInfo := range infos {
+		for tid, tidInfo := range pidInfo.TIDs {
+			cpus := cpuset.New(tidInfo.Affinity...).Intersection(isolated)
+			if cpus.IsEmpty() || !isCompetitor(tidInfo) {
+				continue
+			}
+			th := thread{pid: pid, tid: tid, cpus: cpus}
+			if tidInfo.Policy == SchedFIFO {
+				fifos = append(fifos, th)
+			}
+			for _, cpu := range cpus.List() {
+				competitors[cpu] = append(competitors[cpu], th)
+			}
+		}
+	}
+
+	var conts []Contention
+	for _, fifo := range fifos {
+		shared := cpuset.New()
+		others := make(map[int]struct{})
+		for _, cpu := range fifo.cpus.List() {
+			for _, other := range competitors[cpu] {
+				if other.tid == fifo.tid {
+					continue
+				}
+				shared = shared.Union(cpuset.New(cpu))
+				others[other.tid] = struct{}{}
+			}
+		}
+		if len(others) == 0 {
+			continue
+		}
+		cont := Contention{
+			PID:  fifo.pid,
+			TID:  fifo.tid,
+			CPUs: shared,
+		}
+		for tid := range others {
+			cont.SharesWith = append(cont.SharesWith, tid)
+		}
+		sort.Ints(cont.SharesWith)
+		conts = append(conts, cont)
+	}
+	sort.Slice(conts, func(i, j int) bool {
+		return conts[i].TID < conts[j].TID
+	})
+	return conts
+}
+
+func isCompetitor(tidInfo TIDInfo) bool {
+	if strings.HasPrefix(tidInfo.Name, "ksoftirqd/") {
+		return true
+	}
+	if !tidInfo.Policy.IsRealtime() {
+		return false
+	}
+	for _, prefix := range rtHousekeepingPrefixes {
+		if strings.HasPrefix(tidInfo.Name, prefix) {
+			return false
+		}
+	}
+	return true
+}
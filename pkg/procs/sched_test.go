@@ -0,0 +1,176 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package procs_test
+
+import (
+	"reflect"
+	"testing"
+
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+	"github.com/openshift-kni/debug-tools/pkg/procs"
+)
+
+// nice at field 19, rt_priority at field 40, policy at field 41
+const (
+	fakeStatFIFO  = "4250 (eal-intr (thread)) S 1 4250 4250 0 -1 4194560 0 0 0 0 0 0 0 0 -51 0 3 0 1234 0 0 18446744073709551615 0 0 0 0 0 0 0 0 0 0 0 0 -1 4 50 1 0 0 0 0 0 0 0 0 0 0 0\n"
+	fakeStatOther = "1337 (irqbalance) S 1 1337 1337 0 -1 4194560 0 0 0 0 0 0 0 0 25 5 1 0 1234 0 0 18446744073709551615 0 0 0 0 0 0 0 0 0 0 0 0 17 0 0 0 0 0 0 0 0 0 0 0 0 0 0\n"
+)
+
+func TestParseStat(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		expected procs.SchedInfo
+		expError bool
+	}{
+		{
+			name:     "fifo, comm with spaces and parens",
+			data:     fakeStatFIFO,
+			expected: procs.SchedInfo{Policy: procs.SchedFIFO, RTPriority: 50},
+		},
+		{
+			name:     "other, niced",
+			data:     fakeStatOther,
+			expected: procs.SchedInfo{Policy: procs.SchedOther, Nice: 5},
+		},
+		{
+			name:     "truncated",
+			data:     "1 (init) S 1 1 1",
+			expError: true,
+		},
+		{
+			name:     "missing comm",
+			data:     "garbage",
+			expError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := procs.ParseStat(tc.data)
+			if (err != nil) != tc.expError {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("sched info mismatch got %+v expected %+v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestParseSched(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		expected procs.SchedInfo
+	}{
+		{
+			name:     "rr",
+			data:     "ktimers/3 (40, #threads: 1)\n---------\nse.exec_start  :  1234.56\npolicy  :  2\nprio  :  98\n",
+			expected: procs.SchedInfo{Policy: procs.SchedRR, RTPriority: 1},
+		},
+		{
+			name:     "batch",
+			data:     "make (4242, #threads: 1)\n---------\npolicy  :  3\nprio  :  139\n",
+			expected: procs.SchedInfo{Policy: procs.SchedBatch, Nice: 19},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := procs.ParseSched(tc.data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("sched info mismatch got %+v expected %+v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestReadSchedFallback(t *testing.T) {
+	fsys := fswrap.NewMemFS(map[string]string{
+		"/proc/42/task/42/stat":  fakeStatFIFO,
+		"/proc/42/task/43/sched": "worker (43, #threads: 2)\npolicy  :  1\nprio  :  89\n",
+	})
+	ph := procs.NewWithFS(nullLog, fsys, "/proc")
+
+	info, err := ph.ReadSched(42, 42)
+	if err != nil || info.Policy != procs.SchedFIFO || info.RTPriority != 50 {
+		t.Errorf("unexpected sched info from stat: %+v err=%v", info, err)
+	}
+	info, err = ph.ReadSched(42, 43)
+	if err != nil || info.Policy != procs.SchedFIFO || info.RTPriority != 10 {
+		t.Errorf("unexpected sched info from sched: %+v err=%v", info, err)
+	}
+	if _, err := ph.ReadSched(42, 44); err == nil {
+		t.Errorf("ReadSched succeeded on a missing thread")
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	for _, name := range []string{"fifo", "SCHED_FIFO", "sched_fifo"} {
+		if got, err := procs.ParsePolicy(name); err != nil || got != procs.SchedFIFO {
+			t.Errorf("ParsePolicy(%q) = %q, %v", name, got, err)
+		}
+	}
+	if _, err := procs.ParsePolicy("fast"); err == nil {
+		t.Errorf("ParsePolicy succeeded on an unknown policy")
+	}
+}
+
+func TestFindRTContention(t *testing.T) {
+	thread := func(tid int, name string, policy procs.SchedPolicy, cpus ...int) procs.TIDInfo {
+		return procs.TIDInfo{Tid: tid, Name: name, Affinity: cpus, Policy: policy}
+	}
+	infos := map[int]procs.PIDInfo{
+		// per-cpu kernel threads
+		20: {Pid: 20, TIDs: map[int]procs.TIDInfo{20: thread(20, "migration/2", procs.SchedFIFO, 2)}},
+		21: {Pid: 21, TIDs: map[int]procs.TIDInfo{21: thread(21, "ksoftirqd/2", procs.SchedOther, 2)}},
+		31: {Pid: 31, TIDs: map[int]procs.TIDInfo{31: thread(31, "ksoftirqd/3", procs.SchedOther, 3)}},
+		// housekeeping threads
+		1337: {Pid: 1337, TIDs: map[int]procs.TIDInfo{1337: thread(1337, "irqbalance", procs.SchedOther, 0, 1, 2, 3)}},
+		// application with a pinned and a floating realtime thread
+		4242: {Pid: 4242, TIDs: map[int]procs.TIDInfo{
+			4242: thread(4242, "app", procs.SchedOther, 2, 3),
+			4250: thread(4250, "app-rx", procs.SchedFIFO, 2),
+			4251: thread(4251, "app-ctl", procs.SchedRR, 2, 3),
+		}},
+		// realtime thread on the housekeeping cpus only
+		5000: {Pid: 5000, TIDs: map[int]procs.TIDInfo{5000: thread(5000, "rtkit", procs.SchedFIFO, 0, 1)}},
+	}
+
+	got := procs.FindRTContention(infos, cpuset.New(2, 3))
+	expected := []procs.Contention{
+		{
+			PID:        4242,
+			TID:        4250,
+			CPUs:       cpuset.New(2),
+			SharesWith: []int{21, 4251},
+		},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("contention mismatch got %+v expected %+v", got, expected)
+	}
+
+	if got := procs.FindRTContention(infos, cpuset.New()); len(got) != 0 {
+		t.Errorf("unexpected contention without isolated cpus: %+v", got)
+	}
+}
@@ -0,0 +1,203 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package procs
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	cpuset "k8s.io/utils/cpuset"
+)
+
+// ThreadCounters are the scheduler counters of a thread.
+type ThreadCounters struct {
+	PID  int    `json:"pid"`
+	TID  int    `json:"tid"`
+	Name string `json:"name"`
+	// CPU is the cpu the thread last ran on
+	CPU int `json:"cpu"`
+	// State is the thread state, like "R" (running or runnable) or "S" (sleeping)
+	State string `json:"state,omitempty"`
+	// Kernel is set for the kernel threads
+	Kernel                  bool   `json:"kernel,omitempty"`
+	VoluntaryCtxSwitches    uint64 `json:"voluntaryCtxSwitches"`
+	NonvoluntaryCtxSwitches uint64 `json:"nonvoluntaryCtxSwitches"`
+	Migrations              uint64 `json:"migrations"`
+}
+
+// IsZero tells if all the counters are zero.
+func (tc ThreadCounters) IsZero() bool {
+	return tc.VoluntaryCtxSwitches == 0 && tc.NonvoluntaryCtxSwitches == 0 && tc.Migrations == 0
+}
+
+// TID -> counters
+type SchedStats map[int]ThreadCounters
+
+// assume X is fresher than S. Threads not in X are gone, threads not in S are new, so all their
+// counters happened in the interval. A TID reused in the interval, detected by a different PID or
+// by counters going down, is a new thread as well.
+func (S SchedStats) Delta(X SchedStats) SchedStats {
+	R := make(SchedStats)
+	for tid, last := range X {
+		prev, ok := S[tid]
+		if !ok || prev.PID != last.PID || last.VoluntaryCtxSwitches < prev.VoluntaryCtxSwitches ||
+			last.NonvoluntaryCtxSwitches < prev.NonvoluntaryCtxSwitches || last.Migrations < prev.Migrations {
+			R[tid] = last
+			continue
+		}
+		delta := last
+		delta.VoluntaryCtxSwitches = last.VoluntaryCtxSwitches - prev.VoluntaryCtxSwitches
+		delta.NonvoluntaryCtxSwitches = last.NonvoluntaryCtxSwitches - prev.NonvoluntaryCtxSwitches
+		delta.Migrations = last.Migrations - prev.Migrations
+		R[tid] = delta
+	}
+	return R
+}
+
+func (S SchedStats) Clone() SchedStats {
+	R := make(SchedStats)
+	for k, v := range S {
+		R[k] = v
+	}
+	return R
+}
+
+// ReadSchedStats reads the scheduler counters of all the threads which can run on any of the given cpus.
+// The migrations are available only on kernels built with CONFIG_SCHED_DEBUG, and are zero otherwise.
+func (handler *Handler) ReadSchedStats(cpus cpuset.CPUSet) (SchedStats, error) {
+	stats := make(SchedStats)
+	pidEntries, err := handler.fs.ReadDir(handler.procfsRoot)
+	if err != nil {
+		return stats, err
+	}
+
+	for _, pidEntry := range pidEntries {
+		pid, err := strconv.Atoi(pidEntry.Name())
+		if err != nil || !pidEntry.IsDir() {
+			// doesn't look like a pid
+			continue
+		}
+
+		tasksDir := filepath.Join(handler.procfsRoot, pidEntry.Name(), "task")
+		tidEntries, err := handler.fs.ReadDir(tasksDir)
+		if err != nil {
+			// most likely the process is gone
+			handler.log.Printf("Error reading the tasks %q for %d: %v", tasksDir, pid, err)
+			continue
+		}
+		for _, tidEntry := range tidEntries {
+			tid, err := strconv.Atoi(tidEntry.Name())
+			if err != nil {
+				continue
+			}
+			counters, affinity, err := handler.readThreadCounters(filepath.Join(tasksDir, tidEntry.Name()))
+			if err != nil {
+				// failures are not critical
+				handler.log.Printf("Error reading the scheduler counters for pid %d tid %d: %v", pid, tid, err)
+				continue
+			}
+			if affinity.Intersection(cpus).IsEmpty() {
+				continue
+			}
+			counters.PID = pid
+			counters.TID = tid
+			stats[tid] = counters
+		}
+	}
+	return stats, nil
+}
+
+func (handler *Handler) readThreadCounters(taskDir string) (ThreadCounters, cpuset.CPUSet, error) {
+	counters := ThreadCounters{}
+	affinity := cpuset.New()
+
+	data, err := handler.fs.ReadFile(filepath.Join(taskDir, "status"))
+	if err != nil {
+		return counters, affinity, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		items := strings.SplitN(line, ":", 2)
+		if len(items) != 2 {
+			continue
+		}
+		val := strings.TrimSpace(items[1])
+		switch items[0] {
+		case "Name":
+			counters.Name = val
+		case "Cpus_allowed_list":
+			affinity, err = cpuset.Parse(val)
+		case "voluntary_ctxt_switches":
+			counters.VoluntaryCtxSwitches, err = strconv.ParseUint(val, 10, 64)
+		case "nonvoluntary_ctxt_switches":
+			counters.NonvoluntaryCtxSwitches, err = strconv.ParseUint(val, 10, 64)
+		}
+		if err != nil {
+			return counters, affinity, fmt.Errorf("malformed status field %q: %v", items[0], err)
+		}
+	}
+
+	data, err = handler.fs.ReadFile(filepath.Join(taskDir, "stat"))
+	if err != nil {
+		return counters, affinity, err
+	}
+	const (
+		flagsIdx     = 9 - statFieldsOffset
+		processorIdx = 39 - statFieldsOffset
+	)
+	fields, err := statFields(string(data), processorIdx)
+	if err != nil {
+		return counters, affinity, err
+	}
+	counters.CPU, err = strconv.Atoi(fields[processorIdx])
+	if err != nil {
+		return counters, affinity, fmt.Errorf("malformed stat field %d: %v", processorIdx+statFieldsOffset, err)
+	}
+	counters.State = fields[0]
+	flags, err := strconv.ParseUint(fields[flagsIdx], 10, 64)
+	if err != nil {
+		return counters, affinity, fmt.Errorf("malformed stat field %d: %v", flagsIdx+statFieldsOffset, err)
+	}
+	counters.Kernel = flags&pfKThread != 0
+
+	data, err = handler.fs.ReadFile(filepath.Join(taskDir, "sched"))
+	if err != nil {
+		// no CONFIG_SCHED_DEBUG, not critical
+		return counters, affinity, nil
+	}
+	counters.Migrations, err = parseSchedCounter(string(data), "se.nr_migrations")
+	return counters, affinity, err
+}
+
+// PF_KTHREAD, see include/linux/sched.h
+const pfKThread = 0x00200000
+
+func parseSchedCounter(data, name string) (uint64, error) {
+	for _, line := range strings.Split(data, "\n") {
+		items := strings.SplitN(line, ":", 2)
+		if len(items) != 2 || strings.TrimSpace(items[0]) != name {
+			continue
+		}
+		val, err := strconv.ParseUint(strings.TrimSpace(items[1]), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("malformed sched field %q: %v", name, err)
+		}
+		return val, nil
+	}
+	return 0, nil
+}
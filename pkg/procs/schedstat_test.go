@@ -0,0 +1,118 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package procs_test
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	cpuset "k8s.io/utils/cpuset"
+
+	"github.com/openshift-kni/debug-tools/pkg/fswrap"
+	"github.com/openshift-kni/debug-tools/pkg/procs"
+)
+
+func fakeTaskStatus(name, cpus string, voluntary, nonvoluntary int) string {
+	return "Name:\t" + name + "\nCpus_allowed_list:\t" + cpus + "\n" +
+		"voluntary_ctxt_switches:\t" + strconv.Itoa(voluntary) + "\nnonvoluntary_ctxt_switches:\t" + strconv.Itoa(nonvoluntary) + "\n"
+}
+
+// processor at field 39
+func fakeTaskStat(tid, cpu int) string {
+	return strconv.Itoa(tid) + " (app) R 1 1 1 0 -1 0 0 0 0 0 0 0 0 0 20 0 1 0 1234 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 17 " + strconv.Itoa(cpu) + " 0 0 0 0 0\n"
+}
+
+func TestReadSchedStats(t *testing.T) {
+	fsys := fswrap.NewMemFS(map[string]string{
+		"/proc/42/task/42/status": fakeTaskStatus("app", "0-3", 10, 1),
+		"/proc/42/task/42/stat":   fakeTaskStat(42, 1),
+		"/proc/42/task/43/status": fakeTaskStatus("app-rx", "3", 0, 7),
+		"/proc/42/task/43/stat":   fakeTaskStat(43, 3),
+		"/proc/42/task/43/sched":  "app-rx (43, #threads: 2)\n---------\nse.exec_start  :  1234.56\nse.nr_migrations  :  2\n",
+		"/proc/50/task/50/status": fakeTaskStatus("housekeeping", "0-1", 100, 100),
+		"/proc/50/task/50/stat":   fakeTaskStat(50, 0),
+		// kernel thread, flags at field 9 include PF_KTHREAD
+		"/proc/9/task/9/status": fakeTaskStatus("ksoftirqd/3", "3", 5, 0),
+		"/proc/9/task/9/stat":   strings.NewReplacer("(app) R", "(ksoftirqd/3) S", " -1 0 ", " -1 2129984 ").Replace(fakeTaskStat(9, 3)),
+		// malformed, skipped
+		"/proc/60/task/60/status": fakeTaskStatus("broken", "3", 1, 1),
+		"/proc/60/task/60/stat":   "garbage",
+	})
+	ph := procs.NewWithFS(nullLog, fsys, "/proc")
+
+	got, err := ph.ReadSchedStats(cpuset.New(2, 3))
+	if err != nil {
+		t.Fatalf("ReadSchedStats failed: %v", err)
+	}
+	expected := procs.SchedStats{
+		42: {PID: 42, TID: 42, Name: "app", CPU: 1, State: "R", VoluntaryCtxSwitches: 10, NonvoluntaryCtxSwitches: 1},
+		43: {PID: 42, TID: 43, Name: "app-rx", CPU: 3, State: "R", NonvoluntaryCtxSwitches: 7, Migrations: 2},
+		9:  {PID: 9, TID: 9, Name: "ksoftirqd/3", CPU: 3, State: "S", Kernel: true, VoluntaryCtxSwitches: 5},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("stats mismatch got %+v expected %+v", got, expected)
+	}
+}
+
+func TestSchedStatsDelta(t *testing.T) {
+	prev := procs.SchedStats{
+		42: {PID: 42, TID: 42, Name: "app", CPU: 1, VoluntaryCtxSwitches: 10, NonvoluntaryCtxSwitches: 1},
+		44: {PID: 42, TID: 44, Name: "gone", CPU: 2, VoluntaryCtxSwitches: 10},
+		46: {PID: 46, TID: 46, Name: "recycled", CPU: 1, VoluntaryCtxSwitches: 100, NonvoluntaryCtxSwitches: 3},
+	}
+	last := procs.SchedStats{
+		42: {PID: 42, TID: 42, Name: "app", CPU: 2, VoluntaryCtxSwitches: 15, NonvoluntaryCtxSwitches: 4, Migrations: 1},
+		45: {PID: 42, TID: 45, Name: "new", CPU: 3, VoluntaryCtxSwitches: 2},
+		46: {PID: 46, TID: 46, Name: "other", CPU: 1, VoluntaryCtxSwitches: 7, NonvoluntaryCtxSwitches: 5},
+	}
+	expected := procs.SchedStats{
+		42: {PID: 42, TID: 42, Name: "app", CPU: 2, VoluntaryCtxSwitches: 5, NonvoluntaryCtxSwitches: 3, Migrations: 1},
+		45: {PID: 42, TID: 45, Name: "new", CPU: 3, VoluntaryCtxSwitches: 2},
+		46: {PID: 46, TID: 46, Name: "other", CPU: 1, VoluntaryCtxSwitches: 7, NonvoluntaryCtxSwitches: 5},
+	}
+	if got := prev.Delta(last); !reflect.DeepEqual(got, expected) {
+		t.Errorf("delta mismatch got %+v expected %+v", got, expected)
+	}
+}
+
+func TestReporterText(t *testing.T) {
+	prev := procs.SchedStats{
+		42: {PID: 42, TID: 42, Name: "app", CPU: 3, VoluntaryCtxSwitches: 10},
+		43: {PID: 42, TID: 43, Name: "app-rx", CPU: 3, NonvoluntaryCtxSwitches: 1},
+	}
+	last := procs.SchedStats{
+		42: {PID: 42, TID: 42, Name: "app", CPU: 3, VoluntaryCtxSwitches: 10},
+		43: {PID: 42, TID: 43, Name: "app-rx", CPU: 3, NonvoluntaryCtxSwitches: 4},
+	}
+
+	var buf bytes.Buffer
+	rep := procs.NewReporter(&buf, false, 2, cpuset.New(3))
+	rep.Delta(time.Now(), prev, last)
+	rep.Summary(time.Now(), prev, last)
+
+	out := buf.String()
+	if strings.Count(out, "TID=43 (app-rx) CPU=3 voluntary=+0 nonvoluntary=+3 migrations=+0") != 2 {
+		t.Errorf("missing counters in %q", out)
+	}
+	if strings.Contains(out, "TID=42") {
+		t.Errorf("unexpected idle thread in %q", out)
+	}
+}
@@ -0,0 +1,310 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package snapshot
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	ghwsnapshot "github.com/jaypipes/ghw/pkg/snapshot"
+)
+
+const (
+	DefaultProcFSRoot = "/proc"
+	DefaultSysFSRoot  = "/sys"
+	DefaultEtcRoot    = "/etc"
+)
+
+type Options struct {
+	ProcFSRoot string
+	SysFSRoot  string
+	EtcRoot    string
+	// Scrub removes the machine-identifiable data from the snapshot,
+	// like machineinformer does by default.
+	Scrub bool
+}
+
+// ExpectedCloneContent returns a slice of glob patterns which represent the pseudofiles
+// the knit handlers consume. Like in ghw, patterns are expressed as absolute paths
+// assuming procfs and sysfs are mounted on their usual locations.
+func ExpectedCloneContent() []string {
+	return []string{
+		// irqs, softirqs
+		"/proc/cmdline",
+		"/proc/interrupts",
+		"/proc/softirqs",
+		"/proc/stat",
+		"/proc/irq/default_smp_affinity",
+		"/proc/irq/*/*",
+		"/sys/kernel/irq/*/*",
+		"/sys/kernel/debug/irq/irqs/*",
+		"/sys/bus/pci/devices/*/msi_irqs/*",
+		"/sys/bus/pci/devices/*/uevent",
+		"/sys/bus/pci/devices/*/net/*/ifindex",
+		// procs, numalign
+		"/proc/[0-9]*/cmdline",
+		"/proc/[0-9]*/status",
+		"/proc/[0-9]*/cgroup",
+		"/proc/[0-9]*/task/[0-9]*/status",
+		"/proc/[0-9]*/task/[0-9]*/stat",
+		"/proc/[0-9]*/task/[0-9]*/sched",
+		"/sys/bus/pci/devices/*/numa_node",
+		"/sys/devices/system/node/node*/cpulist",
+		// cpupower
+		"/sys/devices/system/cpu/cpu*/cpufreq/*",
+		"/sys/devices/system/cpu/cpu*/cpuidle/state*/*",
+		// machineinformer (RelocatableSysFs)
+		"/sys/block/*/dev",
+		"/sys/block/*/size",
+		"/sys/block/*/queue/scheduler",
+		"/sys/class/dmi/id/product_uuid",
+		"/sys/class/net/*/address",
+		"/sys/class/net/*/mtu",
+		"/sys/class/net/*/speed",
+		"/sys/devices/system/cpu/online",
+		"/sys/devices/system/node/node*/meminfo",
+		"/sys/devices/system/node/node*/hugepages/hugepages-*/*",
+		"/sys/kernel/mm/hugepages/hugepages-*/*",
+		// irqbalance
+		"/etc/sysconfig/irqbalance",
+		"/etc/default/irqbalance",
+	}
+}
+
+// ScrubbedContent returns a slice of glob patterns, relative to the snapshot root,
+// which represent the pseudofiles holding machine-identifiable data.
+func ScrubbedContent() []string {
+	return []string{
+		"etc/machine-id",
+		"proc/sys/kernel/random/boot_id",
+		"sys/class/dmi/id/board_serial",
+		"sys/class/dmi/id/chassis_serial",
+		"sys/class/dmi/id/product_serial",
+		"sys/class/dmi/id/product_uuid",
+		"sys/class/net/*/address",
+	}
+}
+
+// PackFrom creates the snapshot named `snapshotName` cloning the content from the
+// procfs and sysfs trees set in the given options.
+func PackFrom(logger *log.Logger, snapshotName string, opts Options) error {
+	scratchDir, err := ioutil.TempDir("", "knit-snapshot-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(scratchDir)
+
+	if err := CloneTreeInto(logger, scratchDir, opts); err != nil {
+		return err
+	}
+	if opts.Scrub {
+		if err := Scrub(logger, scratchDir); err != nil {
+			return err
+		}
+	}
+	return ghwsnapshot.PackFrom(snapshotName, scratchDir)
+}
+
+// CloneTreeInto copies all the pseudofiles that knit and ghw will consume into the root
+// `scratchDir`, preserving the hierarchy. Entries which vanish while cloning, like
+// processes exiting, are skipped.
+func CloneTreeInto(logger *log.Logger, scratchDir string, opts Options) error {
+	opts = withDefaults(opts)
+
+	fileSpecs := ExpectedCloneContent()
+	fileSpecs = append(fileSpecs, ghwsnapshot.ExpectedCloneStaticContent()...)
+	if opts.SysFSRoot == DefaultSysFSRoot {
+		// ghw scans the PCI tree directly from the live system
+		fileSpecs = append(fileSpecs, ghwsnapshot.ExpectedClonePCIContent()...)
+	}
+
+	cl := cloner{
+		log:        logger,
+		scratchDir: scratchDir,
+		roots: map[string]string{
+			"proc": opts.ProcFSRoot,
+			"sys":  opts.SysFSRoot,
+			"etc":  opts.EtcRoot,
+		},
+	}
+	for _, fileSpec := range fileSpecs {
+		if err := cl.cloneSpec(fileSpec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Scrub blanks all the pseudofiles holding machine-identifiable data in the cloned
+// tree whose root is `scratchDir`.
+func Scrub(logger *log.Logger, scratchDir string) error {
+	for _, fileSpec := range ScrubbedContent() {
+		matches, err := filepath.Glob(filepath.Join(scratchDir, fileSpec))
+		if err != nil {
+			return err
+		}
+		for _, match := range matches {
+			logger.Printf("snapshot: scrubbing %q", match)
+			if err := ioutil.WriteFile(match, []byte{}, 0644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func withDefaults(opts Options) Options {
+	if opts.ProcFSRoot == "" {
+		opts.ProcFSRoot = DefaultProcFSRoot
+	}
+	if opts.SysFSRoot == "" {
+		opts.SysFSRoot = DefaultSysFSRoot
+	}
+	if opts.EtcRoot == "" {
+		opts.EtcRoot = DefaultEtcRoot
+	}
+	return opts
+}
+
+type cloner struct {
+	log        *log.Logger
+	scratchDir string
+	// top-level directory name -> actual root on the filesystem
+	roots map[string]string
+}
+
+func (cl cloner) cloneSpec(fileSpec string) error {
+	items := strings.SplitN(strings.TrimPrefix(fileSpec, "/"), "/", 2)
+	root, ok := cl.roots[items[0]]
+	if !ok || len(items) != 2 {
+		cl.log.Printf("snapshot: unsupported spec %q - skipped", fileSpec)
+		return nil
+	}
+	cl.log.Printf("snapshot: cloning spec %q from %q", fileSpec, root)
+
+	matches, err := filepath.Glob(filepath.Join(root, items[1]))
+	if err != nil {
+		return err
+	}
+	destRoot := filepath.Join(cl.scratchDir, items[0])
+	for _, match := range matches {
+		relPath, err := filepath.Rel(root, match)
+		if err != nil {
+			return err
+		}
+		if err := cl.cloneEntry(root, destRoot, relPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cloneEntry copies `relPath` from `root` into `destRoot`. Symlinks found along the way
+// are recreated as they are, and their targets are cloned as well, provided they lie
+// within `root`.
+func (cl cloner) cloneEntry(root, destRoot, relPath string) error {
+	components := strings.Split(relPath, string(os.PathSeparator))
+	for idx := range components[:len(components)-1] {
+		prefix := filepath.Join(components[:idx+1]...)
+		fi, err := os.Lstat(filepath.Join(root, prefix))
+		if err != nil {
+			cl.log.Printf("snapshot: cannot access %q: %v - skipped", prefix, err)
+			return nil
+		}
+		if fi.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		if err := cl.cloneLink(root, destRoot, prefix); err != nil {
+			return err
+		}
+
+		realPath, err := filepath.EvalSymlinks(filepath.Join(root, relPath))
+		if err != nil {
+			cl.log.Printf("snapshot: cannot resolve %q: %v - skipped", relPath, err)
+			return nil
+		}
+		realRoot, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			return err
+		}
+		realRelPath, err := filepath.Rel(realRoot, realPath)
+		if err != nil || strings.HasPrefix(realRelPath, "..") {
+			cl.log.Printf("snapshot: %q resolves outside %q - skipped", relPath, root)
+			return nil
+		}
+		return cl.cloneEntry(root, destRoot, realRelPath)
+	}
+
+	srcPath := filepath.Join(root, relPath)
+	fi, err := os.Lstat(srcPath)
+	if err != nil {
+		cl.log.Printf("snapshot: cannot access %q: %v - skipped", srcPath, err)
+		return nil
+	}
+
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
+		return cl.cloneLink(root, destRoot, relPath)
+	case fi.IsDir():
+		return os.MkdirAll(filepath.Join(destRoot, relPath), os.ModePerm)
+	case fi.Mode().IsRegular():
+		return cl.clonePseudoFile(srcPath, filepath.Join(destRoot, relPath))
+	}
+	cl.log.Printf("snapshot: %q is not a regular file - skipped", srcPath)
+	return nil
+}
+
+func (cl cloner) cloneLink(root, destRoot, relPath string) error {
+	destPath := filepath.Join(destRoot, relPath)
+	if _, err := os.Lstat(destPath); err == nil {
+		return nil // already cloned
+	}
+	target, err := os.Readlink(filepath.Join(root, relPath))
+	if err != nil {
+		cl.log.Printf("snapshot: cannot read link %q: %v - skipped", relPath, err)
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(destPath), os.ModePerm); err != nil {
+		return err
+	}
+	return os.Symlink(target, destPath)
+}
+
+// pseudofiles don't report their size, so we need to read them fully before we can tar them.
+func (cl cloner) clonePseudoFile(srcPath, destPath string) error {
+	data, err := ioutil.ReadFile(srcPath)
+	if err != nil {
+		// most likely the process is gone, or the attribute is not readable
+		// in the current state (e.g. network link down)
+		cl.log.Printf("snapshot: cannot read %q: %v - skipped", srcPath, err)
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(destPath), os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(destPath, data, 0644)
+}
+
+// String returns a human-friendly description of the snapshot options.
+func (opts Options) String() string {
+	opts = withDefaults(opts)
+	return fmt.Sprintf("procfs=%q sysfs=%q etc=%q scrub=%v", opts.ProcFSRoot, opts.SysFSRoot, opts.EtcRoot, opts.Scrub)
+}
@@ -0,0 +1,159 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2024 Red Hat, Inc.
+ */
+
+package snapshot_test
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift-kni/debug-tools/pkg/snapshot"
+)
+
+var nullLog = log.New(ioutil.Discard, "", 0)
+
+func TestCloneTreeInto(t *testing.T) {
+	rootDir, err := ioutil.TempDir("", "test")
+	if err != nil {
+		t.Fatalf("creating temp dir %v", err)
+	}
+	defer os.RemoveAll(rootDir) // clean up
+
+	procDir := filepath.Join(rootDir, "proc")
+	sysDir := filepath.Join(rootDir, "sys")
+	if err := makeFakeTree(rootDir, fakeTree); err != nil {
+		t.Fatalf("populating temp dir %v", err)
+	}
+	devDir := filepath.Join(sysDir, "bus", "pci", "devices")
+	if err := os.MkdirAll(devDir, 0755); err != nil {
+		t.Fatalf("Mkdir(%s) failed: %v", devDir, err)
+	}
+	if err := os.Symlink("../../../devices/pci0000:00/0000:00:1f.6", filepath.Join(devDir, "0000:00:1f.6")); err != nil {
+		t.Fatalf("Symlink failed: %v", err)
+	}
+
+	scratchDir := filepath.Join(rootDir, "snapshot")
+	err = snapshot.CloneTreeInto(nullLog, scratchDir, snapshot.Options{
+		ProcFSRoot: procDir,
+		SysFSRoot:  sysDir,
+	})
+	if err != nil {
+		t.Fatalf("CloneTreeInto failed: %v", err)
+	}
+
+	for path, content := range fakeTree {
+		if path == "proc/42/environ" || filepath.Base(path) == ".keep" {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(scratchDir, path))
+		if err != nil {
+			t.Errorf("missing cloned file %q: %v", path, err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("content mismatch for %q: got %q expected %q", path, string(data), content)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(scratchDir, "proc/42/environ")); err == nil {
+		t.Errorf("unexpected file cloned: %q", "proc/42/environ")
+	}
+	if _, err := os.Stat(filepath.Join(scratchDir, "proc/irq/131/enp0s31f6")); err != nil {
+		t.Errorf("missing IRQ action directory: %v", err)
+	}
+
+	linkPath := filepath.Join(scratchDir, "sys/bus/pci/devices/0000:00:1f.6")
+	fi, err := os.Lstat(linkPath)
+	if err != nil {
+		t.Fatalf("missing cloned link %q: %v", linkPath, err)
+	}
+	if fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("expected %q to be a symlink", linkPath)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(linkPath, "numa_node"))
+	if err != nil || string(data) != "0\n" {
+		t.Errorf("cannot read numa_node through the cloned link: %q %v", string(data), err)
+	}
+}
+
+func TestScrub(t *testing.T) {
+	rootDir, err := ioutil.TempDir("", "test")
+	if err != nil {
+		t.Fatalf("creating temp dir %v", err)
+	}
+	defer os.RemoveAll(rootDir) // clean up
+
+	if err := makeFakeTree(rootDir, map[string]string{
+		"sys/class/dmi/id/product_uuid": "4c4c4544-0042-3010-8057-b4c04f564433\n",
+		"sys/class/net/eno1/address":    "b0:26:28:1e:c6:90\n",
+		"sys/class/net/eno1/mtu":        "1500\n",
+	}); err != nil {
+		t.Fatalf("populating temp dir %v", err)
+	}
+
+	if err := snapshot.Scrub(nullLog, rootDir); err != nil {
+		t.Fatalf("Scrub failed: %v", err)
+	}
+
+	for path, expected := range map[string]string{
+		"sys/class/dmi/id/product_uuid": "",
+		"sys/class/net/eno1/address":    "",
+		"sys/class/net/eno1/mtu":        "1500\n",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(rootDir, path))
+		if err != nil {
+			t.Errorf("missing file %q: %v", path, err)
+			continue
+		}
+		if string(data) != expected {
+			t.Errorf("content mismatch for %q: got %q expected %q", path, string(data), expected)
+		}
+	}
+}
+
+var fakeTree = map[string]string{
+	"proc/cmdline":                                  "BOOT_IMAGE=/vmlinuz root=/dev/sda1 isolcpus=2,3\n",
+	"proc/interrupts":                               "           CPU0       CPU1\n 131:  3949116          0  IR-PCI-MSI 520192-edge      enp0s31f6\n",
+	"proc/softirqs":                                 "                    CPU0       CPU1\n       TIMER:     128764     200838\n",
+	"proc/irq/131/smp_affinity_list":                "0-1\n",
+	"proc/irq/131/effective_affinity_list":          "0\n",
+	"proc/irq/131/enp0s31f6/.keep":                  "",
+	"proc/42/cmdline":                               "/usr/bin/sleep\x00inf\x00",
+	"proc/42/status":                                "Name:\tsleep\nPid:\t42\nCpus_allowed_list:\t0-1\n",
+	"proc/42/environ":                               "SECRET=foo\x00",
+	"proc/42/task/42/status":                        "Name:\tsleep\nPid:\t42\nCpus_allowed_list:\t0-1\n",
+	"sys/devices/system/node/node0/cpulist":         "0-1\n",
+	"sys/devices/pci0000:00/0000:00:1f.6/numa_node": "0\n",
+}
+
+func makeFakeTree(root string, entries map[string]string) error {
+	for path, content := range entries {
+		fullPath := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), os.ModePerm); err != nil {
+			return err
+		}
+		if filepath.Base(path) == ".keep" {
+			continue
+		}
+		if err := ioutil.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package e2e
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os/exec"
@@ -50,6 +51,36 @@ var _ = g.Describe("knit IRQ affinity tests", func() {
 			}
 			o.Expect(diff).To(o.BeZero(), "unexpected JSON difference: %v", diff)
 		})
+
+		g.It("Compares the requested and the effective affinity", func() {
+			cmdline := []string{
+				filepath.Join(binariesPath, "knit"),
+				"-P", filepath.Join(snapshotRoot, "proc"),
+				"-S", filepath.Join(snapshotRoot, "sys"),
+				"-J",
+				"irqaff",
+				"--compare",
+			}
+			fmt.Fprintf(g.GinkgoWriter, "running: %v\n", cmdline)
+
+			cmd := exec.Command(cmdline[0], cmdline[1:]...)
+			cmd.Stderr = g.GinkgoWriter
+
+			out, err := cmd.Output()
+			o.Expect(err).ToNot(o.HaveOccurred())
+
+			var irqDiffs []struct {
+				IRQ       int   `json:"irq"`
+				Requested []int `json:"requested"`
+				Effective []int `json:"effective"`
+			}
+			o.Expect(json.Unmarshal(out, &irqDiffs)).To(o.Succeed())
+			o.Expect(irqDiffs).ToNot(o.BeEmpty())
+			for _, irqDiff := range irqDiffs {
+				o.Expect(irqDiff.Requested).ToNot(o.BeEmpty(), "IRQ %d", irqDiff.IRQ)
+				o.Expect(irqDiff.Effective).ToNot(o.BeEmpty(), "IRQ %d", irqDiff.IRQ)
+			}
+		})
 	})
 
 	g.BeforeEach(func() {
@@ -0,0 +1,159 @@
+package e2e
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	g "github.com/onsi/ginkgo"
+	o "github.com/onsi/gomega"
+)
+
+var _ = g.Describe("knit snapshot option tests", func() {
+
+	var fixtureName = "dell_2_numa"
+
+	var dataDir string
+
+	g.Context("With a packed snapshot", func() {
+		g.It("Produces the same affinity output as the unpacked tree", func() {
+			cmdline := []string{
+				filepath.Join(binariesPath, "knit"),
+				"--snapshot", filepath.Join(dataDir, "sysinfo.tgz"),
+				"-e",
+				"-J",
+				"irqaff",
+			}
+			fmt.Fprintf(g.GinkgoWriter, "running: %v\n", cmdline)
+
+			cmd := exec.Command(cmdline[0], cmdline[1:]...)
+			cmd.Stderr = g.GinkgoWriter
+
+			out, err := cmd.Output()
+			o.Expect(err).ToNot(o.HaveOccurred())
+			refPath := filepath.Join(dataDir, "irqaff.json")
+			fmt.Fprintf(g.GinkgoWriter, "reference data at: %q\n", refPath)
+
+			expected, err := ioutil.ReadFile(refPath)
+			if err != nil {
+				g.Fail(fmt.Sprintf("fail to read the irqaff reference data from %q", refPath))
+			}
+
+			diff, err := getJSONBlobsDiff(out, expected)
+			if err != nil {
+				g.Fail("fail to compare the irqaff reference")
+			}
+			o.Expect(diff).To(o.BeZero(), "unexpected JSON difference: %v", diff)
+		})
+
+		g.It("Rejects explicit procfs or sysfs roots", func() {
+			cmdline := []string{
+				filepath.Join(binariesPath, "knit"),
+				"--snapshot", filepath.Join(dataDir, "sysinfo.tgz"),
+				"-P", "/proc",
+				"irqaff",
+			}
+			fmt.Fprintf(g.GinkgoWriter, "running: %v\n", cmdline)
+
+			cmd := exec.Command(cmdline[0], cmdline[1:]...)
+			cmd.Stderr = g.GinkgoWriter
+
+			_, err := cmd.Output()
+			o.Expect(err).To(o.HaveOccurred())
+		})
+	})
+
+	g.Context("With an unpacked snapshot", func() {
+		var snapshotRoot string
+
+		g.BeforeEach(func() {
+			snapshotRoot = snapshotBeforeEach(fixtureName, "sysinfo.tgz")
+		})
+
+		g.AfterEach(func() {
+			snapshotAfterEach(snapshotRoot)
+		})
+
+		g.It("Reads the snapshot in place", func() {
+			cmdline := []string{
+				filepath.Join(binariesPath, "knit"),
+				"--snapshot", snapshotRoot,
+				"-e",
+				"-J",
+				"irqaff",
+			}
+			fmt.Fprintf(g.GinkgoWriter, "running: %v\n", cmdline)
+
+			cmd := exec.Command(cmdline[0], cmdline[1:]...)
+			cmd.Stderr = g.GinkgoWriter
+
+			out, err := cmd.Output()
+			o.Expect(err).ToNot(o.HaveOccurred())
+
+			expected, err := ioutil.ReadFile(filepath.Join(dataDir, "irqaff.json"))
+			o.Expect(err).ToNot(o.HaveOccurred())
+
+			diff, err := getJSONBlobsDiff(out, expected)
+			o.Expect(err).ToNot(o.HaveOccurred())
+			o.Expect(diff).To(o.BeZero(), "unexpected JSON difference: %v", diff)
+		})
+	})
+
+	g.Context("With fixture recording", func() {
+		var workDir string
+
+		g.BeforeEach(func() {
+			var err error
+			workDir, err = ioutil.TempDir("", "knit-fixture-")
+			o.Expect(err).ToNot(o.HaveOccurred())
+		})
+
+		g.AfterEach(func() {
+			os.RemoveAll(workDir)
+		})
+
+		g.It("Records a fixture which replays the same output", func() {
+			fixturePath := filepath.Join(workDir, "fixture.tgz")
+			cmdline := []string{
+				filepath.Join(binariesPath, "knit"),
+				"--snapshot", filepath.Join(dataDir, "sysinfo.tgz"),
+				"--record-fixture", fixturePath,
+				"-e",
+				"-J",
+				"irqaff",
+			}
+			fmt.Fprintf(g.GinkgoWriter, "running: %v\n", cmdline)
+
+			cmd := exec.Command(cmdline[0], cmdline[1:]...)
+			cmd.Stderr = g.GinkgoWriter
+
+			recorded, err := cmd.Output()
+			o.Expect(err).ToNot(o.HaveOccurred())
+
+			cmdline = []string{
+				filepath.Join(binariesPath, "knit"),
+				"--snapshot", fixturePath,
+				"-e",
+				"-J",
+				"irqaff",
+			}
+			fmt.Fprintf(g.GinkgoWriter, "running: %v\n", cmdline)
+
+			cmd = exec.Command(cmdline[0], cmdline[1:]...)
+			cmd.Stderr = g.GinkgoWriter
+
+			replayed, err := cmd.Output()
+			o.Expect(err).ToNot(o.HaveOccurred())
+
+			diff, err := getJSONBlobsDiff(replayed, recorded)
+			o.Expect(err).ToNot(o.HaveOccurred())
+			o.Expect(diff).To(o.BeZero(), "unexpected JSON difference: %v", diff)
+		})
+	})
+
+	g.BeforeEach(func() {
+		dataDir = dataDirFor(fixtureName)
+	})
+})